@@ -50,6 +50,8 @@ func main() {
 	}
 	extract := false
 	out := false
+	ndjson := false
+	cacheDir := ""
 
 	var extractFile string
 	isTemp := false
@@ -71,6 +73,10 @@ func main() {
 			}
 		case strings.HasPrefix(arg, "-out="):
 			out = args.BoolArg(arg, false)
+		case strings.HasPrefix(arg, "-ndjson="):
+			ndjson = args.BoolArg(arg, false)
+		case strings.HasPrefix(arg, "-cache="):
+			cacheDir = args.StringArg(arg, "")
 		case strings.HasPrefix(arg, "-temp="):
 			isTemp = args.BoolArg(arg, false)
 		case strings.HasPrefix(arg, "-cpp="):
@@ -100,15 +106,17 @@ func main() {
 			fmt.Fprintln(os.Stderr, "runFromConfig: config file:", ags.CfgFile)
 			fmt.Fprintln(os.Stderr, "use stdin:", ags.UseStdin)
 			fmt.Fprintln(os.Stderr, "output to file:", out)
+			fmt.Fprintln(os.Stderr, "ndjson:", ndjson)
+			fmt.Fprintln(os.Stderr, "cache:", cacheDir)
 		}
-		runFromConfig(ags.CfgFile, ags.UseStdin, out, ags.Verbose)
+		runFromConfig(ags.CfgFile, ags.UseStdin, out, ndjson, cacheDir, ags.Verbose)
 	}
 
 }
 
 func printUsage() {
 	fmt.Println("Usage:")
-	fmt.Println("  llcppsigfetch [-v] [-out=<bool>] [config_file]")
+	fmt.Println("  llcppsigfetch [-v] [-out=<bool>] [-ndjson=<bool>] [-cache=<dir>] [config_file]")
 	fmt.Println("  OR")
 	fmt.Println("  llcppsigfetch --extract <file> [-out=<bool>] [-temp=<bool>] [-cpp=<bool>] [-v] [args...]")
 	fmt.Println("")
@@ -118,6 +126,10 @@ func printUsage() {
 	fmt.Println("  -out=<bool>:     Optional. Set to 'true' to output results to a file,")
 	fmt.Println("                   'false' (default) to output to stdout")
 	fmt.Println("                   This option can be used with both modes")
+	fmt.Println("  -ndjson=<bool>:  Optional. Set to 'true' to output NDJSON, one declaration")
+	fmt.Println("                   per line, written as the headers are parsed")
+	fmt.Println("  -cache=<dir>:    Optional. Cache the preprocessed headers in <dir> and reuse")
+	fmt.Println("                   them while the includes, cflags and headers don't change")
 	fmt.Println("")
 	fmt.Println("  --extract:       Extract information from a single file")
 	fmt.Println("    <file>:        Path to the file to process, or file content if -temp=true")
@@ -134,7 +146,7 @@ func printUsage() {
 	fmt.Println("Note: The two usage modes are mutually exclusive. Use either [<config_file>] OR --extract, not both.")
 }
 
-func runFromConfig(cfgFile string, useStdin bool, outputToFile bool, verbose bool) {
+func runFromConfig(cfgFile string, useStdin bool, outputToFile bool, ndjson bool, cacheDir string, verbose bool) {
 	var data []byte
 	var err error
 	if useStdin {
@@ -160,10 +172,17 @@ func runFromConfig(cfgFile string, useStdin bool, outputToFile bool, verbose boo
 		os.Exit(1)
 	}
 
+	if ndjson {
+		runStream(conf.Config, outputToFile, cacheDir)
+		return
+	}
+
 	converter, err := parse.Do(&parse.ParseConfig{
-		Conf: conf.Config,
+		Conf:     conf.Config,
+		CacheDir: cacheDir,
 	})
 	check(err)
+	defer converter.Dispose()
 	info := converter.Output()
 	str := info.Print()
 	defer cjson.FreeCStr(str)
@@ -171,6 +190,28 @@ func runFromConfig(cfgFile string, useStdin bool, outputToFile bool, verbose boo
 	outputResult(str, outputToFile)
 }
 
+// runStream writes the output as NDJSON while the headers are parsed, so the
+// whole output is never kept in memory.
+func runStream(conf *llcppg.Config, outputToFile bool, cacheDir string) {
+	out := os.Stdout
+	if outputToFile {
+		f, err := os.Create(llcppg.LLCPPG_SIGFETCH)
+		check(err)
+		defer f.Close()
+		out = f
+	}
+	converter, err := parse.Do(&parse.ParseConfig{
+		Conf:     conf,
+		Stream:   out,
+		CacheDir: cacheDir,
+	})
+	check(err)
+	converter.Dispose()
+	if outputToFile {
+		fmt.Fprintf(os.Stderr, "Results saved to %s\n", llcppg.LLCPPG_SIGFETCH)
+	}
+}
+
 func runExtract(content string, isTemp bool, isCpp bool, outToFile bool, otherArgs []string, verbose bool) {
 	var file string
 	cflags := otherArgs
@@ -180,7 +221,7 @@ func runExtract(content string, isTemp bool, isCpp bool, outToFile bool, otherAr
 			panic(err)
 		}
 		defer temp.Close()
-		defer os.Remove(file)
+		defer os.Remove(temp.Name())
 		temp.Write([]byte(content))
 		file = temp.Name()
 		cflags = append(cflags, "-I"+filepath.Dir(file))
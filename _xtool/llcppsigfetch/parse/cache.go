@@ -0,0 +1,137 @@
+/*
+This file is used to cache the preprocessed output of a package between runs,
+the cache of a package is a directory in the cache directory named after it:
+
+	key             the includes and flags the output is preprocessed with
+	headers         the headers of the package with their modification time and size
+	preprocessed.i  the preprocessed output
+
+The cache is reused while the key is the same and no header has changed.
+*/
+package parse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llcppg/_xtool/llcppsymg/config"
+	"github.com/goplus/llcppg/llcppg"
+)
+
+const (
+	cacheKeyFile          = "key"
+	cacheHeadersFile      = "headers"
+	cachePreprocessedFile = "preprocessed.i"
+)
+
+// cacheVersion is increased when the content of the cache changes incompatibly.
+const cacheVersion = 1
+
+// cacheKey returns the key of the preprocessed output of a package.
+func cacheKey(conf *llcppg.Config, libclangFlags []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "version %d\n", cacheVersion)
+	for _, inc := range conf.Include {
+		fmt.Fprintf(&b, "include %s\n", inc)
+	}
+	fmt.Fprintf(&b, "cflags %s\n", conf.CFlags)
+	fmt.Fprintf(&b, "cplusplus %t\n", conf.Cplusplus)
+	fmt.Fprintf(&b, "mix %t\n", conf.Mix)
+	fmt.Fprintf(&b, "target %s\n", conf.Target.ClangTriple())
+	fmt.Fprintf(&b, "libclang %s\n", strings.Join(libclangFlags, " "))
+	return b.String()
+}
+
+// loadCache returns the header info of the package cached in dir, or nil if
+// there is no cache of the key or one of its headers has changed.
+func loadCache(dir string, key string) *config.PkgHfilesInfo {
+	data, err := os.ReadFile(filepath.Join(dir, cacheKeyFile))
+	if err != nil || string(data) != key {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dir, cachePreprocessedFile)); err != nil {
+		return nil
+	}
+	data, err = os.ReadFile(filepath.Join(dir, cacheHeadersFile))
+	if err != nil {
+		return nil
+	}
+	info := &config.PkgHfilesInfo{
+		Inters: []string{},
+		Impls:  []string{},
+		Thirds: []string{},
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		// kind mtime size file
+		parts := strings.SplitN(line, "\t", 4)
+		if len(parts) != 4 || headerStamp(parts[3]) != parts[1]+"\t"+parts[2] {
+			return nil
+		}
+		switch parts[0] {
+		case "inter":
+			info.Inters = append(info.Inters, parts[3])
+		case "impl":
+			info.Impls = append(info.Impls, parts[3])
+		case "third":
+			info.Thirds = append(info.Thirds, parts[3])
+		default:
+			return nil
+		}
+	}
+	return info
+}
+
+// resetCache creates the cache directory of a package and invalidates its
+// content, before the preprocessed output is written to it.
+func resetCache(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.Remove(filepath.Join(dir, cacheKeyFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// saveCache records the headers of the package and the key in dir, whose
+// preprocessed output is already written. The key is written last, so an
+// interrupted write leaves an invalid cache.
+func saveCache(dir string, key string, info *config.PkgHfilesInfo) error {
+	var b strings.Builder
+	write := func(kind string, files []string) error {
+		for _, file := range files {
+			stamp := headerStamp(file)
+			if stamp == "" {
+				return fmt.Errorf("can't stat header %s", file)
+			}
+			fmt.Fprintf(&b, "%s\t%s\t%s\n", kind, stamp, file)
+		}
+		return nil
+	}
+	if err := write("inter", info.Inters); err != nil {
+		return err
+	}
+	if err := write("impl", info.Impls); err != nil {
+		return err
+	}
+	if err := write("third", info.Thirds); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, cacheHeadersFile), []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, cacheKeyFile), []byte(key), 0644)
+}
+
+// headerStamp returns the modification time and size of a header separated by
+// a tab, or "" if it can't be stat.
+func headerStamp(file string) string {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatInt(fi.ModTime().UnixNano(), 10) + "\t" + strconv.FormatInt(fi.Size(), 10)
+}
@@ -2,9 +2,12 @@ package parse
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
+	"unicode"
 	"unsafe"
 
 	"github.com/goplus/llcppg/_xtool/llcppsigfetch/dbg"
@@ -24,6 +27,23 @@ type Converter struct {
 	unit  *clang.TranslationUnit
 
 	indent int // for verbose debug
+
+	instances map[string]struct{} // spelling of the class template instantiations already emitted
+	inst      *instScope          // the instantiation whose template members are being processed
+
+	stream    io.Writer // if not nil, the top-level nodes are written to it as NDJSON instead of kept in Pkg
+	streamErr error     // the first error of writing to stream
+
+	tempFiles []string // temporary files of the translation unit, removed by Dispose
+
+	targetOS string // GOOS of the target, whose symbols are prefixed with an underscore on darwin
+}
+
+// instScope resolves the template parameters met while processing the members of a
+// class template for one of its instantiations.
+type instScope struct {
+	*clangutils.Instantiation
+	expr *ast.InstantiationType
 }
 
 var tagMap = map[string]ast.Tag{
@@ -36,6 +56,8 @@ var tagMap = map[string]ast.Tag{
 type Config struct {
 	HfileInfo *config.PkgHfilesInfo
 	Cfg       *clangutils.Config
+	Stream    io.Writer // write the output as NDJSON to it while converting
+	TargetOS  string    // GOOS of the target the headers are parsed for, the host if empty
 }
 
 func NewConverter(config *Config) (*Converter, error) {
@@ -47,7 +69,10 @@ func NewConverter(config *Config) (*Converter, error) {
 		fmt.Fprintln(os.Stderr, "config.Temp", config.Cfg.Temp)
 	}
 
-	index, unit, err := clangutils.CreateTranslationUnit(config.Cfg)
+	// the nullability of pointer types is only reported on the attributed types
+	cfg := *config.Cfg
+	cfg.AttributedTypes = true
+	index, unit, err := clangutils.CreateTranslationUnit(&cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +81,10 @@ func NewConverter(config *Config) (*Converter, error) {
 	if err != nil {
 		return nil, err
 	}
+	targetOS := config.TargetOS
+	if targetOS == "" {
+		targetOS = runtime.GOOS
+	}
 	return &Converter{
 		index: index,
 		unit:  unit,
@@ -63,6 +92,9 @@ func NewConverter(config *Config) (*Converter, error) {
 			File:    &ast.File{},
 			FileMap: fileMap,
 		},
+		instances: make(map[string]struct{}),
+		stream:    config.Stream,
+		targetOS:  targetOS,
 	}, nil
 }
 
@@ -97,6 +129,10 @@ func (ct *Converter) Dispose() {
 	ct.logln("Dispose")
 	ct.index.Dispose()
 	ct.unit.Dispose()
+	for _, file := range ct.tempFiles {
+		os.Remove(file)
+	}
+	ct.tempFiles = nil
 }
 
 func (ct *Converter) GetTokens(cursor clang.Cursor) []*ast.Token {
@@ -168,6 +204,7 @@ func (ct *Converter) CreateDeclBase(cursor clang.Cursor) ast.DeclBase {
 	base := ast.DeclBase{
 		Loc:    createLoc(cursor),
 		Parent: ct.BuildScopingExpr(cursor.SemanticParent()),
+		Attrs:  ct.ProcessAttrs(cursor),
 	}
 	commentGroup, isDoc := ct.ParseCommentGroup(cursor)
 	if isDoc {
@@ -176,6 +213,98 @@ func (ct *Converter) CreateDeclBase(cursor clang.Cursor) ast.DeclBase {
 	return base
 }
 
+// ProcessAttrs collects the attributes of a declaration that matter to the bindings.
+// Deprecated and unavailable are taken from the availability of the declaration,
+// the others from its attribute children, which libclang mostly leaves unexposed.
+func (ct *Converter) ProcessAttrs(cursor clang.Cursor) []*ast.Attr {
+	var attrs []*ast.Attr
+	avail := clangutils.PlatformAvailability(cursor)
+	if avail.Deprecated {
+		attrs = append(attrs, &ast.Attr{Kind: ast.DeprecatedAttr, Args: attrMessage(avail.DeprecatedMessage)})
+	}
+	if avail.Unavailable {
+		attrs = append(attrs, &ast.Attr{Kind: ast.UnavailableAttr, Args: attrMessage(avail.UnavailableMessage)})
+	}
+	clangutils.VisitChildren(cursor, func(child, parent clang.Cursor) clang.ChildVisitResult {
+		var attr *ast.Attr
+		switch child.Kind {
+		case clang.CursorPackedAttr:
+			attr = &ast.Attr{Kind: ast.PackedAttr}
+		case clang.CursorVisibilityAttr:
+			attr = &ast.Attr{Kind: ast.VisibilityAttr, Args: []string{toStr(child.String())}}
+		case clang.CursorWarnUnusedResultAttr:
+			attr = &ast.Attr{Kind: ast.WarnUnusedResultAttr}
+		case clang.CursorFlagEnum:
+			attr = &ast.Attr{Kind: ast.FlagEnumAttr}
+		case clang.CursorAlignedAttr, clang.CursorUnexposedAttr:
+			attr = ct.ProcessAttrTokens(child)
+		}
+		if attr != nil {
+			ct.logln("ProcessAttrs:", attr.Kind, attr.Args)
+			attrs = append(attrs, attr)
+		}
+		return clang.ChildVisit_Continue
+	})
+	return attrs
+}
+
+// ProcessAttrTokens recognizes an attribute by its tokens, like: format(printf, 1, 2).
+// Tokenizing works on spelling locations, so attributes written in macros are covered.
+func (ct *Converter) ProcessAttrTokens(cursor clang.Cursor) *ast.Attr {
+	tokens := ct.GetTokens(cursor)
+	// [[gnu::format(printf, 1, 2)]]
+	if len(tokens) > 2 && tokens[1].Lit == "::" {
+		tokens = tokens[2:]
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	var args []string
+	for _, tok := range tokens[1:] {
+		switch tok.Lit {
+		case "(", ")", ",":
+		default:
+			args = append(args, strings.Trim(tok.Lit, "_"))
+		}
+	}
+	switch strings.Trim(tokens[0].Lit, "_") {
+	case "noreturn", "Noreturn":
+		return &ast.Attr{Kind: ast.NoReturnAttr}
+	case "format":
+		return &ast.Attr{Kind: ast.FormatAttr, Args: args}
+	case "nonnull":
+		return &ast.Attr{Kind: ast.NonNullAttr, Args: args}
+	case "aligned", "alignas", "Alignas":
+		return &ast.Attr{Kind: ast.AlignedAttr, Args: args}
+	case "ownership_returns":
+		return &ast.Attr{Kind: ast.OwnershipReturnsAttr, Args: args}
+	case "ownership_takes":
+		return &ast.Attr{Kind: ast.OwnershipTakesAttr, Args: args}
+	case "ownership_holds":
+		return &ast.Attr{Kind: ast.OwnershipHoldsAttr, Args: args}
+	}
+	return nil
+}
+
+func toNullability(kind clangutils.TypeNullabilityKind) ast.Nullability {
+	switch kind {
+	case clangutils.TypeNullabilityNonNull:
+		return ast.Nonnull
+	case clangutils.TypeNullabilityNullable, clangutils.TypeNullabilityNullableResult:
+		return ast.Nullable
+	case clangutils.TypeNullabilityUnspecified:
+		return ast.NullUnspecified
+	}
+	return ast.NullabilityNone
+}
+
+func attrMessage(msg string) []string {
+	if msg == "" {
+		return nil
+	}
+	return []string{msg}
+}
+
 func createLoc(cursor clang.Cursor) *ast.Location {
 	var file clang.String
 	loc := cursor.Location()
@@ -218,6 +347,7 @@ func (ct *Converter) ParseComment(rawComment string) *ast.CommentGroup {
 	for _, line := range lines {
 		commentGroup.List = append(commentGroup.List, &ast.Comment{Text: line + "\n"})
 	}
+	commentGroup.Doxygen = ParseDoxygen(rawComment)
 	return commentGroup
 }
 
@@ -242,17 +372,17 @@ func (ct *Converter) visitTop(cursor, parent clang.Cursor) clang.ChildVisitResul
 			ct.logln(err)
 			return clang.ChildVisit_Continue
 		}
-		ct.Pkg.File.Includes = append(ct.Pkg.File.Includes, include)
+		ct.addInclude(include)
 		ct.logln("visitTop: ProcessInclude END ", include.Path)
 	case clang.CursorMacroDefinition:
 		macro := ct.ProcessMacro(cursor)
 		if cursor.IsMacroBuiltin() == 0 {
-			ct.Pkg.File.Macros = append(ct.Pkg.File.Macros, macro)
+			ct.addMacro(macro)
 		}
 		ct.logln("visitTop: ProcessMacro END ", macro.Name, "Tokens Length:", len(macro.Tokens))
 	case clang.CursorEnumDecl:
 		enum := ct.ProcessEnumDecl(cursor)
-		ct.Pkg.File.Decls = append(ct.Pkg.File.Decls, enum)
+		ct.addDecl(enum)
 		ct.logf("visitTop: ProcessEnumDecl END")
 		if enum.Name != nil {
 			ct.logln(enum.Name.Name)
@@ -261,13 +391,24 @@ func (ct *Converter) visitTop(cursor, parent clang.Cursor) clang.ChildVisitResul
 		}
 
 	case clang.CursorClassDecl:
+		if clangutils.IsInstantiation(cursor.Type()) {
+			// template class Foo<int>;
+			inst := ct.ProcessInstantiationType(cursor.Type())
+			ct.logln("visitTop: ProcessInstantiationType END", inst.Template)
+			return clang.ChildVisit_Continue
+		}
 		classDecl := ct.ProcessClassDecl(cursor)
-		ct.Pkg.File.Decls = append(ct.Pkg.File.Decls, classDecl)
+		ct.addDecl(classDecl)
 		// class havent anonymous situation
 		ct.logln("visitTop: ProcessClassDecl END", classDecl.Name.Name)
 	case clang.CursorStructDecl:
+		if clangutils.IsInstantiation(cursor.Type()) {
+			inst := ct.ProcessInstantiationType(cursor.Type())
+			ct.logln("visitTop: ProcessInstantiationType END", inst.Template)
+			return clang.ChildVisit_Continue
+		}
 		structDecl := ct.ProcessStructDecl(cursor)
-		ct.Pkg.File.Decls = append(ct.Pkg.File.Decls, structDecl)
+		ct.addDecl(structDecl)
 		ct.logf("visitTop: ProcessStructDecl END")
 		if structDecl.Name != nil {
 			ct.logln(structDecl.Name.Name)
@@ -276,27 +417,35 @@ func (ct *Converter) visitTop(cursor, parent clang.Cursor) clang.ChildVisitResul
 		}
 	case clang.CursorUnionDecl:
 		unionDecl := ct.ProcessUnionDecl(cursor)
-		ct.Pkg.File.Decls = append(ct.Pkg.File.Decls, unionDecl)
+		ct.addDecl(unionDecl)
 		ct.logf("visitTop: ProcessUnionDecl END")
 		if unionDecl.Name != nil {
 			ct.logln(unionDecl.Name.Name)
 		} else {
 			ct.logln("ANONY")
 		}
-	case clang.CursorFunctionDecl, clang.CursorCXXMethod, clang.CursorConstructor, clang.CursorDestructor:
+	case clang.CursorFunctionDecl, clang.CursorCXXMethod, clang.CursorConversionFunction, clang.CursorConstructor, clang.CursorDestructor:
 		// Handle functions and class methods (including out-of-class method)
 		// Example: void MyClass::myMethod() { ... } out-of-class method
 		funcDecl := ct.ProcessFuncDecl(cursor)
-		ct.Pkg.File.Decls = append(ct.Pkg.File.Decls, funcDecl)
+		ct.addDecl(funcDecl)
 		ct.logln("visitTop: ProcessFuncDecl END", funcDecl.Name.Name, funcDecl.MangledName, "isStatic:", funcDecl.IsStatic, "isInline:", funcDecl.IsInline)
+	case clang.CursorVarDecl:
+		varDecl := ct.ProcessVarDecl(cursor)
+		if varDecl == nil {
+			return clang.ChildVisit_Continue
+		}
+		ct.addDecl(varDecl)
+		ct.logln("visitTop: ProcessVarDecl END", varDecl.Name.Name, varDecl.MangledName)
 	case clang.CursorTypedefDecl:
 		typedefDecl := ct.ProcessTypeDefDecl(cursor)
 		if typedefDecl == nil {
 			return clang.ChildVisit_Continue
 		}
-		ct.Pkg.File.Decls = append(ct.Pkg.File.Decls, typedefDecl)
+		ct.addDecl(typedefDecl)
 		ct.logln("visitTop: ProcessTypeDefDecl END", typedefDecl.Name.Name)
-	case clang.CursorNamespace:
+	case clang.CursorNamespace, clang.CursorLinkageSpec:
+		// the declarations in an extern "C" block are at the top level too
 		clangutils.VisitChildren(cursor, ct.visitTop)
 	}
 	return clang.ChildVisit_Continue
@@ -305,11 +454,112 @@ func (ct *Converter) visitTop(cursor, parent clang.Cursor) clang.ChildVisitResul
 // for flatten ast,keep type order
 // input is clang -E 's result
 func (ct *Converter) Convert() (*llcppg.Pkg, error) {
+	if ct.stream != nil {
+		if err := ct.writeLine(MarshalPkgHeader(ct.Pkg, ct.declNames())); err != nil {
+			return nil, err
+		}
+	}
 	cursor := ct.unit.Cursor()
 	clangutils.VisitChildren(cursor, ct.visitTop)
+	if ct.streamErr != nil {
+		return nil, ct.streamErr
+	}
 	return ct.Pkg, nil
 }
 
+// declNames returns the qualified names declared at the top level by the decls
+// of each file, like llcppg.DeclNames of the decls visitTop converts. They are
+// written in the header of the NDJSON output, so the Go names of the decls are
+// reserved before any of them is read.
+func (ct *Converter) declNames() map[string][]string {
+	names := make(map[string][]string)
+	var visit clangutils.Visitor
+	visit = func(cursor, parent clang.Cursor) clang.ChildVisitResult {
+		if !ct.InFile(cursor) {
+			return clang.ChildVisit_Continue
+		}
+		file := createLoc(cursor).File
+		scope := clangutils.BuildScopingParts(cursor.SemanticParent())
+		add := func(name string) {
+			names[file] = append(names[file], strings.Join(append(scope[:len(scope):len(scope)], name), "::"))
+		}
+		switch cursor.Kind {
+		case clang.CursorEnumDecl:
+			if cursor.IsAnonymous() == 0 {
+				add(toStr(cursor.String()))
+			}
+			if cursor.IsScoped() == 0 {
+				clangutils.VisitChildren(cursor, func(item, _ clang.Cursor) clang.ChildVisitResult {
+					if item.Kind == clang.CursorEnumConstantDecl {
+						add(toStr(item.String()))
+					}
+					return clang.ChildVisit_Continue
+				})
+			}
+		case clang.CursorClassDecl, clang.CursorStructDecl, clang.CursorUnionDecl:
+			if !clangutils.IsInstantiation(cursor.Type()) && cursor.IsAnonymousRecordDecl() == 0 {
+				add(toStr(cursor.String()))
+			}
+		case clang.CursorTypedefDecl:
+			add(toStr(cursor.String()))
+		case clang.CursorVarDecl:
+			if _, ok := varValue(cursor); ok {
+				add(toStr(cursor.String()))
+			}
+		case clang.CursorNamespace, clang.CursorLinkageSpec:
+			clangutils.VisitChildren(cursor, visit)
+		}
+		return clang.ChildVisit_Continue
+	}
+	clangutils.VisitChildren(ct.unit.Cursor(), visit)
+	return names
+}
+
+// addDecl adds a top-level decl to Pkg, or writes it as a line of the NDJSON
+// output, so the decls of a large header are not all kept in memory.
+func (ct *Converter) addDecl(decl ast.Decl) {
+	if ct.stream == nil {
+		ct.Pkg.File.Decls = append(ct.Pkg.File.Decls, decl)
+		return
+	}
+	ct.emit(MarshalASTDecl(decl))
+}
+
+func (ct *Converter) addInclude(include *ast.Include) {
+	if ct.stream == nil {
+		ct.Pkg.File.Includes = append(ct.Pkg.File.Includes, include)
+		return
+	}
+	ct.emit(MarshalInclude(include))
+}
+
+func (ct *Converter) addMacro(macro *ast.Macro) {
+	if ct.stream == nil {
+		ct.Pkg.File.Macros = append(ct.Pkg.File.Macros, macro)
+		return
+	}
+	ct.emit(MarshalMacro(macro))
+}
+
+// emit writes a node to the NDJSON output, the first error is kept and
+// returned by Convert.
+func (ct *Converter) emit(node *cjson.JSON) {
+	if ct.streamErr != nil {
+		node.Delete()
+		return
+	}
+	ct.streamErr = ct.writeLine(node)
+}
+
+// writeLine writes a node as a line of the NDJSON output and frees it.
+func (ct *Converter) writeLine(node *cjson.JSON) error {
+	defer node.Delete()
+	str := node.PrintUnformatted()
+	defer cjson.FreeCStr(str)
+	_, err := io.WriteString(ct.stream, c.GoString(str)+"\n")
+	return err
+}
+
 func (ct *Converter) ProcessType(t clang.Type) ast.Expr {
 	ct.incIndent()
 	defer ct.decIndent()
@@ -317,11 +567,22 @@ func (ct *Converter) ProcessType(t clang.Type) ast.Expr {
 	typeName, typeKind := getTypeDesc(t)
 	ct.logln("ProcessType: TypeName:", typeName, "TypeKind:", typeKind)
 
+	if ct.inst != nil {
+		if arg, ok := ct.inst.Subst(t); ok {
+			ct.logln("ProcessType: template parameter", typeName)
+			return ct.ProcessType(arg)
+		}
+		if ct.inst.IsSelf(t) {
+			return ct.inst.expr
+		}
+	}
+
 	if t.Kind >= clang.TypeFirstBuiltin && t.Kind <= clang.TypeLastBuiltin {
 		return ct.ProcessBuiltinType(t)
 	}
 
-	if t.Kind == clang.TypeElaborated {
+	// canonical record types come from template arguments
+	if t.Kind == clang.TypeElaborated || t.Kind == clang.TypeRecord || t.Kind == clang.TypeEnum {
 		return ct.ProcessElaboratedType(t)
 	}
 
@@ -329,6 +590,15 @@ func (ct *Converter) ProcessType(t clang.Type) ast.Expr {
 		return ct.ProcessTypeDefType(t)
 	}
 
+	if t.Kind == clang.TypeAttributed {
+		expr := ct.ProcessType(clangutils.ModifiedType(t))
+		if ptr, ok := expr.(*ast.PointerType); ok {
+			ptr.Nullability = toNullability(clangutils.TypeNullability(t))
+			ct.logln("ProcessType: AttributedType Nullability:", ptr.Nullability)
+		}
+		return expr
+	}
+
 	var expr ast.Expr
 	switch t.Kind {
 	case clang.TypePointer:
@@ -373,7 +643,8 @@ func (ct *Converter) ProcessType(t clang.Type) ast.Expr {
 
 // For function types, we can only obtain the parameter types, but not the parameter names.
 // This is because we cannot reverse-lookup the corresponding declaration node from a function type.
-// Note: For function declarations, parameter names are collected in the ProcessFuncDecl method.
+// Note: For function declarations, parameter names are collected in the ProcessFuncDecl method,
+// for function pointer typedefs and fields in the ProcessParamNames method.
 func (ct *Converter) ProcessFunctionType(t clang.Type) *ast.FuncType {
 	ct.incIndent()
 	defer ct.decIndent()
@@ -408,6 +679,55 @@ func (ct *Converter) ProcessFunctionType(t clang.Type) *ast.FuncType {
 	}
 }
 
+// ProcessParamNames collects the parameter names of a function (pointer) type
+// from the ParmDecl children of the declaration using it, like:
+//
+//	typedef int (*lua_CFunction)(lua_State *L);
+//	struct Hooks { void (*free_fn)(void *ptr); };
+func (ct *Converter) ProcessParamNames(cursor clang.Cursor, typ ast.Expr) {
+	fnType, ok := funcTypeOf(typ)
+	if !ok {
+		return
+	}
+	var names []string
+	clangutils.VisitChildren(cursor, func(subcsr, parent clang.Cursor) clang.ChildVisitResult {
+		if subcsr.Kind == clang.CursorParmDecl {
+			names = append(names, toStr(subcsr.String()))
+		}
+		return clang.ChildVisit_Continue
+	})
+	params := fnType.Params.List
+	if n := len(params); n > 0 {
+		if _, ok := params[n-1].Type.(*ast.Variadic); ok {
+			params = params[:n-1]
+		}
+	}
+	// the parameters of a function type behind another typedef aren't children of the cursor
+	if len(names) != len(params) {
+		return
+	}
+	ct.logln("ProcessParamNames:", names)
+	for i, name := range names {
+		if name != "" {
+			params[i].Names = []*ast.Ident{{Name: name}}
+		}
+	}
+}
+
+// funcTypeOf returns the function type of a function or function pointer type.
+func funcTypeOf(typ ast.Expr) (*ast.FuncType, bool) {
+	for {
+		switch t := typ.(type) {
+		case *ast.FuncType:
+			return t, t.Params != nil
+		case *ast.PointerType:
+			typ = t.X
+		default:
+			return nil, false
+		}
+	}
+}
+
 func (ct *Converter) ProcessTypeDefDecl(cursor clang.Cursor) *ast.TypedefDecl {
 	ct.incIndent()
 	defer ct.decIndent()
@@ -423,6 +743,7 @@ func (ct *Converter) ProcessTypeDefDecl(cursor clang.Cursor) *ast.TypedefDecl {
 	if typ == nil {
 		return nil
 	}
+	ct.ProcessParamNames(cursor, typ)
 
 	decl := &ast.TypedefDecl{
 		DeclBase: ct.CreateDeclBase(cursor),
@@ -524,19 +845,29 @@ func (ct *Converter) ProcessFuncDecl(cursor clang.Cursor) *ast.FuncDecl {
 	if fnType.Kind != clang.TypeElaborated {
 		numArgs := cursor.NumArguments()
 		numFields := c.Int(len(funcType.Params.List))
-		for i := c.Int(0); i < numArgs; i++ {
+		for i := c.Int(0); i < numArgs && i < numFields; i++ {
 			arg := cursor.Argument(c.Uint(i))
+			field := funcType.Params.List[i]
 			name := clang.GoString(arg.DisplayName())
-			if len(name) > 0 && i < numFields {
-				field := funcType.Params.List[i]
+			if len(name) > 0 {
 				field.Names = []*ast.Ident{&ast.Ident{Name: name}}
 			}
+			field.Default, field.DefaultTokens = ct.ProcessDefaultArg(arg)
 		}
 	}
 
 	// Linux has one less leading underscore than macOS, so remove one leading underscore on macOS
-	if runtime.GOOS == "darwin" {
-		mangledName = strings.TrimPrefix(mangledName, "_")
+	mangledName = clangutils.TrimMangling(ct.targetOS, mangledName)
+
+	// a member of a class template, libclang can't mangle it for the instantiation
+	if ct.inst != nil && isMethod(cursor) {
+		var err error
+		mangledName, err = ct.inst.MangleMember(cursor)
+		if err != nil {
+			ct.logln("ProcessFuncDecl:", err)
+			return nil
+		}
+		ct.logln("ProcessFuncDecl: instantiated mangledName:", mangledName)
 	}
 
 	funcDecl := &ast.FuncDecl{
@@ -549,6 +880,7 @@ func (ct *Converter) ProcessFuncDecl(cursor clang.Cursor) *ast.FuncDecl {
 	if cursor.IsFunctionInlined() != 0 {
 		funcDecl.IsInline = true
 	}
+	funcDecl.IsExternC = clangutils.IsExternC(cursor)
 
 	if isMethod(cursor) {
 		ct.logln("ProcessFuncDecl: is method, ProcessMethodAttributes")
@@ -562,6 +894,72 @@ func (ct *Converter) ProcessFuncDecl(cursor clang.Cursor) *ast.FuncDecl {
 	return funcDecl
 }
 
+// ProcessDefaultArg returns the default argument of a parameter, which is
+// the tokens after the '=' of the declaration, like: int mode = 0644.
+// A single literal is returned as a BasicLit, other expressions as raw tokens.
+func (ct *Converter) ProcessDefaultArg(cursor clang.Cursor) (ast.Expr, []*ast.Token) {
+	tokens := ct.GetTokens(cursor)
+	depth := 0
+	for i, tok := range tokens {
+		switch tok.Lit {
+		case "(", "[", "{":
+			depth++
+		case ")", "]", "}":
+			depth--
+		case "=":
+			if depth != 0 {
+				continue
+			}
+			value := tokens[i+1:]
+			if len(value) == 0 {
+				return nil, nil
+			}
+			ct.logln("ProcessDefaultArg:", value[0].Lit, "tokens:", len(value))
+			if lit := toBasicLit(value); lit != nil {
+				return lit, nil
+			}
+			return nil, value
+		}
+	}
+	return nil, nil
+}
+
+// toBasicLit converts a literal token, optionally signed, to a BasicLit.
+func toBasicLit(tokens []*ast.Token) *ast.BasicLit {
+	sign := ""
+	if len(tokens) == 2 && (tokens[0].Lit == "-" || tokens[0].Lit == "+") {
+		sign = tokens[0].Lit
+		tokens = tokens[1:]
+	}
+	if len(tokens) != 1 || tokens[0].Token != token.LITERAL {
+		return nil
+	}
+	lit := tokens[0].Lit
+	kind := literalKind(lit)
+	if sign != "" && kind != ast.IntLit && kind != ast.FloatLit {
+		return nil
+	}
+	return &ast.BasicLit{Kind: kind, Value: sign + lit}
+}
+
+func literalKind(lit string) ast.BasicLitKind {
+	lower := strings.ToLower(lit)
+	switch {
+	case strings.HasSuffix(lit, "\""):
+		return ast.StringLit
+	case strings.HasSuffix(lit, "'") && !unicode.IsDigit(rune(lit[0])):
+		return ast.CharLit
+	case strings.HasPrefix(lower, "0x"):
+		if strings.Contains(lower, "p") {
+			return ast.FloatLit
+		}
+		return ast.IntLit
+	case strings.ContainsAny(lower, ".e"):
+		return ast.FloatLit
+	}
+	return ast.IntLit
+}
+
 // get Methods Attributes
 func (ct *Converter) ProcessMethodAttributes(cursor clang.Cursor, fn *ast.FuncDecl) {
 	if parent := cursor.SemanticParent(); parent.Equal(cursor.LexicalParent()) != 1 {
@@ -600,20 +998,26 @@ func (ct *Converter) ProcessMethodAttributes(cursor clang.Cursor, fn *ast.FuncDe
 func (ct *Converter) ProcessEnumType(cursor clang.Cursor) *ast.EnumType {
 	items := make([]*ast.EnumItem, 0)
 
+	intType := clangutils.EnumDeclIntegerType(cursor).CanonicalType()
+	unsigned := isUnsignedKind(intType.Kind)
+
 	clangutils.VisitChildren(cursor, func(cursor, parent clang.Cursor) clang.ChildVisitResult {
 		if cursor.Kind == clang.CursorEnumConstantDecl {
 			name := cursor.String()
 			defer name.Dispose()
 
-			val := (*c.Char)(c.Malloc(unsafe.Sizeof(c.Char(0)) * 20))
-			c.Sprintf(val, c.Str("%lld"), cursor.EnumConstantDeclValue())
-			defer c.Free(unsafe.Pointer(val))
+			var val string
+			if unsigned {
+				val = strconv.FormatUint(clangutils.EnumConstantDeclUnsignedValue(cursor), 10)
+			} else {
+				val = strconv.FormatInt(int64(cursor.EnumConstantDeclValue()), 10)
+			}
 
 			enum := &ast.EnumItem{
 				Name: &ast.Ident{Name: c.GoString(name.CStr())},
 				Value: &ast.BasicLit{
 					Kind:  ast.IntLit,
-					Value: c.GoString(val),
+					Value: val,
 				},
 			}
 			items = append(items, enum)
@@ -621,9 +1025,40 @@ func (ct *Converter) ProcessEnumType(cursor clang.Cursor) *ast.EnumType {
 		return clang.ChildVisit_Continue
 	})
 
-	return &ast.EnumType{
-		Items: items,
+	enumType := &ast.EnumType{
+		Items:  items,
+		Scoped: cursor.IsScoped() != 0,
+	}
+	// the integer type is only recorded if it's written and isn't the default int,
+	// clang chooses one for the other enums, like unsigned int for non-negative values
+	if intType.Kind != clang.TypeInt && intType.Kind != clang.TypeInvalid && ct.hasFixedIntType(cursor) {
+		enumType.IntType = ct.ProcessType(intType)
+		ct.logln("ProcessEnumType: IntType", toStr(intType.String()))
+	}
+	return enumType
+}
+
+// hasFixedIntType reports whether the integer type of an enum is written,
+// like: enum Color : unsigned char { ... };
+func (ct *Converter) hasFixedIntType(cursor clang.Cursor) bool {
+	for _, tok := range ct.GetTokens(cursor) {
+		switch tok.Lit {
+		case ":":
+			return true
+		case "{", ";":
+			return false
+		}
+	}
+	return false
+}
+
+func isUnsignedKind(kind clang.TypeKind) bool {
+	switch kind {
+	case clang.TypeBool, clang.TypeCharU, clang.TypeUChar, clang.TypeChar16, clang.TypeChar32,
+		clang.TypeUShort, clang.TypeUInt, clang.TypeULong, clang.TypeULongLong, clang.TypeUInt128:
+		return true
 	}
+	return false
 }
 
 func (ct *Converter) ProcessEnumDecl(cursor clang.Cursor) *ast.EnumTypeDecl {
@@ -680,6 +1115,7 @@ func (ct *Converter) createBaseField(cursor clang.Cursor) *ast.Field {
 	field := &ast.Field{
 		Type: ct.ProcessType(typ),
 	}
+	ct.ProcessParamNames(cursor, field.Type)
 
 	commentGroup, isDoc := ct.ParseCommentGroup(cursor)
 	if commentGroup != nil {
@@ -714,6 +1150,9 @@ func (ct *Converter) ProcessFieldList(cursor clang.Cursor) *ast.FieldList {
 			ct.logln("ProcessFieldList: CursorFieldDecl")
 			field := ct.createBaseField(subcsr)
 			field.Access = ast.AccessSpecifier(subcsr.CXXAccessSpecifier())
+			if offset := clangutils.CursorOffsetOfField(subcsr); offset > 0 {
+				field.Offset = offset
+			}
 			flds.List = append(flds.List, field)
 		case clang.CursorVarDecl:
 			if subcsr.StorageClass() == clang.SCStatic {
@@ -729,6 +1168,66 @@ func (ct *Converter) ProcessFieldList(cursor clang.Cursor) *ast.FieldList {
 	return flds
 }
 
+// ProcessVarDecl converts a variable declaration. A const variable with a constant
+// initializer, like `static const int kMaxSize = 64;` or `constexpr double kPi = 3.14;`,
+// keeps its value, other variables are kept only if they can be linked.
+func (ct *Converter) ProcessVarDecl(cursor clang.Cursor) *ast.VarDecl {
+	ct.incIndent()
+	defer ct.decIndent()
+	name, kind := getCursorDesc(cursor)
+	typ := cursor.Type()
+	ct.logln("ProcessVarDecl: CursorName:", name, "CursorKind:", kind)
+
+	value, ok := varValue(cursor)
+	if !ok {
+		ct.logln("ProcessVarDecl: neither a constant nor an external variable")
+		return nil
+	}
+
+	mangledName := clangutils.TrimMangling(ct.targetOS, toStr(cursor.Mangling()))
+	return &ast.VarDecl{
+		DeclBase:    ct.CreateDeclBase(cursor),
+		Name:        &ast.Ident{Name: name},
+		MangledName: mangledName,
+		Type:        ct.ProcessType(typ),
+		Value:       value,
+	}
+}
+
+// varValue returns the value of a const variable with a constant initializer, and
+// whether the variable is converted, which is a constant or an external variable
+// but not the definition of a static member variable, which is a field of its class.
+func varValue(cursor clang.Cursor) (*ast.BasicLit, bool) {
+	if parent := cursor.SemanticParent(); parent.Kind == clang.CursorClassDecl || parent.Kind == clang.CursorStructDecl {
+		return nil, false
+	}
+	var value *ast.BasicLit
+	if cursor.Type().IsConstQualifiedType() != 0 {
+		value = evalResultLit(clangutils.Evaluate(cursor))
+	}
+	if value == nil && clangutils.CursorLinkage(cursor) != clangutils.LinkageExternal {
+		return nil, false
+	}
+	return value, true
+}
+
+// evalResultLit returns the literal of an evaluated integer, floating or
+// string constant, or nil for other results.
+func evalResultLit(res clangutils.EvalResult) *ast.BasicLit {
+	switch res.Kind {
+	case clangutils.EvalInt:
+		if res.Unsigned {
+			return &ast.BasicLit{Kind: ast.IntLit, Value: strconv.FormatUint(uint64(res.Int), 10)}
+		}
+		return &ast.BasicLit{Kind: ast.IntLit, Value: strconv.FormatInt(res.Int, 10)}
+	case clangutils.EvalFloat:
+		return &ast.BasicLit{Kind: ast.FloatLit, Value: strconv.FormatFloat(res.Float, 'g', -1, 64)}
+	case clangutils.EvalStrLiteral:
+		return &ast.BasicLit{Kind: ast.StringLit, Value: strconv.Quote(res.Str)}
+	}
+	return nil
+}
+
 // Note:Public Method is considered
 func (ct *Converter) ProcessMethods(cursor clang.Cursor) []*ast.FuncDecl {
 	methods := make([]*ast.FuncDecl, 0)
@@ -807,11 +1306,109 @@ func (ct *Converter) ProcessRecordType(cursor clang.Cursor) *ast.RecordType {
 	ct.logln("ProcessRecordType: ProcessMethods")
 	methods := ct.ProcessMethods(cursor)
 
+	ct.logln("ProcessRecordType: ProcessBases")
+	bases := ct.ProcessBases(cursor)
+
+	size, align := ct.ProcessRecordLayout(cursor)
+	ct.logln("ProcessRecordType: Size", size, "Align", align)
+
 	return &ast.RecordType{
-		Tag:     tag,
-		Fields:  fields,
-		Methods: methods,
+		Tag:         tag,
+		Fields:      fields,
+		Methods:     methods,
+		Bases:       bases,
+		Polymorphic: isPolymorphic(cursor),
+		Abstract:    cursor.IsAbstract() != 0,
+		Size:        size,
+		Align:       align,
+		Packed:      isPacked(cursor, align),
+		IsForward:   !clangutils.IsCursorDefinition(cursor),
+	}
+}
+
+// isPolymorphic reports whether the objects of a class have a vtable pointer,
+// that is it declares a virtual method, including the non-public ones,
+// or has a virtual or polymorphic base.
+func isPolymorphic(cursor clang.Cursor) bool {
+	polymorphic := false
+	clangutils.VisitChildren(cursor, func(subcsr, parent clang.Cursor) clang.ChildVisitResult {
+		switch {
+		case isMethod(subcsr):
+			polymorphic = subcsr.IsVirtual() != 0 || subcsr.IsPureVirtual() != 0
+		case subcsr.Kind == clang.CursorCXXBaseSpecifier:
+			polymorphic = clangutils.IsVirtualBase(subcsr) || isPolymorphic(subcsr.Type().TypeDeclaration())
+		}
+		if polymorphic {
+			return clang.ChildVisit_Break
+		}
+		return clang.ChildVisit_Continue
+	})
+	return polymorphic
+}
+
+// ProcessBases returns the base classes of a C++ class in declaration order.
+func (ct *Converter) ProcessBases(cursor clang.Cursor) []*ast.BaseSpec {
+	var bases []*ast.BaseSpec
+	clangutils.VisitChildren(cursor, func(subcsr, parent clang.Cursor) clang.ChildVisitResult {
+		if subcsr.Kind != clang.CursorCXXBaseSpecifier {
+			return clang.ChildVisit_Continue
+		}
+		base := &ast.BaseSpec{
+			Type:    ct.ProcessType(subcsr.Type()),
+			Access:  ast.AccessSpecifier(subcsr.CXXAccessSpecifier()),
+			Virtual: clangutils.IsVirtualBase(subcsr),
+		}
+		if !base.Virtual {
+			if offset := clangutils.OffsetOfBase(cursor, subcsr); offset > 0 {
+				base.Offset = offset
+			}
+		}
+		ct.logln("ProcessBases: base", clang.GoString(subcsr.Type().String()), "virtual:", base.Virtual)
+		bases = append(bases, base)
+		return clang.ChildVisit_Continue
+	})
+	return bases
+}
+
+// ProcessRecordLayout returns the size and alignment of a record declaration in bytes.
+// Both are 0 if clang can't lay it out, or if it has bit-fields, whose layout the
+// generated Go struct can't express.
+func (ct *Converter) ProcessRecordLayout(cursor clang.Cursor) (size, align int64) {
+	hasBitField := false
+	clangutils.VisitChildren(cursor, func(subcsr, parent clang.Cursor) clang.ChildVisitResult {
+		if subcsr.Kind == clang.CursorFieldDecl && clangutils.FieldDeclBitWidth(subcsr) >= 0 {
+			hasBitField = true
+			return clang.ChildVisit_Break
+		}
+		return clang.ChildVisit_Continue
+	})
+	if hasBitField {
+		return 0, 0
+	}
+	typ := cursor.Type()
+	size = int64(typ.SizeOf())
+	align = clangutils.TypeAlignOf(typ)
+	if size <= 0 || align <= 0 {
+		return 0, 0
+	}
+	return size, align
+}
+
+// isPacked reports whether a record is aligned less than the type of one of its
+// fields, that is it is packed by an attribute or a #pragma pack.
+func isPacked(cursor clang.Cursor, align int64) bool {
+	if align <= 0 {
+		return false
 	}
+	packed := false
+	clangutils.VisitChildren(cursor, func(subcsr, parent clang.Cursor) clang.ChildVisitResult {
+		if subcsr.Kind == clang.CursorFieldDecl && clangutils.TypeAlignOf(subcsr.Type()) > align {
+			packed = true
+			return clang.ChildVisit_Break
+		}
+		return clang.ChildVisit_Continue
+	})
+	return packed
 }
 
 // process ElaboratedType Reference
@@ -831,6 +1428,10 @@ func (ct *Converter) ProcessElaboratedType(t clang.Type) ast.Expr {
 
 	decl := t.TypeDeclaration()
 
+	if clangutils.IsInstantiation(t) {
+		return ct.ProcessInstantiationType(t)
+	}
+
 	if decl.IsAnonymous() != 0 {
 		// anonymous type refer (except anonymous RecordType&EnumType in TypedefDecl)
 		if decl.Kind == clang.CursorEnumDecl {
@@ -854,6 +1455,106 @@ func (ct *Converter) ProcessElaboratedType(t clang.Type) ast.Expr {
 	return ct.BuildScopingExpr(decl)
 }
 
+// ProcessInstantiationType converts a reference to a class template specialization,
+// like Foo<int>, and emits the ast.InstantiationDecl describing it the first time
+// the specialization is met.
+func (ct *Converter) ProcessInstantiationType(t clang.Type) *ast.InstantiationType {
+	ct.incIndent()
+	defer ct.decIndent()
+	typeName, typeKind := getTypeDesc(t)
+	ct.logln("ProcessInstantiationType: TypeName:", typeName, "TypeKind:", typeKind)
+
+	inst := clangutils.NewInstantiation(t)
+	expr := &ast.InstantiationType{
+		Template: ct.BuildScopingExpr(inst.Template),
+		Args:     ct.ProcessTemplateArgs(inst),
+	}
+
+	spelling := inst.Spelling()
+	if _, ok := ct.instances[spelling]; ok {
+		return expr
+	}
+	// templates of third party headers are left to their own package
+	if info, ok := ct.Pkg.FileMap[createLoc(inst.Template).File]; !ok || info.FileType == llcppg.Third {
+		ct.logln("ProcessInstantiationType: template out of package", spelling)
+		return expr
+	}
+	ct.instances[spelling] = struct{}{}
+
+	decl := &ast.InstantiationDecl{
+		DeclBase: ct.CreateDeclBase(inst.Template),
+		Name:     &ast.Ident{Name: spelling},
+		Template: expr,
+	}
+	decl.Type = ct.ProcessInstantiationRecord(inst, expr)
+	ct.addDecl(decl)
+	ct.logln("ProcessInstantiationType: emit", spelling)
+	return expr
+}
+
+func (ct *Converter) ProcessTemplateArgs(inst *clangutils.Instantiation) *ast.FieldList {
+	args := &ast.FieldList{}
+	num := clangutils.NumTemplateArguments(inst.Type)
+	for i := 0; i < num; i++ {
+		var arg ast.Expr
+		if typ := clangutils.TemplateArgumentAsType(inst.Type, i); typ.Kind != clang.TypeInvalid {
+			arg = ct.ProcessType(typ)
+		} else if clangutils.CursorTemplateArgumentKind(inst.Decl, i) == clangutils.TemplateArgumentIntegral {
+			arg = &ast.BasicLit{
+				Kind:  ast.IntLit,
+				Value: strconv.FormatInt(clangutils.CursorTemplateArgumentValue(inst.Decl, i), 10),
+			}
+		} else {
+			ct.logln("ProcessTemplateArgs: unsupported template argument", i)
+		}
+		args.List = append(args.List, &ast.Field{Type: arg})
+	}
+	return args
+}
+
+// ProcessInstantiationRecord collects the fields and methods of a class template instantiation.
+// Explicit specializations are plain records for libclang, for the others the fields are
+// visited through the instantiated type and the methods through the template.
+func (ct *Converter) ProcessInstantiationRecord(inst *clangutils.Instantiation, expr *ast.InstantiationType) *ast.RecordType {
+	ct.incIndent()
+	defer ct.decIndent()
+
+	if inst.IsExplicitSpecialization() {
+		ct.logln("ProcessInstantiationRecord: explicit specialization")
+		return ct.ProcessRecordType(inst.Decl)
+	}
+
+	record := &ast.RecordType{
+		Tag:     toTag(inst.Decl.Kind),
+		Fields:  &ast.FieldList{},
+		Methods: []*ast.FuncDecl{},
+	}
+	members := inst.Members()
+	if members.Kind == clang.CursorNoDeclFound {
+		ct.logln("ProcessInstantiationRecord: members can't be resolved", inst.Spelling())
+		record.IsForward = true
+		return record
+	}
+
+	prev := ct.inst
+	ct.inst = &instScope{Instantiation: inst, expr: expr}
+	defer func() { ct.inst = prev }()
+
+	clangutils.VisitFields(inst.Type, func(cursor clang.Cursor) bool {
+		field := ct.createBaseField(cursor)
+		field.Access = ast.AccessSpecifier(cursor.CXXAccessSpecifier())
+		record.Fields.List = append(record.Fields.List, field)
+		return true
+	})
+	if len(record.Fields.List) == 0 {
+		// the class was never required to be complete, so it is not instantiated
+		ct.logln("ProcessInstantiationRecord: fields from template")
+		record.Fields = ct.ProcessFieldList(members)
+	}
+	record.Methods = ct.ProcessMethods(members)
+	return record
+}
+
 func (ct *Converter) ProcessTypeDefType(t clang.Type) ast.Expr {
 	cursor := t.TypeDeclaration()
 	ct.logln("ProcessTypeDefType: Typedef TypeDeclaration", toStr(cursor.String()), toStr(t.String()))
@@ -981,7 +1682,7 @@ func toToken(tok clang.Token) token.Token {
 	}
 }
 func isMethod(cursor clang.Cursor) bool {
-	return cursor.Kind == clang.CursorCXXMethod || cursor.Kind == clang.CursorConstructor || cursor.Kind == clang.CursorDestructor
+	return cursor.Kind == clang.CursorCXXMethod || cursor.Kind == clang.CursorConversionFunction || cursor.Kind == clang.CursorConstructor || cursor.Kind == clang.CursorDestructor
 }
 
 func buildScopingFromParts(parts []string) ast.Expr {
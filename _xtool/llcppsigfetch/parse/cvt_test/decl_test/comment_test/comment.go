@@ -73,6 +73,24 @@ class Doc
   protected:
     int value;       /*!< protected field comment */
 };`,
+		`
+/**
+ * \brief Adds two numbers.
+ *
+ * The sum may overflow.
+ * @param[in] a the first number
+ * @param[in] b the second number
+ * @return the sum of a and b
+ */
+int add(int a, int b);`,
+		`
+/// @brief Frees a buffer.
+/// @param buf the buffer,
+///        may be NULL
+/// @returns 0 on success
+/// @retval -1 if buf is in use
+/// @see alloc
+int release(void *buf);`,
 	}
 	test.RunTest("TestDoc", testCases)
 }
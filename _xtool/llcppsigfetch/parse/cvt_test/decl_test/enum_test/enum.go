@@ -28,6 +28,14 @@ func TestEnumDecl() {
 			b,
 			c,
 		};`,
+		`enum Color : unsigned char {
+			Red,
+			Green,
+		};`,
+		`enum class Mode {
+			Off = -1,
+			On,
+		};`,
 	}
 	test.RunTest("TestEnumDecl", testCases)
 }
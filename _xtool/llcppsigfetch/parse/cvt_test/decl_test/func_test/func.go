@@ -31,6 +31,10 @@ func TestFuncDecl() {
 		                                void **provctx);
 		OSSL_provider_init_fn OSSL_provider_init;
 		   `,
+		`int *_Nonnull foo(int *_Nullable p);`,
+		`extern "C" {
+		 void foo(int a);
+		 }`,
 	}
 	test.RunTest("TestFuncDecl", testCases)
 }
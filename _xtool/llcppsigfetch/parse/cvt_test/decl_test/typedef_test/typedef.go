@@ -54,6 +54,8 @@ func TestTypeDefDecl() {
 				} MyStruct,MyStruct2,*StructPtr, StructArr[];
 			}
 		}`,
+
+		`typedef int (*Foo)(int a, int, ...);`,
 	}
 	test.RunTest("TestTypeDefDecl", testCases)
 }
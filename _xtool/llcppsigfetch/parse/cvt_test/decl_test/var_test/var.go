@@ -0,0 +1,19 @@
+package main
+
+import test "github.com/goplus/llcppg/_xtool/llcppsigfetch/parse/cvt_test"
+
+func main() {
+	TestVarDecl()
+}
+
+func TestVarDecl() {
+	testCases := []string{
+		`static const int kMaxSize = 64;
+		 constexpr double kPi = 3.14;
+		 const char *const kName = "foo";`,
+
+		`extern int count;
+		 static int hidden;`,
+	}
+	test.RunTest("TestVarDecl", testCases)
+}
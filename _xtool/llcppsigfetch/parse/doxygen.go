@@ -0,0 +1,259 @@
+package parse
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/goplus/llcppg/ast"
+)
+
+type docSection int
+
+const (
+	docDetail docSection = iota
+	docBrief
+	docParam
+	docReturn
+	docDeprecated
+	docSee
+)
+
+// docInlineCmds are the commands that style the word after them, like: \p name
+var docInlineCmds = map[string]bool{
+	"a": true, "b": true, "c": true, "e": true, "em": true, "p": true, "ref": true, "link": true,
+}
+
+// docSkipCmds are the structural commands, whose lines are left out.
+var docSkipCmds = map[string]bool{
+	"file": true, "fn": true, "struct": true, "union": true, "enum": true, "class": true,
+	"namespace": true, "def": true, "typedef": true, "var": true, "name": true,
+	"ingroup": true, "defgroup": true, "addtogroup": true, "weakgroup": true,
+	"{": true, "}": true,
+}
+
+var docInlineRe = regexp.MustCompile(`[@\\](?:a|b|c|e|em|p|ref|link)\s+|[@\\]endlink\b`)
+
+// ParseDoxygen parses a Doxygen comment, like /** ... */, /*! ... */, /// or //!,
+// into its sections. It returns nil if the comment is not a Doxygen comment.
+func ParseDoxygen(rawComment string) *ast.DocComment {
+	lines := doxygenLines(rawComment)
+	if lines == nil {
+		return nil
+	}
+	p := &docParser{doc: &ast.DocComment{}}
+	for _, line := range lines {
+		p.line(line)
+	}
+	p.endCode()
+	p.flush()
+	return p.doc
+}
+
+// doxygenLines returns the lines of a Doxygen comment without the comment markers,
+// or nil if the comment is not a Doxygen comment.
+func doxygenLines(rawComment string) []string {
+	raw := strings.TrimSpace(rawComment)
+	lines := strings.Split(raw, "\n")
+	if strings.HasPrefix(raw, "/*") {
+		if !(strings.HasPrefix(raw, "/**") || strings.HasPrefix(raw, "/*!")) ||
+			strings.HasPrefix(raw, "/***") || raw == "/**/" {
+			return nil
+		}
+		for i, line := range lines {
+			line = strings.TrimSuffix(strings.TrimSpace(line), "*/")
+			if strings.HasPrefix(line, "/**") || strings.HasPrefix(line, "/*!") {
+				// adjacent comments are merged, like: /** doc 1 */\n/** doc 2 */
+				line = trimDocMarker(line)
+			} else {
+				line = strings.TrimPrefix(line, "*")
+			}
+			lines[i] = docLine(line)
+		}
+		return lines
+	}
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		if !strings.HasPrefix(line, "///") && !strings.HasPrefix(line, "//!") {
+			return nil
+		}
+		lines[i] = docLine(trimDocMarker(line))
+	}
+	return lines
+}
+
+// trimDocMarker removes the leading marker of a comment, like /** or ///<.
+func trimDocMarker(line string) string {
+	return strings.TrimPrefix(line[3:], "<")
+}
+
+func docLine(line string) string {
+	line = strings.TrimRight(strings.TrimPrefix(line, " "), " \t\r")
+	if strings.Trim(line, "/*") == "" {
+		// separator lines, like: ///////
+		return ""
+	}
+	return line
+}
+
+type docParser struct {
+	doc    *ast.DocComment
+	sec    docSection    // section of the current paragraph
+	param  *ast.DocParam // the current @param
+	text   []string      // lines of the current paragraph
+	code   []string      // lines of the current @code block
+	inCode bool
+}
+
+func (p *docParser) line(line string) {
+	if p.inCode {
+		if cmd, _, _, ok := docCommand(strings.TrimSpace(line)); ok && (cmd == "endcode" || cmd == "endverbatim") {
+			p.endCode()
+		} else {
+			p.code = append(p.code, line)
+		}
+		return
+	}
+	text := strings.TrimSpace(line)
+	if text == "" {
+		p.flush()
+		p.sec = docDetail
+		return
+	}
+	if cmd, dir, rest, ok := docCommand(text); ok && !docInlineCmds[cmd] {
+		if docSkipCmds[cmd] {
+			return
+		}
+		p.flush()
+		text = rest
+		switch cmd {
+		case "brief", "short":
+			p.sec = docBrief
+		case "param", "tparam":
+			name, rest, _ := strings.Cut(rest, " ")
+			p.param = &ast.DocParam{Name: name, Direction: dir}
+			p.doc.Params = append(p.doc.Params, p.param)
+			p.sec = docParam
+			text = strings.TrimSpace(rest)
+		case "return", "returns", "result", "retval":
+			p.sec = docReturn
+		case "deprecated":
+			p.doc.Deprecated = &ast.DocBlock{}
+			p.sec = docDeprecated
+		case "see", "sa":
+			p.sec = docSee
+		case "note", "warning", "remark", "remarks", "attention":
+			p.sec = docDetail
+			text = strings.ToUpper(cmd[:1]) + cmd[1:] + ": " + rest
+		case "code", "verbatim":
+			p.inCode = true
+			p.code = nil
+			return
+		default:
+			p.sec = docDetail
+		}
+		if text == "" {
+			return
+		}
+	}
+	p.text = append(p.text, text)
+}
+
+// docCommand splits a line that starts with a command, like: @param[in] name text
+func docCommand(line string) (cmd, dir, rest string, ok bool) {
+	if len(line) < 2 || (line[0] != '@' && line[0] != '\\') {
+		return
+	}
+	i := 1
+	for i < len(line) && isLetter(line[i]) {
+		i++
+	}
+	if i == 1 {
+		if line[1] != '{' && line[1] != '}' {
+			return
+		}
+		i = 2
+	}
+	cmd, rest = line[1:i], line[i:]
+	if strings.HasPrefix(rest, "[") {
+		if end := strings.Index(rest, "]"); end > 0 {
+			dir, rest = strings.ReplaceAll(rest[1:end], " ", ""), rest[end+1:]
+		}
+	}
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		// not a command, like: @foo.bar
+		return "", "", "", false
+	}
+	return cmd, dir, strings.TrimSpace(rest), true
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// flush ends the current paragraph.
+func (p *docParser) flush() {
+	if len(p.text) == 0 {
+		return
+	}
+	text := docInlineRe.ReplaceAllString(strings.Join(p.text, " "), "")
+	p.text = nil
+	switch p.sec {
+	case docBrief:
+		p.doc.Brief = joinDoc(p.doc.Brief, text)
+	case docParam:
+		p.param.Text = joinDoc(p.param.Text, text)
+	case docReturn:
+		// each @return and @retval is a paragraph
+		if p.doc.Return != "" {
+			text = "; " + text
+		}
+		p.doc.Return += text
+	case docDeprecated:
+		p.doc.Deprecated.Text = joinDoc(p.doc.Deprecated.Text, text)
+	case docSee:
+		for _, see := range strings.Split(text, ",") {
+			if see = strings.TrimSpace(see); see != "" {
+				p.doc.See = append(p.doc.See, see)
+			}
+		}
+	default:
+		p.doc.Blocks = append(p.doc.Blocks, &ast.DocBlock{Text: text})
+	}
+}
+
+// endCode ends the current @code block, the common indent of its lines is removed.
+func (p *docParser) endCode() {
+	if !p.inCode {
+		return
+	}
+	p.inCode = false
+	for len(p.code) > 0 && strings.TrimSpace(p.code[len(p.code)-1]) == "" {
+		p.code = p.code[:len(p.code)-1]
+	}
+	indent := -1
+	for _, line := range p.code {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	for i, line := range p.code {
+		if len(line) >= indent && indent > 0 {
+			p.code[i] = line[indent:]
+		}
+	}
+	if len(p.code) > 0 {
+		p.doc.Blocks = append(p.doc.Blocks, &ast.DocBlock{Text: strings.Join(p.code, "\n"), Code: true})
+	}
+	p.code = nil
+}
+
+func joinDoc(text, more string) string {
+	if text == "" {
+		return more
+	}
+	return text + " " + more
+}
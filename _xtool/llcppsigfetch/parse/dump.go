@@ -9,11 +9,36 @@ import (
 
 func MarshalPkg(pkg *llcppg.Pkg) *cjson.JSON {
 	root := cjson.Object()
+	root.SetItem(c.Str("_Version"), numberField(llcppg.SchemaVersion))
 	root.SetItem(c.Str("File"), MarshalASTFile(pkg.File))
 	root.SetItem(c.Str("FileMap"), MarshalFileMap(pkg.FileMap))
 	return root
 }
 
+// MarshalPkgHeader marshals the header line of the NDJSON output of a Pkg with
+// the names declared by the decls of each file, the following lines are its
+// includes, macros and decls.
+func MarshalPkgHeader(pkg *llcppg.Pkg, names map[string][]string) *cjson.JSON {
+	root := cjson.Object()
+	root.SetItem(c.Str("_Type"), stringField("Pkg"))
+	root.SetItem(c.Str("_Version"), numberField(llcppg.SchemaVersion))
+	root.SetItem(c.Str("FileMap"), MarshalFileMap(pkg.FileMap))
+	root.SetItem(c.Str("Names"), MarshalNames(names))
+	return root
+}
+
+func MarshalNames(names map[string][]string) *cjson.JSON {
+	root := cjson.Object()
+	for file, list := range names {
+		items := cjson.Array()
+		for _, name := range list {
+			items.AddItem(stringField(name))
+		}
+		root.SetItem(c.AllocaCStr(file), items)
+	}
+	return root
+}
+
 func MarshalFileMap(fmap map[string]*llcppg.FileInfo) *cjson.JSON {
 	root := cjson.Object()
 	for path, info := range fmap {
@@ -50,27 +75,35 @@ func MarshalFieldList(list []*ast.Field) *cjson.JSON {
 func MarshalIncludeList(list []*ast.Include) *cjson.JSON {
 	root := cjson.Array()
 	for _, item := range list {
-		include := cjson.Object()
-		include.SetItem(c.Str("_Type"), stringField("Include"))
-		include.SetItem(c.Str("Path"), stringField(item.Path))
-		root.AddItem(include)
+		root.AddItem(MarshalInclude(item))
 	}
 	return root
 }
 
+func MarshalInclude(include *ast.Include) *cjson.JSON {
+	root := cjson.Object()
+	root.SetItem(c.Str("_Type"), stringField("Include"))
+	root.SetItem(c.Str("Path"), stringField(include.Path))
+	return root
+}
+
 func MarshalMacroList(list []*ast.Macro) *cjson.JSON {
 	root := cjson.Array()
 	for _, item := range list {
-		macro := cjson.Object()
-		macro.SetItem(c.Str("_Type"), stringField("Macro"))
-		macro.SetItem(c.Str("Loc"), MarshalLocation(item.Loc))
-		macro.SetItem(c.Str("Name"), stringField(item.Name))
-		macro.SetItem(c.Str("Tokens"), MarshalTokenList(item.Tokens))
-		root.AddItem(macro)
+		root.AddItem(MarshalMacro(item))
 	}
 	return root
 }
 
+func MarshalMacro(macro *ast.Macro) *cjson.JSON {
+	root := cjson.Object()
+	root.SetItem(c.Str("_Type"), stringField("Macro"))
+	root.SetItem(c.Str("Loc"), MarshalLocation(macro.Loc))
+	root.SetItem(c.Str("Name"), stringField(macro.Name))
+	root.SetItem(c.Str("Tokens"), MarshalTokenList(macro.Tokens))
+	return root
+}
+
 func MarshalTokenList(list []*ast.Token) *cjson.JSON {
 	if list == nil {
 		return cjson.Null()
@@ -134,17 +167,36 @@ func MarshalASTDecl(decl ast.Decl) *cjson.JSON {
 		root.SetItem(c.Str("Type"), MarshalASTExpr(d.Type))
 		root.SetItem(c.Str("IsInline"), boolField(d.IsInline))
 		root.SetItem(c.Str("IsStatic"), boolField(d.IsStatic))
+		// only C++ declarations in an extern "C" block need it to be told from the others
+		if d.IsExternC {
+			root.SetItem(c.Str("IsExternC"), boolField(d.IsExternC))
+		}
 		root.SetItem(c.Str("IsConst"), boolField(d.IsConst))
 		root.SetItem(c.Str("IsExplicit"), boolField(d.IsExplicit))
 		root.SetItem(c.Str("IsConstructor"), boolField(d.IsConstructor))
 		root.SetItem(c.Str("IsDestructor"), boolField(d.IsDestructor))
 		root.SetItem(c.Str("IsVirtual"), boolField(d.IsVirtual))
 		root.SetItem(c.Str("IsOverride"), boolField(d.IsOverride))
+	case *ast.VarDecl:
+		root.SetItem(c.Str("_Type"), stringField("VarDecl"))
+		MarshalASTDeclBase(d.DeclBase, root)
+		root.SetItem(c.Str("Name"), MarshalASTExpr(d.Name))
+		root.SetItem(c.Str("MangledName"), stringField(d.MangledName))
+		root.SetItem(c.Str("Type"), MarshalASTExpr(d.Type))
+		if d.Value != nil {
+			root.SetItem(c.Str("Value"), MarshalASTExpr(d.Value))
+		}
 	case *ast.TypeDecl:
 		root.SetItem(c.Str("_Type"), stringField("TypeDecl"))
 		MarshalASTDeclBase(d.DeclBase, root)
 		root.SetItem(c.Str("Name"), MarshalASTExpr(d.Name))
 		root.SetItem(c.Str("Type"), MarshalASTExpr(d.Type))
+	case *ast.InstantiationDecl:
+		root.SetItem(c.Str("_Type"), stringField("InstantiationDecl"))
+		MarshalASTDeclBase(d.DeclBase, root)
+		root.SetItem(c.Str("Name"), MarshalASTExpr(d.Name))
+		root.SetItem(c.Str("Template"), MarshalASTExpr(d.Template))
+		root.SetItem(c.Str("Type"), MarshalASTExpr(d.Type))
 	}
 	return root
 }
@@ -153,6 +205,67 @@ func MarshalASTDeclBase(decl ast.DeclBase, root *cjson.JSON) {
 	root.SetItem(c.Str("Loc"), MarshalLocation(decl.Loc))
 	root.SetItem(c.Str("Doc"), MarshalASTExpr(decl.Doc))
 	root.SetItem(c.Str("Parent"), MarshalASTExpr(decl.Parent))
+	// most declarations have no attributes, keep them out of the output
+	if len(decl.Attrs) > 0 {
+		root.SetItem(c.Str("Attrs"), MarshalAttrList(decl.Attrs))
+	}
+}
+
+func MarshalAttrList(attrs []*ast.Attr) *cjson.JSON {
+	root := cjson.Array()
+	for _, attr := range attrs {
+		item := cjson.Object()
+		item.SetItem(c.Str("_Type"), stringField("Attr"))
+		item.SetItem(c.Str("Kind"), numberField(uint(attr.Kind)))
+		args := cjson.Array()
+		for _, arg := range attr.Args {
+			args.AddItem(stringField(arg))
+		}
+		item.SetItem(c.Str("Args"), args)
+		root.AddItem(item)
+	}
+	return root
+}
+
+func MarshalDocComment(doc *ast.DocComment) *cjson.JSON {
+	root := cjson.Object()
+	root.SetItem(c.Str("_Type"), stringField("DocComment"))
+	root.SetItem(c.Str("Brief"), stringField(doc.Brief))
+	blocks := cjson.Array()
+	for _, block := range doc.Blocks {
+		blocks.AddItem(MarshalDocBlock(block))
+	}
+	root.SetItem(c.Str("Blocks"), blocks)
+	params := cjson.Array()
+	for _, param := range doc.Params {
+		item := cjson.Object()
+		item.SetItem(c.Str("_Type"), stringField("DocParam"))
+		item.SetItem(c.Str("Name"), stringField(param.Name))
+		item.SetItem(c.Str("Direction"), stringField(param.Direction))
+		item.SetItem(c.Str("Text"), stringField(param.Text))
+		params.AddItem(item)
+	}
+	root.SetItem(c.Str("Params"), params)
+	root.SetItem(c.Str("Return"), stringField(doc.Return))
+	if doc.Deprecated != nil {
+		root.SetItem(c.Str("Deprecated"), MarshalDocBlock(doc.Deprecated))
+	} else {
+		root.SetItem(c.Str("Deprecated"), cjson.Null())
+	}
+	see := cjson.Array()
+	for _, s := range doc.See {
+		see.AddItem(stringField(s))
+	}
+	root.SetItem(c.Str("See"), see)
+	return root
+}
+
+func MarshalDocBlock(block *ast.DocBlock) *cjson.JSON {
+	root := cjson.Object()
+	root.SetItem(c.Str("_Type"), stringField("DocBlock"))
+	root.SetItem(c.Str("Text"), stringField(block.Text))
+	root.SetItem(c.Str("Code"), boolField(block.Code))
+	return root
 }
 
 func MarshalLocation(loc *ast.Location) *cjson.JSON {
@@ -180,6 +293,12 @@ func MarshalASTExpr(t ast.Expr) *cjson.JSON {
 			items.AddItem(MarshalASTExpr(e))
 		}
 		root.SetItem(c.Str("Items"), items)
+		if d.IntType != nil {
+			root.SetItem(c.Str("IntType"), MarshalASTExpr(d.IntType))
+		}
+		if d.Scoped {
+			root.SetItem(c.Str("Scoped"), boolField(true))
+		}
 	case *ast.EnumItem:
 		root.SetItem(c.Str("_Type"), stringField("EnumItem"))
 		root.SetItem(c.Str("Name"), MarshalASTExpr(d.Name))
@@ -193,6 +312,37 @@ func MarshalASTExpr(t ast.Expr) *cjson.JSON {
 			methods.AddItem(MarshalASTDecl(m))
 		}
 		root.SetItem(c.Str("Methods"), methods)
+		if len(d.Bases) > 0 {
+			bases := cjson.Array()
+			for _, b := range d.Bases {
+				bases.AddItem(MarshalASTExpr(b))
+			}
+			root.SetItem(c.Str("Bases"), bases)
+		}
+		if d.Polymorphic {
+			root.SetItem(c.Str("Polymorphic"), boolField(true))
+		}
+		if d.Abstract {
+			root.SetItem(c.Str("Abstract"), boolField(true))
+		}
+		if d.Size > 0 {
+			root.SetItem(c.Str("Size"), numberField(uint(d.Size)))
+			root.SetItem(c.Str("Align"), numberField(uint(d.Align)))
+		}
+		if d.Packed {
+			root.SetItem(c.Str("Packed"), boolField(true))
+		}
+		if d.IsForward {
+			root.SetItem(c.Str("IsForward"), boolField(true))
+		}
+	case *ast.BaseSpec:
+		root.SetItem(c.Str("_Type"), stringField("BaseSpec"))
+		root.SetItem(c.Str("Type"), MarshalASTExpr(d.Type))
+		root.SetItem(c.Str("Access"), numberField(uint(d.Access)))
+		root.SetItem(c.Str("Virtual"), boolField(d.Virtual))
+		if d.Offset > 0 {
+			root.SetItem(c.Str("Offset"), numberField(uint(d.Offset)))
+		}
 	case *ast.FuncType:
 		root.SetItem(c.Str("_Type"), stringField("FuncType"))
 		root.SetItem(c.Str("Params"), MarshalASTExpr(d.Params))
@@ -208,6 +358,15 @@ func MarshalASTExpr(t ast.Expr) *cjson.JSON {
 		root.SetItem(c.Str("IsStatic"), boolField(d.IsStatic))
 		root.SetItem(c.Str("Access"), numberField(uint(d.Access)))
 		root.SetItem(c.Str("Names"), MarshalIdentList(d.Names))
+		if d.Offset > 0 {
+			root.SetItem(c.Str("Offset"), numberField(uint(d.Offset)))
+		}
+		if d.Default != nil {
+			root.SetItem(c.Str("Default"), MarshalASTExpr(d.Default))
+		}
+		if d.DefaultTokens != nil {
+			root.SetItem(c.Str("DefaultTokens"), MarshalTokenList(d.DefaultTokens))
+		}
 	case *ast.Variadic:
 		root.SetItem(c.Str("_Type"), stringField("Variadic"))
 	case *ast.Ident:
@@ -233,6 +392,9 @@ func MarshalASTExpr(t ast.Expr) *cjson.JSON {
 	case *ast.PointerType:
 		root.SetItem(c.Str("_Type"), stringField("PointerType"))
 		root.SetItem(c.Str("X"), MarshalASTExpr(d.X))
+		if d.Nullability != ast.NullabilityNone {
+			root.SetItem(c.Str("Nullability"), numberField(uint(d.Nullability)))
+		}
 	case *ast.ArrayType:
 		root.SetItem(c.Str("_Type"), stringField("ArrayType"))
 		root.SetItem(c.Str("Elt"), MarshalASTExpr(d.Elt))
@@ -257,10 +419,17 @@ func MarshalASTExpr(t ast.Expr) *cjson.JSON {
 			list.AddItem(MarshalASTExpr(c))
 		}
 		root.SetItem(c.Str("List"), list)
+		if d.Doxygen != nil {
+			root.SetItem(c.Str("Doxygen"), MarshalDocComment(d.Doxygen))
+		}
 	case *ast.ScopingExpr:
 		root.SetItem(c.Str("_Type"), stringField("ScopingExpr"))
 		root.SetItem(c.Str("X"), MarshalASTExpr(d.X))
 		root.SetItem(c.Str("Parent"), MarshalASTExpr(d.Parent))
+	case *ast.InstantiationType:
+		root.SetItem(c.Str("_Type"), stringField("InstantiationType"))
+		root.SetItem(c.Str("Template"), MarshalASTExpr(d.Template))
+		root.SetItem(c.Str("Args"), MarshalASTExpr(d.Args))
 	default:
 		return cjson.Null()
 	}
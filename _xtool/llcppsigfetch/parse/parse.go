@@ -2,8 +2,10 @@ package parse
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 
 	"github.com/goplus/llcppg/_xtool/llcppsigfetch/dbg"
@@ -21,42 +23,13 @@ type ParseConfig struct {
 	CombinedFile     string
 	PreprocessedFile string
 	OutputFile       bool
+	Stream           io.Writer // if not nil, the output is written to it as NDJSON while converting
+	// CacheDir is the directory to cache the preprocessed output and the header info
+	// of the package between runs, there is no cache if it's empty.
+	CacheDir string
 }
 
 func Do(cfg *ParseConfig) (*Converter, error) {
-	if err := createTempIfNoExist(&cfg.CombinedFile, cfg.Conf.Name+"*.h"); err != nil {
-		return nil, err
-	}
-	if err := createTempIfNoExist(&cfg.PreprocessedFile, cfg.Conf.Name+"*.i"); err != nil {
-		return nil, err
-	}
-
-	if dbg.GetDebugParse() {
-		fmt.Fprintln(os.Stderr, "Do: combinedFile", cfg.CombinedFile)
-		fmt.Fprintln(os.Stderr, "Do: preprocessedFile", cfg.PreprocessedFile)
-	}
-
-	// compose includes to a combined file
-	err := clangutils.ComposeIncludes(cfg.Conf.Include, cfg.CombinedFile)
-	if err != nil {
-		return nil, err
-	}
-
-	// prepare clang flags to preprocess the combined file
-	clangFlags := strings.Fields(cfg.Conf.CFlags)
-	clangFlags = append(clangFlags, "-C")  // keep comment
-	clangFlags = append(clangFlags, "-dD") // keep macro
-
-	err = clangutils.Preprocess(&clangutils.PreprocessConfig{
-		File:    cfg.CombinedFile,
-		IsCpp:   cfg.Conf.Cplusplus,
-		Args:    clangFlags,
-		OutFile: cfg.PreprocessedFile,
-	})
-	if err != nil {
-		return nil, err
-	}
-
 	// https://github.com/goplus/llgo/issues/603
 	// we need exec.Command("clang", "-print-resource-dir").Output() in llcppsigfetch to obtain the resource directory
 	// to ensure consistency between clang preprocessing and libclang-extracted header filelink cflags.
@@ -66,7 +39,51 @@ func Do(cfg *ParseConfig) (*Converter, error) {
 	if ClangResourceDir != "" {
 		libclangFlags = append(libclangFlags, "-resource-dir="+ClangResourceDir, "-I"+path.Join(ClangResourceDir, "include"))
 	}
-	pkgHfiles := config.PkgHfileInfo(cfg.Conf, libclangFlags)
+
+	var cacheDir, key string
+	var pkgHfiles *config.PkgHfilesInfo
+	if cfg.CacheDir != "" && cfg.Conf.Name != "" && cfg.PreprocessedFile == "" {
+		cacheDir = filepath.Join(cfg.CacheDir, cfg.Conf.Name)
+		key = cacheKey(cfg.Conf, libclangFlags)
+		pkgHfiles = loadCache(cacheDir, key)
+		cfg.PreprocessedFile = filepath.Join(cacheDir, cachePreprocessedFile)
+		if dbg.GetDebugParse() {
+			fmt.Fprintln(os.Stderr, "Do: cacheDir", cacheDir, "cached", pkgHfiles != nil)
+		}
+	}
+
+	// the temporary files are removed when the converter is disposed, or on error
+	var tempFiles []string
+	removeTemps := func() {
+		for _, file := range tempFiles {
+			os.Remove(file)
+		}
+	}
+
+	if pkgHfiles == nil {
+		if cacheDir != "" {
+			if err := resetCache(cacheDir); err != nil {
+				return nil, err
+			}
+		}
+		temp, err := createTempIfNoExist(&cfg.PreprocessedFile, cfg.Conf.Name+"*.i")
+		if err != nil {
+			return nil, err
+		}
+		if temp {
+			tempFiles = append(tempFiles, cfg.PreprocessedFile)
+		}
+		if err := preprocess(cfg); err != nil {
+			removeTemps()
+			return nil, err
+		}
+		pkgHfiles = config.PkgHfileInfo(cfg.Conf, libclangFlags)
+		if cacheDir != "" {
+			if err := saveCache(cacheDir, key, pkgHfiles); err != nil {
+				fmt.Fprintln(os.Stderr, "Do: can't save the cache:", err)
+			}
+		}
+	}
 	if dbg.GetDebugParse() {
 		fmt.Fprintln(os.Stderr, "interfaces", pkgHfiles.Inters)
 		fmt.Fprintln(os.Stderr, "implements", pkgHfiles.Impls)
@@ -77,16 +94,22 @@ func Do(cfg *ParseConfig) (*Converter, error) {
 		&Config{
 			HfileInfo: pkgHfiles,
 			Cfg: &clangutils.Config{
-				File:  cfg.PreprocessedFile,
-				IsCpp: cfg.Conf.Cplusplus,
-				Args:  libclangFlags,
+				File:   cfg.PreprocessedFile,
+				IsCpp:  cfg.Conf.Cplusplus,
+				Target: cfg.Conf.Target.ClangTriple(),
+				Args:   libclangFlags,
 			},
+			Stream:   cfg.Stream,
+			TargetOS: cfg.Conf.Target.Goos(),
 		})
 	if err != nil {
+		removeTemps()
 		return nil, err
 	}
+	converter.tempFiles = tempFiles
 	pkg, err := converter.Convert()
 	if err != nil {
+		converter.Dispose()
 		return nil, err
 	}
 	if dbg.GetDebugParse() {
@@ -99,14 +122,56 @@ func Do(cfg *ParseConfig) (*Converter, error) {
 	return converter, nil
 }
 
-func createTempIfNoExist(filename *string, pattern string) error {
+// preprocess composes the includes to the combined file and preprocesses it to
+// the preprocessed file, the combined file is removed after if it's temporary.
+func preprocess(cfg *ParseConfig) error {
+	temp, err := createTempIfNoExist(&cfg.CombinedFile, cfg.Conf.Name+"*.h")
+	if err != nil {
+		return err
+	}
+	if temp {
+		defer func() {
+			os.Remove(cfg.CombinedFile)
+			cfg.CombinedFile = ""
+		}()
+	}
+
+	if dbg.GetDebugParse() {
+		fmt.Fprintln(os.Stderr, "Do: combinedFile", cfg.CombinedFile)
+		fmt.Fprintln(os.Stderr, "Do: preprocessedFile", cfg.PreprocessedFile)
+	}
+
+	// compose includes to a combined file
+	err = clangutils.ComposeIncludes(cfg.Conf.Include, cfg.CombinedFile)
+	if err != nil {
+		return err
+	}
+
+	// prepare clang flags to preprocess the combined file
+	clangFlags := strings.Fields(cfg.Conf.CFlags)
+	clangFlags = append(clangFlags, "-C")  // keep comment
+	clangFlags = append(clangFlags, "-dD") // keep macro
+
+	return clangutils.Preprocess(&clangutils.PreprocessConfig{
+		File:    cfg.CombinedFile,
+		IsCpp:   cfg.Conf.Cplusplus,
+		Target:  cfg.Conf.Target.ClangTriple(),
+		Args:    clangFlags,
+		OutFile: cfg.PreprocessedFile,
+	})
+}
+
+// createTempIfNoExist creates a temporary file of the pattern if filename is
+// empty, and reports whether it is created.
+func createTempIfNoExist(filename *string, pattern string) (bool, error) {
 	if *filename != "" {
-		return nil
+		return false, nil
 	}
 	f, err := os.CreateTemp("", pattern)
 	if err != nil {
-		return err
+		return false, err
 	}
+	f.Close()
 	*filename = f.Name()
-	return nil
+	return true, nil
 }
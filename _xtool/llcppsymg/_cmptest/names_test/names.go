@@ -13,6 +13,8 @@ func main() {
 	TestPubName()
 	TestExportName()
 	TestHeaderFileToGo()
+	TestSplitScope()
+	TestOperatorGoName()
 }
 
 func TestToGoName() {
@@ -94,6 +96,10 @@ func TestPubName() {
 		{"_", "X_"},
 		{"__", "X__"},
 		{"___", "X___"},
+		{"Foo<int>", "FooInt"},
+		{"Foo<unsigned int *>", "FooUnsignedIntPtr"},
+		{"ns::Pair<int, const char *>", "NsPairIntConstCharPtr"},
+		{"Array<int, -1>", "ArrayIntNeg1"},
 	}
 
 	for _, tc := range testCases {
@@ -160,3 +166,52 @@ func TestHeaderFileToGo() {
 		}
 	}
 }
+
+func TestSplitScope() {
+	fmt.Println("\n=== Test SplitScope ===")
+	testCases := []string{
+		"Widget",
+		"ns::Widget",
+		"a::b::Widget",
+		"Foo<a::Bar>",
+		"ns::Foo<a::Bar, b::Baz<int>>",
+	}
+
+	for _, input := range testCases {
+		scope, name := names.SplitScope(input)
+		fmt.Printf("Input: %s, Scope: %s, Name: %s\n", input, scope, name)
+	}
+}
+
+func TestOperatorGoName() {
+	fmt.Println("\n=== Test OperatorGoName ===")
+	testCases := []struct {
+		name     string
+		operands int
+	}{
+		{"operator+", 2},
+		{"operator+", 1},
+		{"operator*", 1},
+		{"operator++", 1},
+		{"operator++", 2},
+		{"operator[]", 2},
+		{"operator()", 3},
+		{"operator==", 2},
+		{"operator<=>", 2},
+		{"operator delete[]", 1},
+		{"operator bool", 1},
+		{"operator const char *", 1},
+		{"operator ns::Foo<int> &", 1},
+		{`operator""_km`, 1},
+		{"operators_count", 0},
+	}
+
+	for _, tc := range testCases {
+		op, ok := names.Operator(tc.name)
+		if !ok {
+			fmt.Printf("Input: %s, not an operator\n", tc.name)
+			continue
+		}
+		fmt.Printf("Input: %s, Operands: %d, Operator: %s, GoName: %s\n", tc.name, tc.operands, op, names.OperatorGoName(op, tc.operands))
+	}
+}
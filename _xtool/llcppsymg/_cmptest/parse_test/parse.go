@@ -65,10 +65,11 @@ func TestAddSuffix() {
 
 func TestParseHeaderFile() {
 	testCases := []struct {
-		name     string
-		content  string
-		isCpp    bool
-		prefixes []string
+		name      string
+		content   string
+		isCpp     bool
+		prefixes  []string
+		operators map[string]string
 	}{
 		{
 			name: "C++ Class with Methods",
@@ -86,6 +87,28 @@ class INIReader {
 			isCpp:    true,
 			prefixes: []string{"INI"},
 		},
+		{
+			name: "C++ Operators",
+			content: `
+class Vec {
+  public:
+    Vec operator+(const Vec &other) const;
+    Vec operator-() const;
+    Vec &operator++();
+    Vec operator++(int);
+    float &operator[](int i);
+    bool operator==(const Vec &other) const;
+    explicit operator bool() const;
+    float operator()(float x);
+};
+Vec operator*(const Vec &v, float s);
+bool operator<(const Vec &a, const Vec &b);
+int operators_count();
+            `,
+			isCpp:     true,
+			prefixes:  []string{},
+			operators: map[string]string{"operator()": "Eval"},
+		},
 		{
 			name: "C Functions",
 			content: `
@@ -152,12 +175,41 @@ int(lua_sizecomp)(size_t s, int idx1, int idx2, int op);
 			isCpp:    false,
 			prefixes: []string{""},
 		},
+		{
+			name: "C Functions in C++",
+			content: `
+typedef struct lua_State lua_State;
+extern "C" {
+int lua_gettop(lua_State *L);
+}
+int lua_absindex(lua_State *L, int idx);
+			`,
+			isCpp:    true,
+			prefixes: []string{"lua_"},
+		},
+		{
+			name: "C++ Overload of a C Function",
+			content: `
+typedef struct lua_State lua_State;
+int lua_top(lua_State *L, int idx);
+extern "C" int lua_top(lua_State *L);
+			`,
+			isCpp:    true,
+			prefixes: []string{"lua_"},
+		},
 	}
 
 	for _, tc := range testCases {
 		fmt.Printf("=== Test Case: %s ===\n", tc.name)
 
-		symbolMap, err := parse.ParseHeaderFile([]string{tc.content}, tc.prefixes, []string{}, tc.isCpp, true)
+		var operatorName func(string) (string, bool)
+		if tc.operators != nil {
+			operatorName = func(name string) (string, bool) {
+				goName, ok := tc.operators[name]
+				return goName, ok
+			}
+		}
+		symbolMap, err := parse.ParseHeaderFile([]string{tc.content}, tc.prefixes, []string{}, tc.isCpp, nil, true, operatorName)
 
 		if err != nil {
 			fmt.Printf("Error: %v\n", err)
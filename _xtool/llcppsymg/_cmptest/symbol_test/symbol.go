@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"runtime"
 	"sort"
 
 	"github.com/goplus/llcppg/_xtool/llcppsymg/parse"
@@ -60,7 +61,7 @@ func TestGetCommonSymbols() {
 
 	for _, tc := range testCases {
 		fmt.Printf("\nTest Case: %s\n", tc.name)
-		commonSymbols := symbol.GetCommonSymbols(tc.dylibSymbols, tc.headerSymbols)
+		commonSymbols := symbol.GetCommonSymbols(tc.dylibSymbols, tc.headerSymbols, runtime.GOOS)
 		fmt.Printf("Common Symbols (%d):\n", len(commonSymbols))
 		for _, sym := range commonSymbols {
 			fmt.Printf("Mangle: %s, CPP: %s, Go: %s\n", sym.Mangle, sym.CPP, sym.Go)
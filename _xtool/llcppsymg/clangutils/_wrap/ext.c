@@ -0,0 +1,131 @@
+#include <clang-c/Index.h>
+#include <string.h>
+
+typedef enum CXVisitorResult (*llcppg_CXFieldVisitor)(CXCursor *cursor, CXClientData client_data);
+
+typedef struct {
+    CXClientData data;
+    llcppg_CXFieldVisitor visitor;
+} llcppg_field_data;
+
+static enum CXVisitorResult llcppg_field_visitor(CXCursor cursor, CXClientData data) {
+    llcppg_field_data *d = (llcppg_field_data *)data;
+    return d->visitor(&cursor, d->data);
+}
+
+unsigned llcppg_clang_Type_visitFields(CXType *typ, llcppg_CXFieldVisitor visitor, CXClientData client_data) {
+    llcppg_field_data data = {client_data, visitor};
+    return clang_Type_visitFields(*typ, llcppg_field_visitor, &data);
+}
+
+int llcppg_clang_Type_getNumTemplateArguments(CXType *typ) { return clang_Type_getNumTemplateArguments(*typ); }
+
+void llcppg_clang_Type_getTemplateArgumentAsType(CXType *typ, unsigned i, CXType *ret) {
+    *ret = clang_Type_getTemplateArgumentAsType(*typ, i);
+}
+
+void llcppg_clang_getUnqualifiedType(CXType *typ, CXType *ret) { *ret = clang_getUnqualifiedType(*typ); }
+
+void llcppg_clang_getSpecializedCursorTemplate(CXCursor *cursor, CXCursor *ret) {
+    *ret = clang_getSpecializedCursorTemplate(*cursor);
+}
+
+int llcppg_clang_Cursor_getTemplateArgumentKind(CXCursor *cursor, unsigned i) {
+    return clang_Cursor_getTemplateArgumentKind(*cursor, i);
+}
+
+long long llcppg_clang_Cursor_getTemplateArgumentValue(CXCursor *cursor, unsigned i) {
+    return clang_Cursor_getTemplateArgumentValue(*cursor, i);
+}
+
+int llcppg_clang_getCursorPlatformAvailability(CXCursor *cursor, int *always_deprecated, CXString *deprecated_message,
+                                               int *always_unavailable, CXString *unavailable_message) {
+    return clang_getCursorPlatformAvailability(*cursor, always_deprecated, deprecated_message, always_unavailable,
+                                               unavailable_message, NULL, 0);
+}
+
+long long llcppg_clang_Type_getAlignOf(CXType *typ) { return clang_Type_getAlignOf(*typ); }
+
+long long llcppg_clang_Cursor_getOffsetOfField(CXCursor *cursor) { return clang_Cursor_getOffsetOfField(*cursor); }
+
+unsigned llcppg_clang_isVirtualBase(CXCursor *cursor) { return clang_isVirtualBase(*cursor); }
+
+unsigned llcppg_clang_isCursorDefinition(CXCursor *cursor) { return clang_isCursorDefinition(*cursor); }
+
+long long llcppg_clang_getOffsetOfBase(CXCursor *parent, CXCursor *base) { return clang_getOffsetOfBase(*parent, *base); }
+
+int llcppg_clang_getFieldDeclBitWidth(CXCursor *cursor) { return clang_getFieldDeclBitWidth(*cursor); }
+
+void llcppg_clang_getEnumDeclIntegerType(CXCursor *cursor, CXType *ret) { *ret = clang_getEnumDeclIntegerType(*cursor); }
+
+unsigned long long llcppg_clang_getEnumConstantDeclUnsignedValue(CXCursor *cursor) {
+    return clang_getEnumConstantDeclUnsignedValue(*cursor);
+}
+
+int llcppg_clang_Type_getNullability(CXType *typ) { return clang_Type_getNullability(*typ); }
+
+void llcppg_clang_Type_getModifiedType(CXType *typ, CXType *ret) { *ret = clang_Type_getModifiedType(*typ); }
+
+int llcppg_clang_getCursorLinkage(CXCursor *cursor) { return clang_getCursorLinkage(*cursor); }
+
+int llcppg_clang_Cursor_Evaluate(CXCursor *cursor, long long *ival, unsigned *is_unsigned, double *fval, char **sval) {
+    CXEvalResult res = clang_Cursor_Evaluate(*cursor);
+    if (!res) {
+        return CXEval_UnExposed;
+    }
+    CXEvalResultKind kind = clang_EvalResult_getKind(res);
+    switch (kind) {
+    case CXEval_Int:
+        *is_unsigned = clang_EvalResult_isUnsignedInt(res);
+        if (*is_unsigned) {
+            *ival = (long long)clang_EvalResult_getAsUnsigned(res);
+        } else {
+            *ival = clang_EvalResult_getAsLongLong(res);
+        }
+        break;
+    case CXEval_Float:
+        *fval = clang_EvalResult_getAsDouble(res);
+        break;
+    case CXEval_StrLiteral:
+        *sval = strdup(clang_EvalResult_getAsStr(res));
+        break;
+    default:
+        break;
+    }
+    clang_EvalResult_dispose(res);
+    return kind;
+}
+
+unsigned llcppg_clang_isExternC(CXCursor *cursor) {
+    switch (cursor->kind) {
+    case CXCursor_CXXMethod:
+    case CXCursor_Constructor:
+    case CXCursor_Destructor:
+    case CXCursor_ConversionFunction:
+        return 0; // class members always have C++ language linkage
+    default:
+        break;
+    }
+    // The linkage is given by the first declaration, and by the innermost
+    // linkage specification that encloses it.
+    CXCursor parent = clang_getCursorLexicalParent(clang_getCanonicalCursor(*cursor));
+    for (; !clang_Cursor_isNull(parent) && parent.kind != CXCursor_TranslationUnit;
+         parent = clang_getCursorLexicalParent(parent)) {
+        if (parent.kind != CXCursor_LinkageSpec) {
+            continue;
+        }
+        CXTranslationUnit unit = clang_Cursor_getTranslationUnit(parent);
+        CXToken *tokens = NULL;
+        unsigned num = 0;
+        clang_tokenize(unit, clang_getCursorExtent(parent), &tokens, &num);
+        unsigned ret = 0;
+        if (num > 1) {
+            CXString lang = clang_getTokenSpelling(unit, tokens[1]);
+            ret = strcmp(clang_getCString(lang), "\"C\"") == 0;
+            clang_disposeString(lang);
+        }
+        clang_disposeTokens(unit, tokens, num);
+        return ret;
+    }
+    return 0;
+}
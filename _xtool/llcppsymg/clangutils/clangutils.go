@@ -17,9 +17,17 @@ type Config struct {
 	Temp  bool
 	Args  []string
 	IsCpp bool
-	Index *clang.Index
+	// Target is the clang target triple, like aarch64-unknown-linux-gnu, the host if empty.
+	Target string
+	Index  *clang.Index
+	// AttributedTypes keeps the attributed types, like int *_Nonnull, which are
+	// otherwise reported as the types they modify.
+	AttributedTypes bool
 }
 
+// CXTranslationUnit_IncludeAttributedTypes, not covered by the llgo binding
+const includeAttributedTypes = 0x1000
+
 type Visitor func(cursor, parent clang.Cursor) clang.ChildVisitResult
 
 type InclusionVisitor func(included_file clang.File, inclusions []clang.SourceLocation)
@@ -29,7 +37,7 @@ const TEMP_FILE = "temp.h"
 func CreateTranslationUnit(config *Config) (*clang.Index, *clang.TranslationUnit, error) {
 	// default use the c/c++ standard of clang; c:gnu17 c++:gnu++17
 	// https://clang.llvm.org/docs/CommandGuide/clang.html
-	allArgs := append(defaultArgs(config.IsCpp), config.Args...)
+	allArgs := append(defaultArgs(config.IsCpp, config.Target), config.Args...)
 
 	cArgs := make([]*c.Char, len(allArgs))
 	for i, arg := range allArgs {
@@ -44,6 +52,10 @@ func CreateTranslationUnit(config *Config) (*clang.Index, *clang.TranslationUnit
 	}
 
 	var unit *clang.TranslationUnit
+	options := c.Uint(clang.DetailedPreprocessingRecord)
+	if config.AttributedTypes {
+		options |= includeAttributedTypes
+	}
 
 	if config.Temp {
 		content := c.AllocaCStr(config.File)
@@ -57,7 +69,7 @@ func CreateTranslationUnit(config *Config) (*clang.Index, *clang.TranslationUnit
 			tempFile.Filename,
 			unsafe.SliceData(cArgs), c.Int(len(cArgs)),
 			tempFile, 1,
-			clang.DetailedPreprocessingRecord,
+			options,
 		)
 
 	} else {
@@ -66,7 +78,7 @@ func CreateTranslationUnit(config *Config) (*clang.Index, *clang.TranslationUnit
 			cFile,
 			unsafe.SliceData(cArgs), c.Int(len(cArgs)),
 			nil, 0,
-			clang.DetailedPreprocessingRecord,
+			options,
 		)
 	}
 
@@ -121,24 +133,37 @@ func ComposeIncludes(files []string, outfile string) error {
 	return os.WriteFile(outfile, []byte(str), 0644)
 }
 
-func defaultArgs(isCpp bool) []string {
+// TrimMangling removes the underscore prefixed to the symbols when goos, the GOOS
+// of the target, is darwin, so the mangled names are the same for all targets.
+func TrimMangling(goos, mangled string) string {
+	if goos == "darwin" || goos == "ios" {
+		return strings.TrimPrefix(mangled, "_")
+	}
+	return mangled
+}
+
+func defaultArgs(isCpp bool, target string) []string {
 	args := []string{"-x", "c"}
 	if isCpp {
 		args = []string{"-x", "c++"}
 	}
+	if target != "" {
+		args = append(args, "--target="+target)
+	}
 	return args
 }
 
 type PreprocessConfig struct {
 	File    string
 	IsCpp   bool
+	Target  string // the clang target triple, the host if empty
 	Args    []string
 	OutFile string
 }
 
 func Preprocess(cfg *PreprocessConfig) error {
 	args := []string{"-E"}
-	args = append(args, defaultArgs(cfg.IsCpp)...)
+	args = append(args, defaultArgs(cfg.IsCpp, cfg.Target)...)
 	args = append(args, cfg.Args...)
 	args = append(args, cfg.File)
 	args = append(args, "-o", cfg.OutFile)
@@ -150,7 +175,7 @@ func Preprocess(cfg *PreprocessConfig) error {
 
 func GetIncludePaths(isCpp bool) []string {
 	args := []string{"-E", "-v"}
-	args = append(args, defaultArgs(isCpp)...)
+	args = append(args, defaultArgs(isCpp, "")...)
 	args = append(args, "/dev/null")
 	cmd := exec.Command("clang", args...)
 	output, err := cmd.CombinedOutput()
@@ -0,0 +1,300 @@
+package clangutils
+
+import (
+	"unsafe"
+
+	"github.com/goplus/llgo/c"
+	"github.com/goplus/llgo/c/clang"
+)
+
+// libclang APIs that are not covered by github.com/goplus/llgo/c/clang.
+// Like the llgo binding, structs are passed by pointer through the C wrappers in _wrap.
+const LLGoFiles = "$(llvm-config --cflags): _wrap/ext.c"
+
+// TemplateArgumentKind describes the kind of a template argument.
+type TemplateArgumentKind c.Int
+
+const (
+	TemplateArgumentNull TemplateArgumentKind = iota
+	TemplateArgumentType
+	TemplateArgumentDeclaration
+	TemplateArgumentNullPtr
+	TemplateArgumentIntegral
+	TemplateArgumentTemplate
+	TemplateArgumentTemplateExpansion
+	TemplateArgumentExpression
+	TemplateArgumentPack
+	TemplateArgumentInvalid
+)
+
+// TypeNullabilityKind describes the nullability of a pointer type.
+type TypeNullabilityKind c.Int
+
+const (
+	TypeNullabilityNonNull TypeNullabilityKind = iota
+	TypeNullabilityNullable
+	TypeNullabilityUnspecified
+	TypeNullabilityInvalid // the type has no nullability
+	TypeNullabilityNullableResult
+)
+
+// Linkage describes the linkage of a declaration.
+type Linkage c.Int
+
+const (
+	LinkageInvalid Linkage = iota
+	LinkageNoLinkage
+	LinkageInternal
+	LinkageUniqueExternal
+	LinkageExternal
+)
+
+// EvalResultKind describes the kind of an evaluated expression.
+type EvalResultKind c.Int
+
+const (
+	EvalUnExposed EvalResultKind = iota
+	EvalInt
+	EvalFloat
+	EvalObjCStrLiteral
+	EvalStrLiteral
+	EvalCFStr
+	EvalOther
+)
+
+//go:linkname wrapTypeNumTemplateArguments C.llcppg_clang_Type_getNumTemplateArguments
+func wrapTypeNumTemplateArguments(t *clang.Type) c.Int
+
+//go:linkname wrapTypeTemplateArgumentAsType C.llcppg_clang_Type_getTemplateArgumentAsType
+func wrapTypeTemplateArgumentAsType(t *clang.Type, i c.Uint, ret *clang.Type)
+
+//go:linkname wrapUnqualifiedType C.llcppg_clang_getUnqualifiedType
+func wrapUnqualifiedType(t *clang.Type, ret *clang.Type)
+
+//go:linkname wrapSpecializedCursorTemplate C.llcppg_clang_getSpecializedCursorTemplate
+func wrapSpecializedCursorTemplate(cursor *clang.Cursor, ret *clang.Cursor)
+
+//go:linkname wrapCursorTemplateArgumentKind C.llcppg_clang_Cursor_getTemplateArgumentKind
+func wrapCursorTemplateArgumentKind(cursor *clang.Cursor, i c.Uint) c.Int
+
+//go:linkname wrapCursorTemplateArgumentValue C.llcppg_clang_Cursor_getTemplateArgumentValue
+func wrapCursorTemplateArgumentValue(cursor *clang.Cursor, i c.Uint) c.LongLong
+
+//go:linkname wrapCursorPlatformAvailability C.llcppg_clang_getCursorPlatformAvailability
+func wrapCursorPlatformAvailability(cursor *clang.Cursor, alwaysDeprecated *c.Int, deprecatedMessage *clang.String,
+	alwaysUnavailable *c.Int, unavailableMessage *clang.String) c.Int
+
+//llgo:type C
+type fieldVisitor func(cursor *clang.Cursor, clientData c.Pointer) c.Int
+
+//go:linkname wrapTypeAlignOf C.llcppg_clang_Type_getAlignOf
+func wrapTypeAlignOf(t *clang.Type) c.LongLong
+
+//go:linkname wrapCursorOffsetOfField C.llcppg_clang_Cursor_getOffsetOfField
+func wrapCursorOffsetOfField(cursor *clang.Cursor) c.LongLong
+
+//go:linkname wrapIsVirtualBase C.llcppg_clang_isVirtualBase
+func wrapIsVirtualBase(cursor *clang.Cursor) c.Uint
+
+//go:linkname wrapIsCursorDefinition C.llcppg_clang_isCursorDefinition
+func wrapIsCursorDefinition(cursor *clang.Cursor) c.Uint
+
+//go:linkname wrapOffsetOfBase C.llcppg_clang_getOffsetOfBase
+func wrapOffsetOfBase(parent *clang.Cursor, base *clang.Cursor) c.LongLong
+
+//go:linkname wrapFieldDeclBitWidth C.llcppg_clang_getFieldDeclBitWidth
+func wrapFieldDeclBitWidth(cursor *clang.Cursor) c.Int
+
+//go:linkname wrapEnumDeclIntegerType C.llcppg_clang_getEnumDeclIntegerType
+func wrapEnumDeclIntegerType(cursor *clang.Cursor, ret *clang.Type)
+
+//go:linkname wrapEnumConstantDeclUnsignedValue C.llcppg_clang_getEnumConstantDeclUnsignedValue
+func wrapEnumConstantDeclUnsignedValue(cursor *clang.Cursor) c.UlongLong
+
+//go:linkname wrapTypeNullability C.llcppg_clang_Type_getNullability
+func wrapTypeNullability(t *clang.Type) c.Int
+
+//go:linkname wrapTypeModifiedType C.llcppg_clang_Type_getModifiedType
+func wrapTypeModifiedType(t *clang.Type, ret *clang.Type)
+
+//go:linkname wrapCursorLinkage C.llcppg_clang_getCursorLinkage
+func wrapCursorLinkage(cursor *clang.Cursor) c.Int
+
+//go:linkname wrapIsExternC C.llcppg_clang_isExternC
+func wrapIsExternC(cursor *clang.Cursor) c.Uint
+
+//go:linkname wrapCursorEvaluate C.llcppg_clang_Cursor_Evaluate
+func wrapCursorEvaluate(cursor *clang.Cursor, ival *c.LongLong, isUnsigned *c.Uint, fval *c.Double, sval **c.Char) c.Int
+
+//go:linkname wrapTypeVisitFields C.llcppg_clang_Type_visitFields
+func wrapTypeVisitFields(t *clang.Type, visitor fieldVisitor, clientData c.Pointer) c.Uint
+
+// VisitFields visits the fields of a complete record type until fn returns false.
+// Unlike VisitChildren, it also reaches the fields of class template instantiations.
+func VisitFields(t clang.Type, fn func(cursor clang.Cursor) bool) {
+	wrapTypeVisitFields(&t, func(cursor *clang.Cursor, clientData c.Pointer) c.Int {
+		cfn := *(*func(clang.Cursor) bool)(clientData)
+		if cfn(*cursor) {
+			return 1 // CXVisit_Continue
+		}
+		return 0 // CXVisit_Break
+	}, unsafe.Pointer(&fn))
+}
+
+// NumTemplateArguments returns the number of template arguments of a class template
+// specialization type, or -1 if t is not a specialization.
+func NumTemplateArguments(t clang.Type) int {
+	return int(wrapTypeNumTemplateArguments(&t))
+}
+
+// TemplateArgumentAsType returns the i-th template argument of t as a type.
+// The result is invalid if that argument is not a type.
+func TemplateArgumentAsType(t clang.Type, i int) (ret clang.Type) {
+	wrapTypeTemplateArgumentAsType(&t, c.Uint(i), &ret)
+	return
+}
+
+// UnqualifiedType returns t without its const, volatile and restrict qualifiers.
+func UnqualifiedType(t clang.Type) (ret clang.Type) {
+	wrapUnqualifiedType(&t, &ret)
+	return
+}
+
+// SpecializedCursorTemplate returns the template that a specialization or
+// instantiation cursor was derived from, or a null cursor.
+func SpecializedCursorTemplate(cursor clang.Cursor) (ret clang.Cursor) {
+	wrapSpecializedCursorTemplate(&cursor, &ret)
+	return
+}
+
+// CursorTemplateArgumentKind returns the kind of the i-th template argument of a
+// class or function template specialization cursor.
+func CursorTemplateArgumentKind(cursor clang.Cursor, i int) TemplateArgumentKind {
+	return TemplateArgumentKind(wrapCursorTemplateArgumentKind(&cursor, c.Uint(i)))
+}
+
+// CursorTemplateArgumentValue returns the value of the i-th template argument,
+// which must be of kind TemplateArgumentIntegral.
+func CursorTemplateArgumentValue(cursor clang.Cursor, i int) int64 {
+	return int64(wrapCursorTemplateArgumentValue(&cursor, c.Uint(i)))
+}
+
+// Availability describes whether a declaration is marked deprecated or unavailable
+// on all platforms, with the messages given to the attributes.
+type Availability struct {
+	Deprecated         bool
+	DeprecatedMessage  string
+	Unavailable        bool
+	UnavailableMessage string
+}
+
+// PlatformAvailability returns the availability of a declaration, including the
+// attributes that come from macros.
+func PlatformAvailability(cursor clang.Cursor) (ret Availability) {
+	var deprecated, unavailable c.Int
+	var deprecatedMsg, unavailableMsg clang.String
+	wrapCursorPlatformAvailability(&cursor, &deprecated, &deprecatedMsg, &unavailable, &unavailableMsg)
+	ret.Deprecated = deprecated != 0
+	ret.DeprecatedMessage = clang.GoString(deprecatedMsg)
+	ret.Unavailable = unavailable != 0
+	ret.UnavailableMessage = clang.GoString(unavailableMsg)
+	return
+}
+
+// TypeAlignOf returns the alignment of a type in bytes, or a negative
+// layout error for incomplete and dependent types.
+func TypeAlignOf(t clang.Type) int64 {
+	return int64(wrapTypeAlignOf(&t))
+}
+
+// CursorOffsetOfField returns the offset of a field in bits from the start of
+// its record, or a negative layout error.
+func CursorOffsetOfField(cursor clang.Cursor) int64 {
+	return int64(wrapCursorOffsetOfField(&cursor))
+}
+
+// IsVirtualBase reports whether a base class specifier is virtual.
+func IsVirtualBase(cursor clang.Cursor) bool {
+	return wrapIsVirtualBase(&cursor) != 0
+}
+
+// IsCursorDefinition reports whether a declaration is also a definition, unlike
+// a forward declaration like: struct a;
+func IsCursorDefinition(cursor clang.Cursor) bool {
+	return wrapIsCursorDefinition(&cursor) != 0
+}
+
+// OffsetOfBase returns the offset of a non-virtual base class in bits from the
+// start of the record parent, or a negative layout error.
+func OffsetOfBase(parent, base clang.Cursor) int64 {
+	return int64(wrapOffsetOfBase(&parent, &base))
+}
+
+// FieldDeclBitWidth returns the bit width of a bit-field, or -1 if the field is
+// not a bit-field.
+func FieldDeclBitWidth(cursor clang.Cursor) int {
+	return int(wrapFieldDeclBitWidth(&cursor))
+}
+
+// EnumDeclIntegerType returns the integer type of an enum declaration.
+func EnumDeclIntegerType(cursor clang.Cursor) (ret clang.Type) {
+	wrapEnumDeclIntegerType(&cursor, &ret)
+	return
+}
+
+// EnumConstantDeclUnsignedValue returns the value of an enum constant whose
+// enum has an unsigned integer type.
+func EnumConstantDeclUnsignedValue(cursor clang.Cursor) uint64 {
+	return uint64(wrapEnumConstantDeclUnsignedValue(&cursor))
+}
+
+// TypeNullability returns the nullability of an attributed type, like int *_Nonnull.
+func TypeNullability(t clang.Type) TypeNullabilityKind {
+	return TypeNullabilityKind(wrapTypeNullability(&t))
+}
+
+// ModifiedType returns the type that an attributed type modifies.
+func ModifiedType(t clang.Type) (ret clang.Type) {
+	wrapTypeModifiedType(&t, &ret)
+	return
+}
+
+// CursorLinkage returns the linkage of a declaration.
+func CursorLinkage(cursor clang.Cursor) Linkage {
+	return Linkage(wrapCursorLinkage(&cursor))
+}
+
+// IsExternC reports whether a C++ function has C language linkage, that is, its first
+// declaration is in an extern "C" block or follows extern "C", so its symbol is not
+// mangled. Methods are never extern "C". The language of the cursor does not tell
+// it, as libclang reports C for any function that is not a member.
+func IsExternC(cursor clang.Cursor) bool {
+	return wrapIsExternC(&cursor) != 0
+}
+
+// EvalResult is the value of an expression evaluated by Evaluate.
+type EvalResult struct {
+	Kind     EvalResultKind
+	Int      int64 // the bits of the value if Unsigned
+	Unsigned bool
+	Float    float64
+	Str      string
+}
+
+// Evaluate evaluates the initializer of a variable declaration, or an
+// expression, if it is a constant.
+func Evaluate(cursor clang.Cursor) (ret EvalResult) {
+	var ival c.LongLong
+	var isUnsigned c.Uint
+	var fval c.Double
+	var sval *c.Char
+	ret.Kind = EvalResultKind(wrapCursorEvaluate(&cursor, &ival, &isUnsigned, &fval, &sval))
+	ret.Int = int64(ival)
+	ret.Unsigned = isUnsigned != 0
+	ret.Float = float64(fval)
+	if sval != nil {
+		ret.Str = c.GoString(sval)
+		c.Free(unsafe.Pointer(sval))
+	}
+	return
+}
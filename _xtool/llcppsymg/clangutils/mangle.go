@@ -0,0 +1,191 @@
+package clangutils
+
+import (
+	"fmt"
+
+	"github.com/goplus/llcppg/_xtool/llcppsymg/mangle"
+	"github.com/goplus/llcppg/_xtool/llcppsymg/names"
+	"github.com/goplus/llgo/c"
+	"github.com/goplus/llgo/c/clang"
+)
+
+// MangleMember derives the Itanium C++ ABI symbol of a member function of the
+// instantiation, like _ZN3FooIiE3getEv for Foo<int>::get().
+//
+// libclang can't mangle the members of an instantiation because it never exposes
+// them, so method must be the member as declared in the template. Its types are
+// resolved against the instantiation and encoded by the mangle package.
+func (inst *Instantiation) MangleMember(method clang.Cursor) (string, error) {
+	class, err := inst.mangleClass(inst)
+	if err != nil {
+		return "", err
+	}
+	member := &mangle.Member{
+		Class:    class,
+		Const:    method.IsConst() != 0,
+		Variadic: method.IsVariadic() != 0,
+	}
+	switch method.Kind {
+	case clang.CursorConstructor:
+		// the complete object variants are the ones called from outside the class
+		member.Name = mangle.CompleteCtor
+	case clang.CursorDestructor:
+		member.Name = mangle.CompleteDtor
+	case clang.CursorConversionFunction:
+		typ, err := inst.mangleType(method.ResultType())
+		if err != nil {
+			return "", err
+		}
+		member.Name = mangle.Conversion{Type: typ}
+	case clang.CursorCXXMethod:
+		name := clang.GoString(method.String())
+		if op, ok := names.Operator(name); ok {
+			// the object is an operand of a member operator
+			member.Name = mangle.Operator{Op: op, Operands: int(method.NumArguments()) + 1}
+		} else {
+			member.Name = mangle.Ident(name)
+		}
+	default:
+		return "", fmt.Errorf("mangle %s: not a member function", clang.GoString(method.String()))
+	}
+	numArgs := int(method.NumArguments())
+	for i := 0; i < numArgs; i++ {
+		typ, err := inst.mangleType(method.Argument(c.Uint(i)).Type())
+		if err != nil {
+			return "", err
+		}
+		member.Params = append(member.Params, typ)
+	}
+	symbol, err := member.Mangle()
+	if err != nil {
+		return "", fmt.Errorf("mangle %s: %w", clang.GoString(method.String()), err)
+	}
+	return symbol, nil
+}
+
+// builtinCodes are the codes of the builtin types in the ABI
+var builtinCodes = map[clang.TypeKind]string{
+	clang.TypeVoid:       "v",
+	clang.TypeBool:       "b",
+	clang.TypeCharU:      "c",
+	clang.TypeCharS:      "c",
+	clang.TypeSChar:      "a",
+	clang.TypeUChar:      "h",
+	clang.TypeWChar:      "w",
+	clang.TypeChar16:     "Ds",
+	clang.TypeChar32:     "Di",
+	clang.TypeShort:      "s",
+	clang.TypeUShort:     "t",
+	clang.TypeInt:        "i",
+	clang.TypeUInt:       "j",
+	clang.TypeLong:       "l",
+	clang.TypeULong:      "m",
+	clang.TypeLongLong:   "x",
+	clang.TypeULongLong:  "y",
+	clang.TypeInt128:     "n",
+	clang.TypeUInt128:    "o",
+	clang.TypeHalf:       "Dh",
+	clang.TypeFloat16:    "DF16_",
+	clang.TypeFloat:      "f",
+	clang.TypeDouble:     "d",
+	clang.TypeLongDouble: "e",
+	clang.TypeFloat128:   "g",
+	clang.TypeNullPtr:    "Dn",
+}
+
+// mangleType resolves t, whose template parameters are the ones of the instantiation.
+func (inst *Instantiation) mangleType(t clang.Type) (mangle.Type, error) {
+	quals := mangle.Qualified{
+		Const:    t.IsConstQualifiedType() != 0,
+		Volatile: t.IsVolatileQualifiedType() != 0,
+		Restrict: t.IsRestrictQualifiedType() != 0,
+	}
+	if quals.Const || quals.Volatile || quals.Restrict {
+		typ, err := inst.mangleType(UnqualifiedType(t))
+		quals.Type = typ
+		return quals, err
+	}
+	if arg, ok := inst.Subst(t); ok {
+		return inst.mangleType(arg)
+	}
+	switch t.Kind {
+	case clang.TypeElaborated:
+		return inst.mangleType(t.NamedType())
+	case clang.TypeTypedef:
+		return inst.mangleType(t.TypeDeclaration().TypedefDeclUnderlyingType())
+	}
+	if code, ok := builtinCodes[t.Kind]; ok {
+		return mangle.Builtin(code), nil
+	}
+	switch t.Kind {
+	case clang.TypePointer:
+		elem, err := inst.mangleType(t.PointeeType())
+		return mangle.Pointer{Elem: elem}, err
+	case clang.TypeLValueReference:
+		elem, err := inst.mangleType(t.PointeeType())
+		return mangle.LValueRef{Elem: elem}, err
+	case clang.TypeRValueReference:
+		elem, err := inst.mangleType(t.PointeeType())
+		return mangle.RValueRef{Elem: elem}, err
+	case clang.TypeConstantArray:
+		elem, err := inst.mangleType(t.ArrayElementType())
+		return mangle.Array{Len: int64(t.ArraySize()), Elem: elem}, err
+	case clang.TypeFunctionProto:
+		return inst.mangleFunc(t)
+	case clang.TypeRecord, clang.TypeEnum, clang.TypeUnexposed:
+		if inst.IsSelf(t) {
+			return inst.mangleClass(inst)
+		}
+		if IsInstantiation(t) {
+			return inst.mangleClass(NewInstantiation(t))
+		}
+		if t.Kind != clang.TypeUnexposed {
+			return &mangle.Class{Scopes: BuildScopingParts(t.TypeDeclaration())}, nil
+		}
+	}
+	return nil, fmt.Errorf("mangle: unsupported type %s", clang.GoString(t.String()))
+}
+
+func (inst *Instantiation) mangleFunc(t clang.Type) (mangle.Type, error) {
+	result, err := inst.mangleType(t.ResultType())
+	if err != nil {
+		return nil, err
+	}
+	fn := mangle.Func{Result: result, Variadic: t.IsFunctionTypeVariadic() != 0}
+	numArgs := int(t.NumArgTypes())
+	for i := 0; i < numArgs; i++ {
+		arg, err := inst.mangleType(t.ArgType(c.Uint(i)))
+		if err != nil {
+			return nil, err
+		}
+		fn.Params = append(fn.Params, arg)
+	}
+	return fn, nil
+}
+
+// mangleClass resolves the class template specialization of class with its arguments.
+func (inst *Instantiation) mangleClass(class *Instantiation) (*mangle.Class, error) {
+	typ := &mangle.Class{Scopes: BuildScopingParts(class.Template)}
+	num := NumTemplateArguments(class.Type)
+	for i := 0; i < num; i++ {
+		if arg := TemplateArgumentAsType(class.Type, i); arg.Kind != clang.TypeInvalid {
+			argType, err := inst.mangleType(arg)
+			if err != nil {
+				return nil, err
+			}
+			typ.Args = append(typ.Args, argType)
+			continue
+		}
+		if CursorTemplateArgumentKind(class.Decl, i) != TemplateArgumentIntegral {
+			return nil, fmt.Errorf("mangle %s: unsupported template argument %d", class.Spelling(), i)
+		}
+		code := "i"
+		if param, ok := class.nonTypeParam(i); ok {
+			if c, ok := builtinCodes[param.CanonicalType().Kind]; ok {
+				code = c
+			}
+		}
+		typ.Args = append(typ.Args, mangle.Integral{Type: mangle.Builtin(code), Value: CursorTemplateArgumentValue(class.Decl, i)})
+	}
+	return typ, nil
+}
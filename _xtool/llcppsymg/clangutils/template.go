@@ -0,0 +1,124 @@
+package clangutils
+
+import (
+	"github.com/goplus/llgo/c/clang"
+)
+
+// Instantiation is a class template specialization reached through its type,
+// like Foo<int> in `typedef Foo<int> IntFoo;` or `extern template class Foo<int>;`.
+//
+// libclang only visits the members of explicit specializations. For implicit and
+// explicit instantiations the fields are reached with VisitFields, while the methods
+// have to be taken from the template itself: their types are resolved with Subst
+// and their symbols derived with MangleMember.
+type Instantiation struct {
+	Type     clang.Type   // the canonical specialization type, like Foo<int>
+	Decl     clang.Cursor // the specialization decl
+	Template clang.Cursor // the class template (or partial specialization) it derives from
+
+	params    map[string]clang.Type // template type parameter name -> argument
+	paramList []clang.Cursor        // template parameters in declaration order
+}
+
+// IsInstantiation reports whether t refers to a class template specialization.
+// Typedef names are not looked through.
+func IsInstantiation(t clang.Type) bool {
+	switch t.TypeDeclaration().Kind {
+	case clang.CursorStructDecl, clang.CursorClassDecl, clang.CursorUnionDecl:
+		return NumTemplateArguments(t) > 0
+	}
+	return false
+}
+
+func NewInstantiation(t clang.Type) *Instantiation {
+	// the canonical type carries the canonical template arguments,
+	// so Foo<size_t> and Foo<unsigned long> are the same instantiation
+	t = t.CanonicalType()
+	decl := t.TypeDeclaration()
+	inst := &Instantiation{
+		Type:     t,
+		Decl:     decl,
+		Template: SpecializedCursorTemplate(decl),
+		params:   make(map[string]clang.Type),
+	}
+	if inst.Template.Kind == clang.CursorClassTemplate {
+		VisitChildren(inst.Template, func(cursor, parent clang.Cursor) clang.ChildVisitResult {
+			switch cursor.Kind {
+			case clang.CursorTemplateTypeParameter:
+				inst.params[clang.GoString(cursor.String())] = TemplateArgumentAsType(t, len(inst.paramList))
+				inst.paramList = append(inst.paramList, cursor)
+			case clang.CursorNonTypeTemplateParameter, clang.CursorTemplateTemplateParameter:
+				inst.paramList = append(inst.paramList, cursor)
+			}
+			return clang.ChildVisit_Continue
+		})
+	}
+	return inst
+}
+
+// Spelling returns the C++ spelling of the specialization, like Foo<int>.
+func (inst *Instantiation) Spelling() string {
+	return clang.GoString(inst.Type.String())
+}
+
+// IsExplicitSpecialization reports whether the specialization is written out
+// as `template<> class Foo<int> {...}`, in which case libclang visits its members
+// and they carry their own mangled names.
+func (inst *Instantiation) IsExplicitSpecialization() bool {
+	hasBody := false
+	VisitChildren(inst.Decl, func(cursor, parent clang.Cursor) clang.ChildVisitResult {
+		switch cursor.Kind {
+		case clang.CursorFieldDecl, clang.CursorCXXMethod, clang.CursorConstructor, clang.CursorDestructor:
+			hasBody = true
+			return clang.ChildVisit_Break
+		}
+		return clang.ChildVisit_Continue
+	})
+	return hasBody
+}
+
+// Members returns the cursor whose children are the members of the instantiation,
+// or a null cursor if they can't be resolved (eg. instantiated from a partial specialization).
+func (inst *Instantiation) Members() clang.Cursor {
+	if inst.IsExplicitSpecialization() {
+		return inst.Decl
+	}
+	if inst.Template.Kind == clang.CursorClassTemplate {
+		return inst.Template
+	}
+	return clang.Cursor{Kind: clang.CursorNoDeclFound}
+}
+
+// Subst resolves a template type parameter of the template to its argument.
+func (inst *Instantiation) Subst(t clang.Type) (clang.Type, bool) {
+	for t.Kind == clang.TypeElaborated {
+		t = t.NamedType()
+	}
+	decl := t.TypeDeclaration()
+	if decl.Kind != clang.CursorTemplateTypeParameter {
+		return t, false
+	}
+	arg, ok := inst.params[clang.GoString(decl.String())]
+	if !ok || arg.Kind == clang.TypeInvalid {
+		return t, false
+	}
+	return arg, true
+}
+
+// IsSelf reports whether t names the template being instantiated from inside it,
+// like Foo in `template <class T> struct Foo { Foo *next; };`.
+func (inst *Instantiation) IsSelf(t clang.Type) bool {
+	decl := t.TypeDeclaration()
+	if decl.IsNull() == 1 || decl.Kind == clang.CursorNoDeclFound {
+		return false
+	}
+	return clang.GoString(decl.USR()) == clang.GoString(inst.Template.USR())
+}
+
+// nonTypeParam returns the type of the i-th template parameter if it is a non-type parameter.
+func (inst *Instantiation) nonTypeParam(i int) (clang.Type, bool) {
+	if i >= len(inst.paramList) || inst.paramList[i].Kind != clang.CursorNonTypeTemplateParameter {
+		return clang.Type{}, false
+	}
+	return inst.paramList[i].Type(), true
+}
@@ -34,6 +34,13 @@ func GetConf(data []byte) (Conf, error) {
 		Cplusplus:    GetBoolItem(parsedConf, "cplusplus"),
 		Mix:          GetBoolItem(parsedConf, "mix"),
 	}
+	if target := parsedConf.GetObjectItemCaseSensitive(c.AllocaCStr("target")); target != nil {
+		config.Target = &llcppg.Target{
+			GOOS:   GetStringItem(target, "goos", ""),
+			GOARCH: GetStringItem(target, "goarch", ""),
+			Triple: GetStringItem(target, "triple", ""),
+		}
+	}
 
 	return Conf{
 		JSON:   parsedConf,
@@ -41,6 +48,20 @@ func GetConf(data []byte) (Conf, error) {
 	}, nil
 }
 
+// OperatorName returns the Go name configured for the operator function name
+// in the operators of the config,like "operators": {"operator()": "Eval"}.
+func (conf Conf) OperatorName(name string) (string, bool) {
+	operators := conf.GetObjectItemCaseSensitive(c.AllocaCStr("operators"))
+	if operators == nil {
+		return "", false
+	}
+	item := operators.GetObjectItemCaseSensitive(c.AllocaCStr(name))
+	if item == nil || item.IsString() == 0 {
+		return "", false
+	}
+	return GetString(item), true
+}
+
 func GetString(obj *cjson.JSON) (value string) {
 	str := obj.GetStringValue()
 	return unsafe.String((*byte)(unsafe.Pointer(str)), c.Strlen(str))
@@ -106,6 +127,8 @@ func PkgHfileInfo(conf *llcppg.Config, args []string) *PkgHfilesInfo {
 	if err != nil {
 		panic(err)
 	}
+	outfile.Close()
+	defer os.Remove(outfile.Name())
 
 	cflags := append(args, strings.Fields(conf.CFlags)...)
 	inters := make(map[string]struct{})
@@ -113,9 +136,10 @@ func PkgHfileInfo(conf *llcppg.Config, args []string) *PkgHfilesInfo {
 	for _, f := range conf.Include {
 		content := "#include <" + f + ">"
 		index, unit, err := clangutils.CreateTranslationUnit(&clangutils.Config{
-			File: content,
-			Temp: true,
-			Args: cflags,
+			File:   content,
+			Temp:   true,
+			Args:   cflags,
+			Target: conf.Target.ClangTriple(),
 		})
 		if err != nil {
 			panic(err)
@@ -133,9 +157,10 @@ func PkgHfileInfo(conf *llcppg.Config, args []string) *PkgHfilesInfo {
 
 	clangutils.ComposeIncludes(conf.Include, outfile.Name())
 	index, unit, err := clangutils.CreateTranslationUnit(&clangutils.Config{
-		File: outfile.Name(),
-		Temp: false,
-		Args: cflags,
+		File:   outfile.Name(),
+		Temp:   false,
+		Args:   cflags,
+		Target: conf.Target.ClangTriple(),
 	})
 	defer unit.Dispose()
 	defer index.Dispose()
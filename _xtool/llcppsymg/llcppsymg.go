@@ -69,6 +69,7 @@ func main() {
 		fmt.Println("Include:", conf.Include)
 		fmt.Println("TrimPrefixes:", conf.TrimPrefixes)
 		fmt.Println("Cplusplus:", conf.Cplusplus)
+		fmt.Println("Target:", conf.Target.Goos()+"/"+conf.Target.Goarch())
 	}
 
 	if err != nil {
@@ -83,10 +84,10 @@ func main() {
 		fmt.Println("implements", pkgHfiles.Impls)
 		fmt.Println("thirdhfile", pkgHfiles.Thirds)
 	}
-	headerInfos, err := parse.ParseHeaderFile(pkgHfiles.CurPkgFiles(), conf.TrimPrefixes, strings.Fields(conf.CFlags), conf.Cplusplus, false)
+	headerInfos, err := parse.ParseHeaderFile(pkgHfiles.CurPkgFiles(), conf.TrimPrefixes, strings.Fields(conf.CFlags), conf.Cplusplus, conf.Target, false, conf.OperatorName)
 	check(err)
 
-	symbolData, err := symbol.GenerateAndUpdateSymbolTable(symbols, headerInfos, symbFile)
+	symbolData, err := symbol.GenerateAndUpdateSymbolTable(symbols, headerInfos, symbFile, conf.Target.Goos())
 	check(err)
 
 	err = os.WriteFile(symbFile, symbolData, 0644)
@@ -0,0 +1,470 @@
+// Package mangle derives the Itanium C++ ABI symbols of member functions,
+// like _ZN3FooIiE3getEv for Foo<int>::get().
+//
+// Only the subset of the ABI that C style libraries use is covered: builtin,
+// qualified, pointer, reference, array, function and (template) class types,
+// with the std abbreviations. The types are resolved by the caller, from libclang
+// in llcppsymg, so the encoding itself doesn't depend on it.
+package mangle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Type is a C++ type: Builtin, Qualified, Pointer, LValueRef, RValueRef, Array,
+// Func, *Class, or Integral as a template argument.
+type Type interface {
+	isType()
+}
+
+// Builtin is a builtin type by its code, like i for int.
+type Builtin string
+
+// Qualified is a cv-qualified type.
+type Qualified struct {
+	Const    bool
+	Volatile bool
+	Restrict bool
+	Type     Type
+}
+
+type Pointer struct {
+	Elem Type
+}
+
+type LValueRef struct {
+	Elem Type
+}
+
+type RValueRef struct {
+	Elem Type
+}
+
+type Array struct {
+	Len  int64
+	Elem Type
+}
+
+// Func is a function type, the top-level qualifiers of its parameters are ignored.
+type Func struct {
+	Result   Type
+	Params   []Type
+	Variadic bool
+}
+
+// Class is a class, struct, union or enum named by its scopes, like std, vector.
+// Args are the arguments of a class template specialization.
+type Class struct {
+	Scopes []string
+	Args   []Type
+}
+
+// Integral is a non-type template argument of a builtin type.
+type Integral struct {
+	Type  Builtin
+	Value int64
+}
+
+func (Builtin) isType()   {}
+func (Qualified) isType() {}
+func (Pointer) isType()   {}
+func (LValueRef) isType() {}
+func (RValueRef) isType() {}
+func (Array) isType()     {}
+func (Func) isType()      {}
+func (*Class) isType()    {}
+func (Integral) isType()  {}
+
+// Name is the unqualified name of a member function: Ident, Ctor, Dtor,
+// Operator or Conversion.
+type Name interface {
+	isName()
+}
+
+// Ident is the name of an ordinary member function.
+type Ident string
+
+// Ctor is a constructor variant.
+type Ctor int
+
+const (
+	CompleteCtor   Ctor = 1 // C1, called to construct a complete object
+	BaseCtor       Ctor = 2 // C2, called by the constructors of derived classes
+	AllocatingCtor Ctor = 3 // C3
+)
+
+// Dtor is a destructor variant.
+type Dtor int
+
+const (
+	DeletingDtor Dtor = 0 // D0, destroys and deletes the object, only for virtual destructors
+	CompleteDtor Dtor = 1 // D1, called to destroy a complete object
+	BaseDtor     Dtor = 2 // D2, called by the destructors of derived classes
+)
+
+// Operator is a member operator, whose object counts as one of its operands.
+type Operator struct {
+	Op       string // like + or [], spaces are ignored
+	Operands int
+}
+
+// Conversion is a conversion operator to Type.
+type Conversion struct {
+	Type Type
+}
+
+func (Ident) isName()      {}
+func (Ctor) isName()       {}
+func (Dtor) isName()       {}
+func (Operator) isName()   {}
+func (Conversion) isName() {}
+
+// Member is a member function of a class.
+type Member struct {
+	Class    *Class
+	Name     Name
+	Const    bool
+	Params   []Type // the top-level qualifiers are ignored
+	Variadic bool
+}
+
+// Mangle returns the symbol of the member function.
+func (m *Member) Mangle() (string, error) {
+	e := &encoder{}
+	var b strings.Builder
+	b.WriteString("_ZN")
+	if m.Const {
+		b.WriteString("K")
+	}
+	class, _, err := e.class(m.Class)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(class)
+	name, err := e.name(m.Name)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(name)
+	b.WriteString("E")
+	params, err := e.params(m.Params, m.Variadic)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(params)
+	return b.String(), nil
+}
+
+// operatorCodes are the operator names of the ABI,
+// the first is for the unary form and the second for the binary form
+var operatorCodes = map[string][2]string{
+	"+":        {"ps", "pl"},
+	"-":        {"ng", "mi"},
+	"*":        {"de", "ml"},
+	"&":        {"ad", "an"},
+	"++":       {"pp", "pp"},
+	"--":       {"mm", "mm"},
+	"/":        {"dv", "dv"},
+	"%":        {"rm", "rm"},
+	"^":        {"eo", "eo"},
+	"|":        {"or", "or"},
+	"~":        {"co", "co"},
+	"!":        {"nt", "nt"},
+	"=":        {"aS", "aS"},
+	"<":        {"lt", "lt"},
+	">":        {"gt", "gt"},
+	"+=":       {"pL", "pL"},
+	"-=":       {"mI", "mI"},
+	"*=":       {"mL", "mL"},
+	"/=":       {"dV", "dV"},
+	"%=":       {"rM", "rM"},
+	"^=":       {"eO", "eO"},
+	"&=":       {"aN", "aN"},
+	"|=":       {"oR", "oR"},
+	"<<":       {"ls", "ls"},
+	">>":       {"rs", "rs"},
+	"<<=":      {"lS", "lS"},
+	">>=":      {"rS", "rS"},
+	"==":       {"eq", "eq"},
+	"!=":       {"ne", "ne"},
+	"<=":       {"le", "le"},
+	">=":       {"ge", "ge"},
+	"<=>":      {"ss", "ss"},
+	"&&":       {"aa", "aa"},
+	"||":       {"oo", "oo"},
+	",":        {"cm", "cm"},
+	"->*":      {"pm", "pm"},
+	"->":       {"pt", "pt"},
+	"()":       {"cl", "cl"},
+	"[]":       {"ix", "ix"},
+	"new":      {"nw", "nw"},
+	"delete":   {"dl", "dl"},
+	"new[]":    {"na", "na"},
+	"delete[]": {"da", "da"},
+}
+
+// stdTemplates are the abbreviations of the std class templates
+var stdTemplates = map[string]string{
+	"allocator":    "Sa",
+	"basic_string": "Sb",
+}
+
+// stdClasses are the abbreviations of the std specializations, keyed by their
+// mangling without substitutions
+var stdClasses = map[string]string{
+	"St12basic_stringIcSt11char_traitsIcESaIcEE": "Ss",
+	"St13basic_istreamIcSt11char_traitsIcEE":     "Si",
+	"St13basic_ostreamIcSt11char_traitsIcEE":     "So",
+	"St14basic_iostreamIcSt11char_traitsIcEE":    "Sd",
+}
+
+type encoder struct {
+	subs []string // substitution candidates, keyed by their mangling without substitutions
+	// plain disables substitutions, it is used to compute the keys of subs
+	plain bool
+}
+
+func (e *encoder) name(name Name) (string, error) {
+	switch name := name.(type) {
+	case Ident:
+		return sourceName(string(name)), nil
+	case Ctor:
+		return "C" + strconv.Itoa(int(name)), nil
+	case Dtor:
+		return "D" + strconv.Itoa(int(name)), nil
+	case Operator:
+		codes, ok := operatorCodes[strings.ReplaceAll(name.Op, " ", "")]
+		if !ok {
+			return "", fmt.Errorf("operator %s is not supported", name.Op)
+		}
+		if name.Operands == 1 {
+			return codes[0], nil
+		}
+		return codes[1], nil
+	case Conversion:
+		typ, err := e.typ(name.Type)
+		return "cv" + typ, err
+	}
+	return "", fmt.Errorf("unsupported name %v", name)
+}
+
+func (e *encoder) params(params []Type, variadic bool) (string, error) {
+	var b strings.Builder
+	for _, param := range params {
+		// top-level cv-qualifiers of parameters are not part of the signature
+		if q, ok := param.(Qualified); ok {
+			param = q.Type
+		}
+		typ, err := e.typ(param)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(typ)
+	}
+	if variadic {
+		b.WriteString("z")
+	} else if len(params) == 0 {
+		b.WriteString("v")
+	}
+	return b.String(), nil
+}
+
+func (e *encoder) typ(t Type) (string, error) {
+	switch t := t.(type) {
+	case Builtin:
+		return string(t), nil
+	case Integral:
+		if t.Value < 0 {
+			return "L" + string(t.Type) + "n" + strconv.FormatInt(-t.Value, 10) + "E", nil
+		}
+		return "L" + string(t.Type) + strconv.FormatInt(t.Value, 10) + "E", nil
+	case Qualified:
+		quals := t.qualifiers()
+		if quals == "" {
+			return e.typ(t.Type)
+		}
+		return e.substitutable(t, func() (string, error) {
+			s, err := e.typ(t.Type)
+			return quals + s, err
+		})
+	case Pointer:
+		return e.substitutable(t, func() (string, error) {
+			s, err := e.typ(t.Elem)
+			return "P" + s, err
+		})
+	case LValueRef:
+		return e.substitutable(t, func() (string, error) {
+			s, err := e.typ(t.Elem)
+			return "R" + s, err
+		})
+	case RValueRef:
+		return e.substitutable(t, func() (string, error) {
+			s, err := e.typ(t.Elem)
+			return "O" + s, err
+		})
+	case Array:
+		return e.substitutable(t, func() (string, error) {
+			s, err := e.typ(t.Elem)
+			return "A" + strconv.FormatInt(t.Len, 10) + "_" + s, err
+		})
+	case Func:
+		return e.substitutable(t, func() (string, error) {
+			ret, err := e.typ(t.Result)
+			if err != nil {
+				return "", err
+			}
+			params, err := e.params(t.Params, t.Variadic)
+			return "F" + ret + params + "E", err
+		})
+	case *Class:
+		name, nested, err := e.class(t)
+		if nested {
+			name = "N" + name + "E"
+		}
+		return name, err
+	}
+	return "", fmt.Errorf("unsupported type %v", t)
+}
+
+// class mangles the name of a class and its template arguments,
+// nested reports whether it has to be wrapped in N...E
+func (e *encoder) class(class *Class) (name string, nested bool, err error) {
+	key := pathKey(class.Scopes)
+	if len(class.Args) > 0 {
+		plainArgs, err := (&encoder{plain: true}).templateArgs(class.Args)
+		if err != nil {
+			return "", false, err
+		}
+		key += "I" + plainArgs + "E"
+		if abbr, ok := stdClasses[key]; ok {
+			return abbr, false, nil
+		}
+	}
+	if s, ok := e.lookup(key); ok {
+		return s, false, nil
+	}
+	name, nested = e.prefix(class.Scopes)
+	if len(class.Args) == 0 {
+		return name, nested, nil
+	}
+	args, err := e.templateArgs(class.Args)
+	if err != nil {
+		return "", false, err
+	}
+	e.add(key)
+	return name + "I" + args + "E", nested, nil
+}
+
+// prefix mangles the scopes and the name of a class, adding each of them as a substitution candidate
+func (e *encoder) prefix(scopes []string) (name string, nested bool) {
+	key := ""
+	for i, scope := range scopes {
+		if i == 0 && scope == "std" {
+			if abbr, ok := stdTemplates[scopes[len(scopes)-1]]; ok && len(scopes) == 2 {
+				return abbr, false
+			}
+			key, name = "St", "St"
+			continue
+		}
+		key += sourceName(scope)
+		if s, ok := e.lookup(key); ok {
+			name, nested = s, false
+			continue
+		}
+		if name != "" && name != "St" {
+			nested = true
+		}
+		name += sourceName(scope)
+		e.add(key)
+	}
+	return
+}
+
+func (e *encoder) templateArgs(args []Type) (string, error) {
+	var b strings.Builder
+	for _, arg := range args {
+		s, err := e.typ(arg)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s)
+	}
+	return b.String(), nil
+}
+
+func (e *encoder) substitutable(t Type, mangle func() (string, error)) (string, error) {
+	if e.plain {
+		return mangle()
+	}
+	key, err := (&encoder{plain: true}).typ(t)
+	if err != nil {
+		return "", err
+	}
+	if s, ok := e.lookup(key); ok {
+		return s, nil
+	}
+	s, err := mangle()
+	if err != nil {
+		return "", err
+	}
+	e.add(key)
+	return s, nil
+}
+
+func (e *encoder) lookup(key string) (string, bool) {
+	if e.plain {
+		return "", false
+	}
+	for i, sub := range e.subs {
+		if sub == key {
+			return seqID(i), true
+		}
+	}
+	return "", false
+}
+
+func (e *encoder) add(key string) {
+	if !e.plain {
+		e.subs = append(e.subs, key)
+	}
+}
+
+// S_, S0_, S1_, ..., S9_, SA_, ..., SZ_, S10_, ...
+func seqID(i int) string {
+	if i == 0 {
+		return "S_"
+	}
+	return "S" + strings.ToUpper(strconv.FormatInt(int64(i-1), 36)) + "_"
+}
+
+func sourceName(name string) string {
+	return strconv.Itoa(len(name)) + name
+}
+
+func pathKey(scopes []string) string {
+	var b strings.Builder
+	for i, scope := range scopes {
+		if i == 0 && scope == "std" {
+			b.WriteString("St")
+			continue
+		}
+		b.WriteString(sourceName(scope))
+	}
+	return b.String()
+}
+
+func (q Qualified) qualifiers() string {
+	quals := ""
+	if q.Restrict {
+		quals += "r"
+	}
+	if q.Volatile {
+		quals += "V"
+	}
+	if q.Const {
+		quals += "K"
+	}
+	return quals
+}
@@ -0,0 +1,262 @@
+package mangle_test
+
+import (
+	"testing"
+
+	"github.com/goplus/llcppg/_xtool/llcppsymg/mangle"
+)
+
+// The symbols are the ones g++ emits for the members of explicit
+// instantiations, demangled in the comments by c++filt.
+func TestMangle(t *testing.T) {
+	var (
+		i    = mangle.Builtin("i")
+		c    = mangle.Builtin("c")
+		v    = mangle.Builtin("v")
+		bar  = &mangle.Class{Scopes: []string{"Bar"}}
+		foo  = &mangle.Class{Scopes: []string{"Foo"}, Args: []mangle.Type{i}}
+		char = &mangle.Class{Scopes: []string{"std", "char_traits"}, Args: []mangle.Type{c}}
+	)
+	method := func(name string, params ...mangle.Type) *mangle.Member {
+		return &mangle.Member{Class: foo, Name: mangle.Ident(name), Params: params}
+	}
+	cref := func(t mangle.Type) mangle.Type {
+		return mangle.LValueRef{Elem: mangle.Qualified{Const: true, Type: t}}
+	}
+	letters := func(names ...string) []mangle.Type {
+		var types []mangle.Type
+		for _, name := range names {
+			types = append(types, &mangle.Class{Scopes: []string{name}})
+		}
+		return types
+	}
+	testCases := []struct {
+		name   string
+		member *mangle.Member
+		want   string
+	}{
+		{
+			// Foo<int>::get()
+			name:   "method",
+			member: method("get"),
+			want:   "_ZN3FooIiE3getEv",
+		},
+		{
+			// Foo<int>::get() const
+			name:   "const method",
+			member: &mangle.Member{Class: foo, Name: mangle.Ident("get"), Const: true},
+			want:   "_ZNK3FooIiE3getEv",
+		},
+		{
+			// Foo<int>::set(int)
+			name:   "top-level const parameter",
+			member: method("set", mangle.Qualified{Const: true, Type: i}),
+			want:   "_ZN3FooIiE3setEi",
+		},
+		{
+			// Foo<int>::set(Foo<int> const&, Foo<int>*)
+			name:   "class substitution",
+			member: method("set", cref(foo), mangle.Pointer{Elem: foo}),
+			want:   "_ZN3FooIiE3setERKS0_PS0_",
+		},
+		{
+			// Foo<int>::swap(Foo<int>&, Bar*, Bar*)
+			name:   "pointer substitution",
+			member: method("swap", mangle.LValueRef{Elem: foo}, mangle.Pointer{Elem: bar}, mangle.Pointer{Elem: bar}),
+			want:   "_ZN3FooIiE4swapERS0_P3BarS3_",
+		},
+		{
+			// Foo<int>::many(A, B, C, D, E, F, G, H, I, J, K, L, L)
+			name:   "base 36 substitution",
+			member: method("many", letters("A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "L")...),
+			want:   "_ZN3FooIiE4manyE1A1B1C1D1E1F1G1H1I1J1K1LSC_",
+		},
+		{
+			// ns::Foo<ns::Bar>::get(ns::Bar)
+			name: "namespace substitution",
+			member: &mangle.Member{
+				Class:  &mangle.Class{Scopes: []string{"ns", "Foo"}, Args: []mangle.Type{&mangle.Class{Scopes: []string{"ns", "Bar"}}}},
+				Name:   mangle.Ident("get"),
+				Params: []mangle.Type{&mangle.Class{Scopes: []string{"ns", "Bar"}}},
+			},
+			want: "_ZN2ns3FooINS_3BarEE3getES1_",
+		},
+		{
+			// Pair<Bar, Bar*>::get(Bar, Bar*)
+			name: "template argument substitution",
+			member: &mangle.Member{
+				Class:  &mangle.Class{Scopes: []string{"Pair"}, Args: []mangle.Type{bar, mangle.Pointer{Elem: bar}}},
+				Name:   mangle.Ident("get"),
+				Params: []mangle.Type{bar, mangle.Pointer{Elem: bar}},
+			},
+			want: "_ZN4PairI3BarPS0_E3getES0_S1_",
+		},
+		{
+			// Foo<int>::call(void (*)(int), void (*)(int))
+			name: "function pointer",
+			member: method("call",
+				mangle.Pointer{Elem: mangle.Func{Result: v, Params: []mangle.Type{i}}},
+				mangle.Pointer{Elem: mangle.Func{Result: v, Params: []mangle.Type{i}}}),
+			want: "_ZN3FooIiE4callEPFviES2_",
+		},
+		{
+			// Foo<int>::arr(int (&) [4])
+			name:   "array reference",
+			member: method("arr", mangle.LValueRef{Elem: mangle.Array{Len: 4, Elem: i}}),
+			want:   "_ZN3FooIiE3arrERA4_i",
+		},
+		{
+			// Foo<int>::log(char const*, ...)
+			name:   "variadic",
+			member: &mangle.Member{Class: foo, Name: mangle.Ident("log"), Params: []mangle.Type{mangle.Pointer{Elem: mangle.Qualified{Const: true, Type: c}}}, Variadic: true},
+			want:   "_ZN3FooIiE3logEPKcz",
+		},
+		{
+			// Foo<int>::cv(int const volatile*, int volatile*)
+			name: "cv-qualifiers",
+			member: method("cv",
+				mangle.Pointer{Elem: mangle.Qualified{Const: true, Volatile: true, Type: i}},
+				mangle.Pointer{Elem: mangle.Qualified{Volatile: true, Type: i}}),
+			want: "_ZN3FooIiE2cvEPVKiPVi",
+		},
+		{
+			// Foo<int>::alloc(std::allocator<int>, std::allocator<int>)
+			name: "std::allocator",
+			member: method("alloc",
+				&mangle.Class{Scopes: []string{"std", "allocator"}, Args: []mangle.Type{i}},
+				&mangle.Class{Scopes: []string{"std", "allocator"}, Args: []mangle.Type{i}}),
+			want: "_ZN3FooIiE5allocESaIiES1_",
+		},
+		{
+			// Foo<int>::put(std::string, std::string) with the old string of libstdc++
+			name: "std::string",
+			member: method("put",
+				&mangle.Class{Scopes: []string{"std", "basic_string"}, Args: []mangle.Type{c, char, &mangle.Class{Scopes: []string{"std", "allocator"}, Args: []mangle.Type{c}}}},
+				&mangle.Class{Scopes: []string{"std", "basic_string"}, Args: []mangle.Type{c, char, &mangle.Class{Scopes: []string{"std", "allocator"}, Args: []mangle.Type{c}}}}),
+			want: "_ZN3FooIiE3putESsSs",
+		},
+		{
+			// Foo<int>::put(std::__cxx11::string, std::__cxx11::string)
+			name: "std::__cxx11::string",
+			member: method("put",
+				&mangle.Class{Scopes: []string{"std", "__cxx11", "basic_string"}, Args: []mangle.Type{c, char, &mangle.Class{Scopes: []string{"std", "allocator"}, Args: []mangle.Type{c}}}},
+				&mangle.Class{Scopes: []string{"std", "__cxx11", "basic_string"}, Args: []mangle.Type{c, char, &mangle.Class{Scopes: []string{"std", "allocator"}, Args: []mangle.Type{c}}}}),
+			want: "_ZN3FooIiE3putENSt7__cxx1112basic_stringIcSt11char_traitsIcESaIcEEES6_",
+		},
+		{
+			// Foo<int>::print(std::ostream&)
+			name:   "std::ostream",
+			member: method("print", mangle.LValueRef{Elem: &mangle.Class{Scopes: []string{"std", "basic_ostream"}, Args: []mangle.Type{c, char}}}),
+			want:   "_ZN3FooIiE5printERSo",
+		},
+		{
+			// Foo<int>::take(std::detail::Q*, std::detail::Q*)
+			name: "nested std",
+			member: method("take",
+				mangle.Pointer{Elem: &mangle.Class{Scopes: []string{"std", "detail", "Q"}}},
+				mangle.Pointer{Elem: &mangle.Class{Scopes: []string{"std", "detail", "Q"}}}),
+			want: "_ZN3FooIiE4takeEPNSt6detail1QES3_",
+		},
+		{
+			// Foo<int>::box(std::__1::box<std::__1::traits<char> >, std::__1::traits<char>)
+			name: "std scope substitution",
+			member: method("box",
+				&mangle.Class{Scopes: []string{"std", "__1", "box"}, Args: []mangle.Type{&mangle.Class{Scopes: []string{"std", "__1", "traits"}, Args: []mangle.Type{c}}}},
+				&mangle.Class{Scopes: []string{"std", "__1", "traits"}, Args: []mangle.Type{c}}),
+			want: "_ZN3FooIiE3boxENSt3__13boxINS1_6traitsIcEEEES4_",
+		},
+		{
+			// Foo<int>::Foo()
+			name:   "complete constructor",
+			member: &mangle.Member{Class: foo, Name: mangle.CompleteCtor},
+			want:   "_ZN3FooIiEC1Ev",
+		},
+		{
+			// Foo<int>::Foo(Foo<int> const&)
+			name:   "base constructor",
+			member: &mangle.Member{Class: foo, Name: mangle.BaseCtor, Params: []mangle.Type{cref(foo)}},
+			want:   "_ZN3FooIiEC2ERKS0_",
+		},
+		{
+			// Foo<int>::~Foo()
+			name:   "deleting destructor",
+			member: &mangle.Member{Class: foo, Name: mangle.DeletingDtor},
+			want:   "_ZN3FooIiED0Ev",
+		},
+		{
+			// Foo<int>::~Foo()
+			name:   "complete destructor",
+			member: &mangle.Member{Class: foo, Name: mangle.CompleteDtor},
+			want:   "_ZN3FooIiED1Ev",
+		},
+		{
+			// Foo<int>::~Foo()
+			name:   "base destructor",
+			member: &mangle.Member{Class: foo, Name: mangle.BaseDtor},
+			want:   "_ZN3FooIiED2Ev",
+		},
+		{
+			// Arr<5>::get()
+			name:   "integral argument",
+			member: &mangle.Member{Class: &mangle.Class{Scopes: []string{"Arr"}, Args: []mangle.Type{mangle.Integral{Type: i, Value: 5}}}, Name: mangle.Ident("get")},
+			want:   "_ZN3ArrILi5EE3getEv",
+		},
+		{
+			// Arr<-3>::get()
+			name:   "negative argument",
+			member: &mangle.Member{Class: &mangle.Class{Scopes: []string{"Arr"}, Args: []mangle.Type{mangle.Integral{Type: i, Value: -3}}}, Name: mangle.Ident("get")},
+			want:   "_ZN3ArrILin3EE3getEv",
+		},
+		{
+			// Arr2<-3l>::get()
+			name:   "negative long argument",
+			member: &mangle.Member{Class: &mangle.Class{Scopes: []string{"Arr2"}, Args: []mangle.Type{mangle.Integral{Type: "l", Value: -3}}}, Name: mangle.Ident("get")},
+			want:   "_ZN4Arr2ILln3EE3getEv",
+		},
+		{
+			// Flag<true>::get()
+			name:   "bool argument",
+			member: &mangle.Member{Class: &mangle.Class{Scopes: []string{"Flag"}, Args: []mangle.Type{mangle.Integral{Type: "b", Value: 1}}}, Name: mangle.Ident("get")},
+			want:   "_ZN4FlagILb1EE3getEv",
+		},
+		{
+			// Foo<int>::operator+(Foo<int> const&)
+			name:   "binary operator",
+			member: &mangle.Member{Class: foo, Name: mangle.Operator{Op: "+", Operands: 2}, Params: []mangle.Type{cref(foo)}},
+			want:   "_ZN3FooIiEplERKS0_",
+		},
+		{
+			// Foo<int>::operator-()
+			name:   "unary operator",
+			member: &mangle.Member{Class: foo, Name: mangle.Operator{Op: "-", Operands: 1}},
+			want:   "_ZN3FooIiEngEv",
+		},
+		{
+			// Foo<int>::operator int()
+			name:   "conversion",
+			member: &mangle.Member{Class: foo, Name: mangle.Conversion{Type: i}},
+			want:   "_ZN3FooIiEcviEv",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.member.Mangle()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("Mangle() = %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMangleUnsupportedOperator(t *testing.T) {
+	member := &mangle.Member{
+		Class: &mangle.Class{Scopes: []string{"Foo"}},
+		Name:  mangle.Operator{Op: "co_await", Operands: 1},
+	}
+	if _, err := member.Mangle(); err == nil {
+		t.Error("expected an error for an unsupported operator")
+	}
+}
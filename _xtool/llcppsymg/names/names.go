@@ -9,14 +9,16 @@ import (
 
 // NameMapper handles name mapping and uniqueness for Go symbols
 type NameMapper struct {
-	count   map[string]int    // tracks count of each public name for uniqueness
-	mapping map[string]string // maps original c names to Go names,like: foo(in c) -> Foo(in go)
+	count    map[string]int             // tracks count of each public name for uniqueness
+	mapping  map[string]string          // maps original c names to Go names,like: foo(in c) -> Foo(in go)
+	unscoped map[string]map[string]bool // maps Go names without scopes to the c names reserved for them
 }
 
 func NewNameMapper() *NameMapper {
 	return &NameMapper{
-		count:   make(map[string]int),
-		mapping: make(map[string]string),
+		count:    make(map[string]int),
+		mapping:  make(map[string]string),
+		unscoped: make(map[string]map[string]bool),
 	}
 }
 
@@ -37,6 +39,49 @@ func (m *NameMapper) GetUniqueGoName(name string, trimPrefixes []string, toCamel
 	return pubName, pubName != name
 }
 
+// returns a unique Go name for a C++ qualified name,like ns::Widget -> Widget.
+// The scopes are dropped from the Go name, unless the name without them is
+// already taken or reserved for another c name, then the scopes are kept to
+// tell them apart,like ns::Widget -> NsWidget.
+func (m *NameMapper) GetUniqueUnscopedGoName(name string, trimPrefixes []string) (string, bool) {
+	if _, exist := m.mapping[name]; !exist {
+		if _, base := SplitScope(name); base != name {
+			pubName := unscopedName(base, trimPrefixes)
+			if m.count[pubName] == 0 && m.reservedFor(pubName, name) {
+				m.count[pubName]++
+				return pubName, true
+			}
+		}
+	}
+	return m.GetUniqueGoName(name, trimPrefixes, true)
+}
+
+// ReserveUnscopedName records a c name to be declared, then GetUniqueUnscopedGoName
+// keeps the scopes of all the names that are the same without them, whatever
+// order they are declared in,like: a::Widget -> AWidget, b::Widget -> BWidget.
+func (m *NameMapper) ReserveUnscopedName(name string, trimPrefixes []string) {
+	_, base := SplitScope(name)
+	pubName := unscopedName(base, trimPrefixes)
+	if m.unscoped[pubName] == nil {
+		m.unscoped[pubName] = make(map[string]bool)
+	}
+	m.unscoped[pubName][name] = true
+}
+
+// reservedFor reports whether no c name other than name is reserved for pubName.
+func (m *NameMapper) reservedFor(pubName, name string) bool {
+	for reserved := range m.unscoped[pubName] {
+		if reserved != name {
+			return false
+		}
+	}
+	return true
+}
+
+func unscopedName(base string, trimPrefixes []string) string {
+	return PubName(removePrefixedName(base, trimPrefixes))
+}
+
 // returns the Go name for an original name,if the name is already mapped,return the mapped name
 func (m *NameMapper) genGoName(name string, trimPrefixes []string, toCamel bool) (string, bool) {
 	if goName, exists := m.mapping[name]; exists {
@@ -68,6 +113,25 @@ func GoName(name string, trimPrefixes []string, inCurPkg bool) string {
 	return PubName(name)
 }
 
+// SplitScope splits a C++ qualified name into its scopes and its name,
+// the scopes in template arguments are kept,like: a::Foo<b::Bar> -> a, Foo<b::Bar>
+func SplitScope(name string) (scope string, base string) {
+	depth := 0
+	for i := len(name) - 1; i > 0; i-- {
+		switch name[i] {
+		case '>':
+			depth++
+		case '<':
+			depth--
+		case ':':
+			if depth == 0 && name[i-1] == ':' {
+				return name[:i-1], name[i+1:]
+			}
+		}
+	}
+	return "", name
+}
+
 func removePrefixedName(name string, trimPrefixes []string) string {
 	if len(trimPrefixes) == 0 {
 		return name
@@ -84,6 +148,9 @@ func PubName(name string) string {
 	if len(name) == 0 {
 		return name
 	}
+	if strings.ContainsAny(name, "<:") {
+		name = flatTemplateName(name)
+	}
 	baseName := strings.Trim(name, "_")
 	if len(baseName) == 0 {
 		return "X" + name
@@ -97,6 +164,152 @@ func PubName(name string) string {
 	return ToCamelCase(baseName, true) + suffix
 }
 
+// flatTemplateName turns the spelling of a class template instantiation
+// into an identifier,like: Foo<unsigned int *> -> Foo_unsigned_int_ptr
+func flatTemplateName(name string) string {
+	var b strings.Builder
+	sep := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "_") {
+			b.WriteByte('_')
+		}
+	}
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '*':
+			sep()
+			b.WriteString("ptr")
+		case r == '&':
+			sep()
+			b.WriteString("ref")
+		case r == '-':
+			sep()
+			b.WriteString("neg")
+		default:
+			sep()
+		}
+	}
+	return strings.TrimRight(b.String(), "_")
+}
+
+// Operator returns the operator of a C++ operator function name,like:
+// operator+ -> +, operator[] -> [], operator bool -> bool.
+// ok is false if the name isn't an operator function,like operators_count.
+func Operator(name string) (op string, ok bool) {
+	rest, found := strings.CutPrefix(name, "operator")
+	if !found || rest == "" {
+		return "", false
+	}
+	if r := rune(rest[0]); r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// operators maps the C++ operators to Go method names,
+// the first is for the unary form and the second for the binary form
+var operators = map[string][2]string{
+	"+":        {"Plus", "Add"},
+	"-":        {"Neg", "Sub"},
+	"*":        {"Deref", "Mul"},
+	"&":        {"AddrOf", "And"},
+	"++":       {"Inc", "PostInc"},
+	"--":       {"Dec", "PostDec"},
+	"/":        {"Div", "Div"},
+	"%":        {"Mod", "Mod"},
+	"^":        {"Xor", "Xor"},
+	"|":        {"Or", "Or"},
+	"~":        {"Not", "Not"},
+	"!":        {"LNot", "LNot"},
+	"=":        {"Assign", "Assign"},
+	"<":        {"Less", "Less"},
+	">":        {"Greater", "Greater"},
+	"+=":       {"AddAssign", "AddAssign"},
+	"-=":       {"SubAssign", "SubAssign"},
+	"*=":       {"MulAssign", "MulAssign"},
+	"/=":       {"DivAssign", "DivAssign"},
+	"%=":       {"ModAssign", "ModAssign"},
+	"^=":       {"XorAssign", "XorAssign"},
+	"&=":       {"AndAssign", "AndAssign"},
+	"|=":       {"OrAssign", "OrAssign"},
+	"<<":       {"Shl", "Shl"},
+	">>":       {"Shr", "Shr"},
+	"<<=":      {"ShlAssign", "ShlAssign"},
+	">>=":      {"ShrAssign", "ShrAssign"},
+	"==":       {"Eq", "Eq"},
+	"!=":       {"Ne", "Ne"},
+	"<=":       {"LessEq", "LessEq"},
+	">=":       {"GreaterEq", "GreaterEq"},
+	"<=>":      {"Compare", "Compare"},
+	"&&":       {"LAnd", "LAnd"},
+	"||":       {"LOr", "LOr"},
+	",":        {"Comma", "Comma"},
+	"->*":      {"ArrowStar", "ArrowStar"},
+	"->":       {"Arrow", "Arrow"},
+	"()":       {"Call", "Call"},
+	"[]":       {"Index", "Index"},
+	"new":      {"New", "New"},
+	"delete":   {"Delete", "Delete"},
+	"new[]":    {"NewArray", "NewArray"},
+	"delete[]": {"DeleteArray", "DeleteArray"},
+}
+
+// OperatorGoName returns the Go method name of a C++ operator,operands is the
+// number of its operands including the object of a member operator,like:
+// (+, 1) -> Plus, (+, 2) -> Add, (++, 2) -> PostInc, ([], 2) -> Index.
+// A conversion operator is named after its type,like: bool -> ToBool,
+// const char * -> ToCharPtr, and a literal operator after its suffix,like: ""_km -> LitKm.
+func OperatorGoName(op string, operands int) string {
+	if names, ok := operators[strings.ReplaceAll(op, " ", "")]; ok {
+		if operands == 1 {
+			return names[0]
+		}
+		return names[1]
+	}
+	if suffix, ok := strings.CutPrefix(op, `""`); ok {
+		return "Lit" + PubName(strings.Trim(suffix, " _"))
+	}
+	return "To" + conversionTypeName(op)
+}
+
+// conversionTypeName turns the type of a conversion operator into a name,
+// the qualifiers and scopes are dropped,like: const ns::Foo & -> FooRef
+func conversionTypeName(typ string) string {
+	var b strings.Builder
+	word := func(w string) {
+		switch w {
+		case "", "const", "volatile", "struct", "class", "enum", "union":
+			return
+		}
+		if _, base := SplitScope(w); base != "" {
+			w = base
+		}
+		b.WriteString(PubName(w))
+	}
+	start := 0
+	depth := 0
+	for i, r := range typ {
+		switch {
+		case r == '<':
+			depth++
+		case r == '>':
+			depth--
+		case depth > 0:
+		case r == '*' || r == '&' || r == ' ':
+			word(typ[start:i])
+			start = i + 1
+			if r == '*' {
+				b.WriteString("Ptr")
+			} else if r == '&' {
+				b.WriteString("Ref")
+			}
+		}
+	}
+	word(typ[start:])
+	return b.String()
+}
+
 func sufUScore(name string) string {
 	return strings.Repeat("_", len(name)-len(strings.TrimRight(name, "_")))
 }
@@ -10,6 +10,7 @@ import (
 	"github.com/goplus/llcppg/_xtool/llcppsymg/clangutils"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/dbg"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/names"
+	"github.com/goplus/llcppg/llcppg"
 	"github.com/goplus/llgo/c/clang"
 )
 
@@ -27,6 +28,16 @@ type SymbolProcessor struct {
 	// will clean in a translation unit process end
 	processingFiles map[string]struct{}
 	processedFiles  map[string]struct{}
+	// spelling of the class template instantiations already collected
+	instances map[string]struct{}
+	// OperatorName returns the Go name configured for an operator function,
+	// like operator+ -> Plus, it's nil if none is configured
+	OperatorName func(name string) (string, bool)
+	// TargetOS is the GOOS of the target, whose symbols are prefixed with an underscore on darwin
+	TargetOS string
+	// signal that only the functions of C linkage are collected, in the first
+	// pass over a translation unit
+	externC bool
 }
 
 func panicSourceLocation(loc clang.SourceLocation, prefix string) {
@@ -45,6 +56,8 @@ func NewSymbolProcessor(Files []string, Prefixes []string) *SymbolProcessor {
 		NameCounts:      make(map[string]int),
 		processedFiles:  make(map[string]struct{}),
 		processingFiles: make(map[string]struct{}),
+		instances:       make(map[string]struct{}),
+		TargetOS:        runtime.GOOS,
 	}
 }
 
@@ -175,12 +188,59 @@ func (p *SymbolProcessor) isMethod(cur clang.Cursor, isArg bool) (bool, bool, st
 	return isInCurPkg, false, goName
 }
 
+// operatorGoName returns the Go name of the operator function cursor,
+// operands is the number of its operands including the object of a member operator.
+func (p *SymbolProcessor) operatorGoName(cursor clang.Cursor, operands int) (string, bool) {
+	name := clang.GoString(cursor.String())
+	op, ok := names.Operator(name)
+	if !ok {
+		return "", false
+	}
+	if p.OperatorName != nil {
+		if goName, ok := p.OperatorName(name); ok {
+			return goName, true
+		}
+	}
+	return names.OperatorGoName(op, operands), true
+}
+
+// refReceiver reports whether arg is a reference to a class of the current package,
+// like a in the free operator `Vec operator+(const Vec &a, const Vec &b)`.
+// A reference is passed as a pointer, so the class is a pointer receiver.
+func (p *SymbolProcessor) refReceiver(arg clang.Cursor) (bool, string) {
+	typ := arg.Type()
+	if typ.Kind != clang.TypeLValueReference && typ.Kind != clang.TypeRValueReference {
+		return false, ""
+	}
+	pointee := clangutils.UnqualifiedType(typ.PointeeType())
+	if pointee.CanonicalType().Kind != clang.TypeRecord {
+		return false, ""
+	}
+	decl := pointee.TypeDeclaration()
+	if !p.inCurPkg(decl, false) {
+		return false, ""
+	}
+	name := clang.GoString(pointee.NamedType().String())
+	if len(name) == 0 {
+		name = clang.GoString(decl.String())
+	}
+	return true, names.GoName(name, p.Prefixes, true)
+}
+
 func (p *SymbolProcessor) genGoName(cursor clang.Cursor) string {
 	originName := clang.GoString(cursor.String())
 	isDestructor := cursor.Kind == clang.CursorDestructor
+	numArgs := int(cursor.NumArguments())
+	operands := numArgs
+	if cursor.Kind != clang.CursorFunctionDecl {
+		operands++
+	}
 	var convertedName string
+	opName, isOperator := p.operatorGoName(cursor, operands)
 	if isDestructor {
 		convertedName = names.GoName(originName[1:], p.Prefixes, p.inCurPkg(cursor, false))
+	} else if isOperator {
+		convertedName = opName
 	} else {
 		convertedName = names.GoName(originName, p.Prefixes, p.inCurPkg(cursor, false))
 	}
@@ -189,11 +249,16 @@ func (p *SymbolProcessor) genGoName(cursor clang.Cursor) string {
 		class := names.GoName(clang.GoString(parent.String()), p.Prefixes, p.inCurPkg(cursor, false))
 		return p.AddSuffix(p.GenMethodName(class, convertedName, isDestructor, true))
 	} else if cursor.Kind == clang.CursorFunctionDecl {
-		numArgs := cursor.NumArguments()
 		if numArgs > 0 {
 			if ok, isPtr, typeName := p.isMethod(cursor.Argument(0), true); ok {
 				return p.AddSuffix(p.GenMethodName(typeName, convertedName, isDestructor, isPtr))
 			}
+			// a free operator is a method of the class of its first operand
+			if isOperator {
+				if ok, typeName := p.refReceiver(cursor.Argument(0)); ok {
+					return p.AddSuffix(p.GenMethodName(typeName, convertedName, false, true))
+				}
+			}
 		}
 	}
 	return p.AddSuffix(convertedName)
@@ -227,10 +292,7 @@ func (p *SymbolProcessor) collectFuncInfo(cursor clang.Cursor) {
 	if dbg.GetDebugSymbol() {
 		fmt.Printf("collectFuncInfo: %s %s\n", clang.GoString(cursor.Mangling()), clang.GoString(cursor.String()))
 	}
-	symbolName := clang.GoString(cursor.Mangling())
-	if runtime.GOOS == "darwin" {
-		symbolName = strings.TrimPrefix(symbolName, "_")
-	}
+	symbolName := clangutils.TrimMangling(p.TargetOS, clang.GoString(cursor.Mangling()))
 
 	// In C, multiple declarations of the same function are allowed.
 	// Functions with identical signatures will have the same mangled name.
@@ -245,6 +307,69 @@ func (p *SymbolProcessor) collectFuncInfo(cursor clang.Cursor) {
 	}
 }
 
+// collectInstantiation collects the methods of a class template instantiation,
+// like Foo<int> in `typedef Foo<int> IntFoo;` or `template class Foo<int>;`.
+// Except for explicit specializations, libclang can't give the members of an
+// instantiation, so their symbols are mangled from the members of the template.
+func (p *SymbolProcessor) collectInstantiation(t clang.Type) {
+	inst := clangutils.NewInstantiation(t)
+	spelling := inst.Spelling()
+	if _, ok := p.instances[spelling]; ok {
+		return
+	}
+	p.instances[spelling] = struct{}{}
+	if !p.inCurPkg(inst.Template, false) {
+		return
+	}
+	members := inst.Members()
+	if members.Kind == clang.CursorNoDeclFound {
+		return
+	}
+	explicit := inst.IsExplicitSpecialization()
+	class := names.GoName(spelling, p.Prefixes, true)
+	clangutils.VisitChildren(members, func(cursor, parent clang.Cursor) clang.ChildVisitResult {
+		switch cursor.Kind {
+		case clang.CursorCXXMethod, clang.CursorConversionFunction, clang.CursorConstructor, clang.CursorDestructor:
+		default:
+			return clang.ChildVisit_Continue
+		}
+		if cursor.CXXAccessSpecifier() != clang.CXXPublic {
+			return clang.ChildVisit_Continue
+		}
+		var symbolName string
+		if explicit {
+			symbolName = clangutils.TrimMangling(p.TargetOS, clang.GoString(cursor.Mangling()))
+		} else {
+			var err error
+			symbolName, err = inst.MangleMember(cursor)
+			if err != nil {
+				if dbg.GetDebugSymbol() {
+					fmt.Printf("collectInstantiation: %s %v\n", spelling, err)
+				}
+				return clang.ChildVisit_Continue
+			}
+		}
+		if dbg.GetDebugSymbol() {
+			fmt.Printf("collectInstantiation: %s %s::%s\n", symbolName, spelling, clang.GoString(cursor.String()))
+		}
+		if _, exists := p.SymbolMap[symbolName]; exists {
+			return clang.ChildVisit_Continue
+		}
+		isDestructor := cursor.Kind == clang.CursorDestructor
+		name := class
+		if opName, ok := p.operatorGoName(cursor, int(cursor.NumArguments())+1); ok {
+			name = opName
+		} else if cursor.Kind == clang.CursorCXXMethod {
+			name = names.GoName(clang.GoString(cursor.String()), p.Prefixes, true)
+		}
+		p.SymbolMap[symbolName] = &SymbolInfo{
+			GoName:    p.AddSuffix(p.GenMethodName(class, name, isDestructor, true)),
+			ProtoName: spelling + "::" + clang.GoString(cursor.DisplayName()),
+		}
+		return clang.ChildVisit_Continue
+	})
+}
+
 func (p *SymbolProcessor) visitTop(cursor, parent clang.Cursor) clang.ChildVisitResult {
 	filename := clang.GoString(cursor.Location().File().FileName())
 	if _, ok := p.processedFiles[filename]; ok {
@@ -261,10 +386,29 @@ func (p *SymbolProcessor) visitTop(cursor, parent clang.Cursor) clang.ChildVisit
 		fmt.Printf("visitTop: %s\n", filename)
 	}
 	switch cursor.Kind {
-	case clang.CursorNamespace, clang.CursorClassDecl:
+	case clang.CursorNamespace, clang.CursorLinkageSpec:
 		clangutils.VisitChildren(cursor, p.visitTop)
-	case clang.CursorCXXMethod, clang.CursorFunctionDecl, clang.CursorConstructor, clang.CursorDestructor:
-		isPublicMethod := (cursor.CXXAccessSpecifier() == clang.CXXPublic) && cursor.Kind == clang.CursorCXXMethod || cursor.Kind == clang.CursorConstructor || cursor.Kind == clang.CursorDestructor
+	case clang.CursorClassDecl, clang.CursorStructDecl:
+		if clangutils.IsInstantiation(cursor.Type()) {
+			// template class Foo<int>;
+			if p.isSelfFile(filename) && !p.externC {
+				p.collectInstantiation(cursor.Type())
+			}
+			return clang.ChildVisit_Continue
+		}
+		if cursor.Kind == clang.CursorClassDecl {
+			clangutils.VisitChildren(cursor, p.visitTop)
+		}
+	case clang.CursorTypedefDecl:
+		// typedef Foo<int> IntFoo;
+		if typ := cursor.TypedefDeclUnderlyingType().CanonicalType(); p.isSelfFile(filename) && !p.externC && clangutils.IsInstantiation(typ) {
+			p.collectInstantiation(typ)
+		}
+	case clang.CursorCXXMethod, clang.CursorConversionFunction, clang.CursorFunctionDecl, clang.CursorConstructor, clang.CursorDestructor:
+		isPublicMethod := (cursor.CXXAccessSpecifier() == clang.CXXPublic) && (cursor.Kind == clang.CursorCXXMethod || cursor.Kind == clang.CursorConversionFunction) || cursor.Kind == clang.CursorConstructor || cursor.Kind == clang.CursorDestructor
+		if p.externC && !clangutils.IsExternC(cursor) {
+			return clang.ChildVisit_Continue
+		}
 		if p.isSelfFile(filename) && (cursor.Kind == clang.CursorFunctionDecl || isPublicMethod) {
 			p.collectFuncInfo(cursor)
 		}
@@ -294,6 +438,11 @@ func (p *SymbolProcessor) collect(cfg *clangutils.Config) error {
 	if dbg.GetDebugSymbol() {
 		fmt.Printf("%s start collect \n", filename)
 	}
+	// The functions of C linkage are named first, so they keep their Go names
+	// whatever the C++ overloads declared before them, which take the suffixes.
+	p.externC = true
+	clangutils.VisitChildren(cursor, p.visitTop)
+	p.externC = false
 	clangutils.VisitChildren(cursor, p.visitTop)
 	for filename := range p.processingFiles {
 		p.processedFiles[filename] = struct{}{}
@@ -303,19 +452,25 @@ func (p *SymbolProcessor) collect(cfg *clangutils.Config) error {
 	return nil
 }
 
-func ParseHeaderFile(files []string, prefixes []string, cflags []string, isCpp bool, isTemp bool) (map[string]*SymbolInfo, error) {
+// ParseHeaderFile collects the symbols of the functions and methods declared in files
+// for the target, the host if it's nil. operatorName returns the Go name configured
+// for an operator function, it can be nil.
+func ParseHeaderFile(files []string, prefixes []string, cflags []string, isCpp bool, target *llcppg.Target, isTemp bool, operatorName func(name string) (string, bool)) (map[string]*SymbolInfo, error) {
 	index := clang.CreateIndex(0, 0)
 	if isTemp {
 		files = append(files, clangutils.TEMP_FILE)
 	}
 	processer := NewSymbolProcessor(files, prefixes)
+	processer.OperatorName = operatorName
+	processer.TargetOS = target.Goos()
 	for _, file := range files {
 		processer.collect(&clangutils.Config{
-			File:  file,
-			Temp:  isTemp,
-			IsCpp: isCpp,
-			Index: index,
-			Args:  cflags,
+			File:   file,
+			Temp:   isTemp,
+			IsCpp:  isCpp,
+			Target: target.ClangTriple(),
+			Index:  index,
+			Args:   cflags,
 		})
 	}
 	index.Dispose()
@@ -6,9 +6,9 @@ import (
 	"os"
 	"runtime"
 	"sort"
-	"strings"
 	"unsafe"
 
+	"github.com/goplus/llcppg/_xtool/llcppsymg/clangutils"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/config"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/config/cfgparse"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/dbg"
@@ -96,16 +96,14 @@ func ParseDylibSymbols(lib string) ([]*nm.Symbol, error) {
 }
 
 // finds the intersection of symbols from the dynamic library's symbol table and the symbols parsed from header files.
-// It returns a list of symbols that can be externally linked.
-func GetCommonSymbols(dylibSymbols []*nm.Symbol, headerSymbols map[string]*parse.SymbolInfo) []*llcppg.SymbolInfo {
+// It returns a list of symbols that can be externally linked. goos is the GOOS of the
+// target, whose symbols are prefixed with an underscore on darwin.
+func GetCommonSymbols(dylibSymbols []*nm.Symbol, headerSymbols map[string]*parse.SymbolInfo, goos string) []*llcppg.SymbolInfo {
 	var commonSymbols []*llcppg.SymbolInfo
 	processedSymbols := make(map[string]bool)
 
 	for _, dylibSym := range dylibSymbols {
-		symName := dylibSym.Name
-		if runtime.GOOS == "darwin" {
-			symName = strings.TrimPrefix(symName, "_")
-		}
+		symName := clangutils.TrimMangling(goos, dylibSym.Name)
 		if _, ok := processedSymbols[symName]; ok {
 			continue
 		}
@@ -204,8 +202,8 @@ func GenSymbolTableData(commonSymbols []*llcppg.SymbolInfo, existingSymbols map[
 	return result, nil
 }
 
-func GenerateAndUpdateSymbolTable(symbols []*nm.Symbol, headerInfos map[string]*parse.SymbolInfo, symbFile string) ([]byte, error) {
-	commonSymbols := GetCommonSymbols(symbols, headerInfos)
+func GenerateAndUpdateSymbolTable(symbols []*nm.Symbol, headerInfos map[string]*parse.SymbolInfo, symbFile string, goos string) ([]byte, error) {
+	commonSymbols := GetCommonSymbols(symbols, headerInfos, goos)
 	if dbg.GetDebugSymbol() {
 		fmt.Println("GenerateAndUpdateSymbolTable:", len(commonSymbols), "common symbols")
 	}
@@ -169,7 +169,8 @@ func (*ScopingExpr) exprNode() {}
 
 // X*
 type PointerType struct {
-	X Expr
+	X           Expr
+	Nullability Nullability
 }
 
 func (*PointerType) exprNode() {}
@@ -210,11 +211,35 @@ type Comment struct {
 func (*Comment) exprNode() {}
 
 type CommentGroup struct {
-	List []*Comment // len(List) > 0
+	List    []*Comment  // len(List) > 0
+	Doxygen *DocComment // the parsed comment if it is a Doxygen comment; or nil
 }
 
 func (*CommentGroup) exprNode() {}
 
+// DocComment is a Doxygen comment, like /** ... */ or ///, split into its sections.
+type DocComment struct {
+	Brief      string      // @brief
+	Blocks     []*DocBlock // detailed description
+	Params     []*DocParam // @param
+	Return     string      // @return and @retval
+	Deprecated *DocBlock   // @deprecated; or nil
+	See        []string    // @see and @sa
+}
+
+// DocBlock is a paragraph, or the lines of a @code block.
+type DocBlock struct {
+	Text string
+	Code bool
+}
+
+// DocParam is the description of a parameter.
+type DocParam struct {
+	Name      string
+	Direction string // in, out or in,out; or ""
+	Text      string
+}
+
 // ------------------------------------------------
 
 type Field struct {
@@ -224,6 +249,10 @@ type Field struct {
 	Comment  *CommentGroup   // line comments; or nil
 	Access   AccessSpecifier // field access(Record Type); Struct Field default is Public,Class Field default is Private
 	IsStatic bool            // static field
+	Offset   int64           // bit offset of a record field; valid if the record layout is known
+
+	Default       Expr     // default argument of a parameter if it is a literal; or nil
+	DefaultTokens []*Token // tokens of a default argument that is not a literal; or nil
 }
 
 func (*Field) exprNode() {}
@@ -250,12 +279,40 @@ type RecordType struct {
 	Tag     Tag
 	Fields  *FieldList
 	Methods []*FuncDecl
+	Bases   []*BaseSpec // base classes of a C++ class, in declaration order
+	// Polymorphic is true for a C++ class that declares or inherits a virtual
+	// method, whose objects start with a hidden vtable pointer.
+	Polymorphic bool
+	Abstract    bool // has a pure virtual method, which can't be instantiated
+	// Size and Align are the size and alignment in bytes computed by clang for the
+	// target; both are 0 if the layout is unknown, like for incomplete and dependent
+	// types or records with bit-fields.
+	Size  int64
+	Align int64
+	// Packed is true if the record is aligned less than one of its fields, like
+	// with __attribute__((packed)) or #pragma pack, so fields may be misaligned.
+	Packed bool
+	// IsForward is true for a forward declaration like `struct a;`, whose fields
+	// are unknown, unlike an empty record like `struct a {};`.
+	IsForward bool
 }
 
 func (*RecordType) exprNode() {}
 
 // ------------------------------------------------
 
+// public virtual Base in `class Derived : public virtual Base`
+type BaseSpec struct {
+	Type    Expr // Ident, ScopingExpr or InstantiationType of the base class
+	Access  AccessSpecifier
+	Virtual bool
+	Offset  int64 // bit offset of a non-virtual base in the class; valid if the record layout is known
+}
+
+func (*BaseSpec) exprNode() {}
+
+// ------------------------------------------------
+
 // Template<Arg1, Arg2, ...>
 type InstantiationType struct {
 	Template Expr
@@ -271,10 +328,45 @@ type Location struct {
 	File string
 }
 
+// Nullability of a pointer type, like int *_Nonnull
+type Nullability uint
+
+const (
+	NullabilityNone Nullability = iota // not annotated
+	Nonnull                            // _Nonnull
+	Nullable                           // _Nullable
+	NullUnspecified                    // _Null_unspecified
+)
+
+type AttrKind uint
+
+const (
+	DeprecatedAttr       AttrKind = iota // deprecated("message")
+	UnavailableAttr                      // unavailable("message")
+	NoReturnAttr                         // noreturn
+	FormatAttr                           // format(archetype, string-index, first-to-check)
+	NonNullAttr                          // nonnull(arg-index, ...)
+	WarnUnusedResultAttr                 // warn_unused_result
+	VisibilityAttr                       // visibility("default")
+	AlignedAttr                          // aligned(alignment)
+	PackedAttr                           // packed
+	FlagEnumAttr                         // flag_enum
+	OwnershipReturnsAttr                 // ownership_returns(module[, size-index])
+	OwnershipTakesAttr                   // ownership_takes(module, ptr-index, ...)
+	OwnershipHoldsAttr                   // ownership_holds(module, ptr-index, ...)
+)
+
+// __attribute__((Kind(Args...)))
+type Attr struct {
+	Kind AttrKind
+	Args []string // the message of deprecated, the 1-based parameter indexes of nonnull, ...
+}
+
 type DeclBase struct {
 	Doc    *CommentGroup // associated documentation; or nil
 	Loc    *Location
-	Parent Expr // namespace or class
+	Parent Expr    // namespace or class
+	Attrs  []*Attr // attributes of the declaration; or nil
 }
 
 // ------------------------------------------------
@@ -298,7 +390,9 @@ type EnumItem struct {
 func (*EnumItem) exprNode() {}
 
 type EnumType struct {
-	Items []*EnumItem
+	Items   []*EnumItem
+	IntType Expr // integer type of the enum if it isn't int, like: enum class Color : uint8_t; or nil
+	Scoped  bool // true for a scoped enum, whose items are in the scope of the enum, like: enum class Color { Red };
 }
 
 func (*EnumType) exprNode() {}
@@ -322,6 +416,7 @@ type FuncDecl struct {
 	Type        *FuncType
 	IsInline    bool
 	IsStatic    bool
+	IsExternC   bool // has C language linkage, like a function in an extern "C" block
 
 	// Class method specific fields
 	IsConst       bool // const member function
@@ -336,6 +431,20 @@ func (*FuncDecl) declNode() {}
 
 // ------------------------------------------------
 
+// Type Name = Value;
+// extern Type Name;
+type VarDecl struct {
+	DeclBase
+	Name        *Ident
+	MangledName string // C: same as Name, C++: mangled
+	Type        Expr
+	Value       *BasicLit // value of a constant, nil for a variable
+}
+
+func (*VarDecl) declNode() {}
+
+// ------------------------------------------------
+
 // struct/union/class Name { Field1, Field2, ... };
 type TypeDecl struct {
 	DeclBase
@@ -345,6 +454,19 @@ type TypeDecl struct {
 
 func (*TypeDecl) declNode() {}
 
+// ------------------------------------------------
+
+// template class Template<Arg1, Arg2, ...>;
+// typedef Template<Arg1, Arg2, ...> Name;
+type InstantiationDecl struct {
+	DeclBase
+	Name     *Ident             // spelling of the instantiation, like Foo<int>
+	Template *InstantiationType // the instantiated template and its arguments
+	Type     *RecordType        // instantiated fields and methods
+}
+
+func (*InstantiationDecl) declNode() {}
+
 // =============================================================================
 // AST File
 
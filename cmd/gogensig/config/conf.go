@@ -45,6 +45,16 @@ func ReadSigfetchFile(sigfetchFile string) ([]byte, error) {
 	return data, err
 }
 
+// OpenSigfetchFile opens the output of llcppsigfetch to be read as a stream,
+// it's stdin if the file is "-".
+func OpenSigfetchFile(sigfetchFile string) (io.ReadCloser, error) {
+	_, file := filepath.Split(sigfetchFile)
+	if file == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(sigfetchFile)
+}
+
 type SigfetchExtractConfig struct {
 	File   string
 	IsTemp bool
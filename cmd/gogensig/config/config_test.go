@@ -30,6 +30,26 @@ func TestLookupSymbolOK(t *testing.T) {
 	}
 }
 
+func TestIsOverloaded(t *testing.T) {
+	table, err := config.NewSymbolTable("./_testinput/llcppg.symb.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		cppName  string
+		expected bool
+	}{
+		{"INIReader::INIReader(const char *)", true},
+		{"INIReader::ParseError() const", false},
+		{"INIReader::GetBoolean(std::__1::basic_string<char, std::__1::char_traits<char>, std::__1::allocator<char>> const&, std::__1::basic_string<char, std::__1::char_traits<char>, std::__1::allocator<char>> const&, bool) const", false},
+	}
+	for _, tc := range testCases {
+		if got := table.IsOverloaded(&config.SymbolEntry{CppName: tc.cppName}); got != tc.expected {
+			t.Errorf("IsOverloaded(%q) = %v, want %v", tc.cppName, got, tc.expected)
+		}
+	}
+}
+
 func TestLookupSymbolError(t *testing.T) {
 	_, err := config.NewSymbolTable("./_testinput/llcppg.symb.txt")
 	if err == nil {
@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"strings"
 
 	"github.com/goplus/llcppg/cmd/gogensig/errs"
 )
@@ -19,7 +20,8 @@ type SymbolEntry struct {
 }
 
 type SymbolTable struct {
-	t map[MangleNameType]SymbolEntry
+	t         map[MangleNameType]SymbolEntry
+	overloads map[string]int // number of symbols of a function name
 }
 
 // llcppg.symb.json
@@ -37,10 +39,12 @@ func NewSymbolTable(filePath string) (*SymbolTable, error) {
 }
 func CreateSymbolTable(symbs []SymbolEntry) *SymbolTable {
 	symbolTable := &SymbolTable{
-		t: make(map[MangleNameType]SymbolEntry),
+		t:         make(map[MangleNameType]SymbolEntry),
+		overloads: make(map[string]int),
 	}
 	for _, symb := range symbs {
 		symbolTable.t[symb.MangleName] = symb
+		symbolTable.overloads[funcName(symb.CppName)]++
 	}
 	return symbolTable
 }
@@ -58,3 +62,33 @@ func (t *SymbolTable) LookupSymbol(name MangleNameType) (*SymbolEntry, error) {
 	}
 	return nil, errs.NewSymbolNotFoudError(name)
 }
+
+// IsOverloaded reports whether the function of a symbol shares its name with
+// other symbols, like the constructors of a class.
+func (t *SymbolTable) IsOverloaded(symb *SymbolEntry) bool {
+	if t == nil || t.overloads == nil {
+		return false
+	}
+	return t.overloads[funcName(symb.CppName)] > 1
+}
+
+// funcName trims the parameter list of a C++ name, like: INIReader::Get(const char *) => INIReader::Get
+func funcName(cppName string) string {
+	end := strings.LastIndex(cppName, ")")
+	if end < 0 {
+		return cppName
+	}
+	depth := 0
+	for i := end; i >= 0; i-- {
+		switch cppName[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return cppName[:i]
+			}
+		}
+	}
+	return cppName
+}
@@ -0,0 +1,180 @@
+/*
+This file is used to convert the anonymous records nested in a record
+*/
+package convert
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	"github.com/goplus/llcppg/cmd/gogensig/errs"
+)
+
+// anonRecord is the named type of an anonymous record nested in a record.
+type anonRecord struct {
+	member  bool         // if it is a C11 anonymous member, like: struct { int x, y; };
+	union   bool         // if the record is a union
+	fields  []*types.Var // fields of all members of the record
+	methods []*types.Var // members promoted from its own anonymous members, reached by accessors
+}
+
+// recordFields converts the fields of a record. An anonymous record nested in a
+// named one gets a type named after the enclosing record, like:
+//
+//	struct Outer {
+//		union { int i; float f; } u; // U Outer_U
+//		struct { int x, y; };        // Anon0 Outer_Anon0
+//	};
+func (p *TypeConv) recordFields(name string, recordType *ast.RecordType) ([]*types.Var, error) {
+	if name == "" || recordType.Fields == nil {
+		return p.fieldListToVars(recordType.Fields, false)
+	}
+	var vars []*types.Var
+	anon := 0
+	for index, field := range recordType.Fields.List {
+		anonType, ok := field.Type.(*ast.RecordType)
+		if !ok || field.IsStatic {
+			fieldVar, err := p.fieldToVar(field, false, index)
+			if err != nil {
+				return nil, err
+			}
+			vars = append(vars, fieldVar)
+			continue
+		}
+		var fieldName string
+		if len(field.Names) > 0 {
+			fieldName = getFieldName(field.Names[0].Name)
+		} else {
+			fieldName = fmt.Sprintf("Anon%d", anon)
+			anon++
+		}
+		typ, err := p.pkg.newAnonRecordType(name+"_"+fieldName, anonType, len(field.Names) == 0)
+		if err != nil {
+			return nil, err
+		}
+		vars = append(vars, types.NewVar(token.NoPos, p.types(), fieldName, typ))
+	}
+	return vars, nil
+}
+
+// newAnonRecordType declares the named type of an anonymous record nested in a record.
+func (p *Package) newAnonRecordType(name string, recordType *ast.RecordType, member bool) (types.Type, error) {
+	if obj := p.p.Types.Scope().Lookup(name); obj != nil {
+		return nil, errs.NewTypeDefinedError(name, name)
+	}
+	decl := p.emptyTypeDecl(name, nil)
+	structType, fields, err := p.cvt.recordToStruct(name, recordType)
+	if err != nil {
+		decl.InitType(p.p, types.NewStruct(p.cvt.defaultRecordField(), nil))
+		return nil, err
+	}
+	decl.InitType(p.p, structType)
+	named := decl.Type()
+	union := recordType.Tag == ast.Union
+	fields, methods := p.newRecordAccessors(named, fields, union)
+	p.anonRecords[named] = &anonRecord{
+		member:  member,
+		union:   union,
+		fields:  fields,
+		methods: methods,
+	}
+	return named, nil
+}
+
+// newAnonMemberAccessors generates the accessors of the members promoted from the
+// C11 anonymous members of a record, and returns the promoted members, like:
+//
+//	struct Outer { union { int i; float f; }; };
+//
+//	func (recv_ *Outer) I() *c.Int {
+//		return (*c.Int)(unsafe.Pointer(&recv_.Anon0))
+//	}
+//
+// A member is not promoted if its name is already taken by a field or method.
+func (p *Package) newAnonMemberAccessors(named *types.Named, fields []*types.Var, union bool) []*types.Var {
+	var promoted []*types.Var
+	for _, field := range fields {
+		fieldType, _ := field.Type().(*types.Named)
+		anon := p.anonRecords[fieldType]
+		if anon == nil || !anon.member {
+			continue
+		}
+		for _, member := range anon.fields {
+			if memberType, ok := member.Type().(*types.Named); ok && p.anonRecords[memberType] != nil && p.anonRecords[memberType].member {
+				continue
+			}
+			if p.newAnonMemberAccessor(named, fields, union, field, anon, member, false) {
+				promoted = append(promoted, member)
+			}
+		}
+		for _, member := range anon.methods {
+			if p.newAnonMemberAccessor(named, fields, union, field, anon, member, true) {
+				promoted = append(promoted, member)
+			}
+		}
+	}
+	return promoted
+}
+
+// newAnonMemberAccessor generates the accessor of a member of the anonymous member
+// field, the member is reached by the accessor of field's type if method is true.
+func (p *Package) newAnonMemberAccessor(named *types.Named, fields []*types.Var, union bool,
+	field *types.Var, anon *anonRecord, member *types.Var, method bool) bool {
+	name := member.Name()
+	recv := p.p.NewParam(token.NoPos, "recv_", types.NewPointer(named))
+	if name == "" || name == "_" || p.funcDefined(recv, name) || hasField(fields, name) {
+		if dbg.GetDebugLog() {
+			log.Printf("newAnonMemberAccessor: %s.%s is already defined\n", named.Obj().Name(), name)
+		}
+		return false
+	}
+	ret := types.NewPointer(member.Type())
+	results := types.NewTuple(p.p.NewParam(token.NoPos, "", ret))
+	sig := types.NewSignatureType(recv, nil, nil, nil, results, false)
+	fn := p.p.NewFuncDecl(token.NoPos, name, sig)
+	cb := fn.BodyStart(p.p)
+	unsafePointer := types.Typ[types.UnsafePointer]
+	// pushField pushes the field as an addressable value, a field of a union is
+	// at the start of it.
+	pushField := func() {
+		if union {
+			cb.Typ(types.NewPointer(field.Type())).Typ(unsafePointer).Val(recv).Call(1).Call(1)
+		} else {
+			cb.Val(recv).MemberVal(field.Name())
+		}
+	}
+	switch {
+	case method:
+		// return recv_.Anon0.X()
+		pushField()
+		cb.MemberVal(name).Call(0)
+	case anon.union:
+		// return (*c.Int)(unsafe.Pointer(&recv_.Anon0))
+		cb.Typ(ret).Typ(unsafePointer)
+		if union {
+			cb.Val(recv)
+		} else {
+			cb.Val(recv).MemberRef(field.Name()).UnaryOp(token.AND)
+		}
+		cb.Call(1).Call(1)
+	default:
+		// return &recv_.Anon0.X
+		pushField()
+		cb.MemberRef(name).UnaryOp(token.AND)
+	}
+	cb.Return(1).End()
+	return true
+}
+
+func hasField(fields []*types.Var, name string) bool {
+	for _, field := range fields {
+		if field.Name() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -3,6 +3,7 @@ package convert
 import (
 	"fmt"
 	"go/types"
+	"runtime"
 
 	"github.com/goplus/gogen"
 	"github.com/goplus/llcppg/ast"
@@ -21,6 +22,7 @@ func NewBuiltinTypeMapWithPkgRefS(pkgs ...gogen.PkgRef) *BuiltinTypeMap {
 		builtinTypeMap.pkgMap[pkg.Path()] = pkg
 	}
 	builtinTypeMap.initBuiltinTypeMap()
+	builtinTypeMap.SetTarget(runtime.GOOS, runtime.GOARCH)
 	return builtinTypeMap
 }
 
@@ -52,14 +54,36 @@ func (p *BuiltinTypeMap) FindBuiltinType(builtinType ast.BuiltinType) (types.Typ
 	return nil, fmt.Errorf("%s", "not found in type map")
 }
 
+// SetTarget maps the builtin types whose size depends on the system to the types
+// of the target system, wchar_t is 2 bytes on windows and 4 bytes on the others,
+// and long double is a double on some systems only.
+func (p *BuiltinTypeMap) SetTarget(goos, goarch string) {
+	wchar := types.Typ[types.Int32]
+	if goos == "windows" {
+		wchar = types.Typ[types.Uint16]
+	}
+	p.builtinTypeMap[ast.BuiltinType{Kind: ast.WChar}] = wchar
+
+	longDouble := ast.BuiltinType{Kind: ast.Float, Flags: ast.Double | ast.Long}
+	complexLongDouble := ast.BuiltinType{Kind: ast.Complex, Flags: ast.Double | ast.Long}
+	if longDoubleFormat(goos, goarch) == longDoubleDouble {
+		p.builtinTypeMap[longDouble] = p.CType("Double")
+		p.builtinTypeMap[complexLongDouble] = types.Typ[types.Complex128]
+	} else {
+		// converted to an opaque type declared in the package
+		delete(p.builtinTypeMap, longDouble)
+		delete(p.builtinTypeMap, complexLongDouble)
+	}
+}
+
 func (p *BuiltinTypeMap) initBuiltinTypeMap() {
-	// todo(zzy): int128/uint128  half(float16),long double,float 128
+	// int128, float16, float128 and long double wider than double are converted
+	// to opaque types declared in the package
 	p.builtinTypeMap = map[ast.BuiltinType]types.Type{
 		{Kind: ast.Void}:                                    p.CType("Void"),             // [0]byte
 		{Kind: ast.Bool}:                                    types.Typ[types.Bool],       // Bool
 		{Kind: ast.Char, Flags: ast.Signed}:                 p.CType("Char"),             // Char_S
 		{Kind: ast.Char, Flags: ast.Unsigned}:               p.CType("Char"),             // Char_U
-		{Kind: ast.WChar}:                                   types.Typ[types.Int16],      // WChar
 		{Kind: ast.Char16}:                                  types.Typ[types.Int16],      // Char16
 		{Kind: ast.Char32}:                                  types.Typ[types.Int32],      // Char32
 		{Kind: ast.Int, Flags: ast.Short}:                   types.Typ[types.Int16],      // Short
@@ -72,7 +96,6 @@ func (p *BuiltinTypeMap) initBuiltinTypeMap() {
 		{Kind: ast.Int, Flags: ast.LongLong | ast.Unsigned}: p.CType("UlongLong"),        // ULongLong
 		{Kind: ast.Float}:                                   p.CType("Float"),            // Float
 		{Kind: ast.Float, Flags: ast.Double}:                p.CType("Double"),           // Double
-		{Kind: ast.Float, Flags: ast.Double | ast.Long}:     p.CType("Double"),           // Long Double (same as double,need more precision)
 		{Kind: ast.Complex}:                                 types.Typ[types.Complex64],  // ComplexFloat
 		{Kind: ast.Complex, Flags: ast.Double}:              types.Typ[types.Complex128], // ComplexDouble
 	}
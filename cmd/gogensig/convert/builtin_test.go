@@ -6,6 +6,7 @@ import (
 
 	"github.com/goplus/llcppg/ast"
 	"github.com/goplus/llcppg/cmd/gogensig/convert"
+	"github.com/goplus/llcppg/cmd/gogensig/convert/sizes"
 )
 
 func TestBuiltinType(t *testing.T) {
@@ -20,7 +21,7 @@ func TestBuiltinType(t *testing.T) {
 		{"Bool", &ast.BuiltinType{Kind: ast.Bool}, "bool", false},
 		{"Char_S", &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, "int8", false},
 		{"Char_U", &ast.BuiltinType{Kind: ast.Char, Flags: ast.Unsigned}, "int8", false},
-		{"WChar", &ast.BuiltinType{Kind: ast.WChar}, "int16", false},
+		{"WChar", &ast.BuiltinType{Kind: ast.WChar}, "int32", false},
 		{"Char16", &ast.BuiltinType{Kind: ast.Char16}, "int16", false},
 		{"Char32", &ast.BuiltinType{Kind: ast.Char32}, "int32", false},
 		{"Short", &ast.BuiltinType{Kind: ast.Int, Flags: ast.Short}, "int16", false},
@@ -57,6 +58,63 @@ func TestBuiltinType(t *testing.T) {
 	}
 }
 
+func TestBuiltinTypeTarget(t *testing.T) {
+	typmap := convert.NewBuiltinTypeMap(".", "temp", nil)
+	wchar := ast.BuiltinType{Kind: ast.WChar}
+	long := ast.BuiltinType{Kind: ast.Int, Flags: ast.Long}
+	longDouble := ast.BuiltinType{Kind: ast.Float, Flags: ast.Long | ast.Double}
+	complexLongDouble := ast.BuiltinType{Kind: ast.Complex, Flags: ast.Long | ast.Double}
+	testCases := []struct {
+		goos, goarch string
+		wchar        types.Type
+		wcharSize    int64
+		longSize     int64
+		longDouble   bool // if long double is a double
+	}{
+		{"linux", "amd64", types.Typ[types.Int32], 4, 8, false},
+		{"linux", "arm64", types.Typ[types.Int32], 4, 8, false},
+		{"linux", "386", types.Typ[types.Int32], 4, 4, false},
+		{"linux", "arm", types.Typ[types.Int32], 4, 4, true},
+		{"darwin", "arm64", types.Typ[types.Int32], 4, 8, true},
+		{"darwin", "amd64", types.Typ[types.Int32], 4, 8, false},
+		{"windows", "amd64", types.Typ[types.Uint16], 2, 4, true},
+		{"windows", "386", types.Typ[types.Uint16], 2, 4, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.goos+"/"+tc.goarch, func(t *testing.T) {
+			typmap.SetTarget(tc.goos, tc.goarch)
+			targetSizes, err := sizes.For(tc.goos, tc.goarch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := typmap.FindBuiltinType(wchar)
+			if err != nil || !types.Identical(result, tc.wchar) {
+				t.Errorf("wchar_t = %v, %v, want %v", result, err, tc.wchar)
+			} else if size := targetSizes.Sizeof(result); size != tc.wcharSize {
+				t.Errorf("sizeof(wchar_t) = %d, want %d", size, tc.wcharSize)
+			}
+			result, err = typmap.FindBuiltinType(long)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := targetSizes.Sizeof(result); size != tc.longSize {
+				t.Errorf("sizeof(long) = %d, want %d", size, tc.longSize)
+			}
+			result, err = typmap.FindBuiltinType(longDouble)
+			if tc.longDouble {
+				if err != nil || !types.Identical(result, typmap.CType("Double")) {
+					t.Errorf("long double = %v, %v, want c.Double", result, err)
+				}
+				if result, err := typmap.FindBuiltinType(complexLongDouble); err != nil || !types.Identical(result, types.Typ[types.Complex128]) {
+					t.Errorf("_Complex long double = %v, %v, want complex128", result, err)
+				}
+			} else if err == nil {
+				t.Errorf("long double = %v, want an opaque type declared in the package", result)
+			}
+		})
+	}
+}
+
 func TestIsVoidType(t *testing.T) {
 	typmap := convert.NewBuiltinTypeMap(".", "temp", nil)
 	if !typmap.IsVoidType(typmap.CType("Void")) {
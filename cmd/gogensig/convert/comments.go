@@ -2,7 +2,11 @@ package convert
 
 import (
 	goast "go/ast"
+	"go/types"
+	"strconv"
 	"strings"
+
+	"github.com/goplus/llcppg/ast"
 )
 
 const (
@@ -28,3 +32,133 @@ func NewTypecDocComments() *goast.CommentGroup {
 			{Text: TYPEC},
 		}}
 }
+
+// NewAttrDocComments describes the attributes of a declaration that matter to
+// a Go caller, as a Deprecated paragraph and notes on nil arguments, format
+// strings, results not to be ignored and functions that don't return.
+// The lines are preceded by an empty comment line when sep is true.
+func NewAttrDocComments(attrs []*ast.Attr, sep bool) *goast.CommentGroup {
+	var list []*goast.Comment
+	for _, attr := range attrs {
+		var txt string
+		switch attr.Kind {
+		case ast.DeprecatedAttr:
+			txt = "// Deprecated: "
+			if len(attr.Args) > 0 {
+				txt += attr.Args[0]
+			} else {
+				txt += "this declaration is marked deprecated in C."
+			}
+		case ast.NonNullAttr:
+			switch len(attr.Args) {
+			case 0:
+				txt = "// The pointer arguments must not be nil."
+			case 1:
+				txt = "// The pointer argument at position " + attr.Args[0] + " must not be nil."
+			default:
+				txt = "// The pointer arguments at positions " + strings.Join(attr.Args, ", ") + " must not be nil."
+			}
+		case ast.FormatAttr:
+			// format(printf, 2, 3), the arguments from 3 are checked, or none for 0
+			if len(attr.Args) < 3 {
+				continue
+			}
+			txt = "// The argument at position " + attr.Args[1] + " is a " + attr.Args[0] + " format string"
+			if attr.Args[2] == "0" {
+				txt += "."
+			} else {
+				txt += " for the arguments from position " + attr.Args[2] + "."
+			}
+		case ast.WarnUnusedResultAttr:
+			txt = "// The result should not be ignored."
+		case ast.NoReturnAttr:
+			txt = "// It never returns."
+		default:
+			continue
+		}
+		if sep || len(list) > 0 {
+			list = append(list, &goast.Comment{Text: "//"})
+		}
+		list = append(list, &goast.Comment{Text: txt})
+	}
+	return &goast.CommentGroup{List: list}
+}
+
+// NewNilDocComments notes the nullability of the pointer parameters and result
+// of a function, like:
+//
+//	// The pointer argument name must not be nil.
+//	// The returned pointer may be nil.
+//
+// The lines are preceded by an empty comment line when sep is true.
+func NewNilDocComments(sig *types.Signature, funcDecl *ast.FuncDecl, sep bool) *goast.CommentGroup {
+	var nonnull, nullable []string
+	if params := funcDecl.Type.Params; params != nil && len(params.List) >= sig.Params().Len() {
+		// the first parameter is the receiver of a method converted from a C function
+		offset := len(params.List) - sig.Params().Len()
+		for i, field := range params.List {
+			var name string
+			switch {
+			case i < offset && sig.Recv() != nil:
+				name = sig.Recv().Name()
+			case i >= offset:
+				name = sig.Params().At(i - offset).Name()
+			}
+			if name == "" || len(field.Names) == 0 {
+				name = "#" + strconv.Itoa(i+1)
+			}
+			switch nullability(field.Type) {
+			case ast.Nonnull:
+				nonnull = append(nonnull, name)
+			case ast.Nullable:
+				nullable = append(nullable, name)
+			}
+		}
+	}
+	var texts []string
+	if len(nonnull) > 0 {
+		texts = append(texts, pointerArgs(nonnull)+" must not be nil.")
+	}
+	if len(nullable) > 0 {
+		texts = append(texts, pointerArgs(nullable)+" may be nil.")
+	}
+	switch nullability(funcDecl.Type.Ret) {
+	case ast.Nonnull:
+		texts = append(texts, "// The returned pointer is never nil.")
+	case ast.Nullable:
+		texts = append(texts, "// The returned pointer may be nil.")
+	}
+	var list []*goast.Comment
+	if len(texts) > 0 && sep {
+		list = append(list, &goast.Comment{Text: "//"})
+	}
+	for _, txt := range texts {
+		list = append(list, &goast.Comment{Text: txt})
+	}
+	return &goast.CommentGroup{List: list}
+}
+
+func pointerArgs(names []string) string {
+	if len(names) == 1 {
+		return "// The pointer argument " + names[0]
+	}
+	return "// The pointer arguments " + strings.Join(names, ", ")
+}
+
+// nullability returns the nullability of a pointer type, or NullabilityNone
+// for other types.
+func nullability(expr ast.Expr) ast.Nullability {
+	if ptr, ok := expr.(*ast.PointerType); ok {
+		return ptr.Nullability
+	}
+	return ast.NullabilityNone
+}
+
+func hasAttr(attrs []*ast.Attr, kind ast.AttrKind) bool {
+	for _, attr := range attrs {
+		if attr.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -2,9 +2,11 @@ package convert
 
 import (
 	"errors"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
 
 	"github.com/goplus/llcppg/ast"
@@ -19,6 +21,7 @@ type Config struct {
 	CfgFile   string // llcppg.cfg
 	PubFile   string // llcppg.pub
 	OutputDir string
+	Target    *llcppg.Target // overrides the target of llcppg.cfg if not nil
 
 	Pkg *llcppg.Pkg
 }
@@ -48,6 +51,9 @@ func NewConverter(config *Config) (*Converter, error) {
 		}
 		conf = llcppg.NewDefaultConfig()
 	}
+	if config.Target != nil {
+		conf.Target = config.Target
+	}
 
 	pubs, err := cfg.GetPubFromPath(config.PubFile)
 	if err != nil {
@@ -77,6 +83,65 @@ func (p *Converter) Convert() {
 }
 
 func (p *Converter) Process() {
+	for _, macro := range p.Pkg.File.Macros {
+		p.processNode(macro)
+	}
+	p.reserveNames(llcppg.DeclNames(p.Pkg.File.Decls))
+	for _, decl := range p.Pkg.File.Decls {
+		p.processNode(decl)
+	}
+}
+
+// NodeReader reads the top-level nodes of a Pkg one by one, like an
+// unmarshal.PkgStream, it returns io.EOF at the end.
+type NodeReader interface {
+	Next() (ast.Node, error)
+}
+
+// ConvertStream converts the nodes read from r, instead of converting a whole
+// Pkg, the FileMap of Pkg must be set before. names are the qualified names
+// declared by the decls of each file, like the Names of an unmarshal.PkgStream.
+func (p *Converter) ConvertStream(r NodeReader, names map[string][]string) error {
+	if err := p.ProcessStream(r, names); err != nil {
+		return err
+	}
+	p.Write()
+	p.Fmt()
+	return nil
+}
+
+// ProcessStream converts each node read from r as soon as it's read. The names
+// declared by the decls of each file are reserved before, so the Go names are
+// the same as the ones of a whole Pkg converted by Process.
+func (p *Converter) ProcessStream(r NodeReader, names map[string][]string) error {
+	p.reserveNames(names)
+	for {
+		node, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		p.processNode(node)
+	}
+}
+
+// reserveNames reserves the names declared by the decls of each file before
+// they are converted, then their Go names don't depend on their order.
+func (p *Converter) reserveNames(names map[string][]string) {
+	files := make([]string, 0, len(names))
+	for file := range names {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		p.setCurFile(file)
+		p.GenPkg.ReserveNames(names[file])
+	}
+}
+
+func (p *Converter) processNode(node ast.Node) {
 	processDecl := func(file string, name *ast.Ident, declType string, process func() error) {
 		var declName string
 		if name != nil {
@@ -90,31 +155,35 @@ func (p *Converter) Process() {
 		}
 	}
 
-	for _, macro := range p.Pkg.File.Macros {
-		processDecl(macro.Loc.File, &ast.Ident{Name: macro.Name}, "Macro", func() error {
-			return p.GenPkg.NewMacro(macro)
+	switch node := node.(type) {
+	case *ast.Macro:
+		processDecl(node.Loc.File, &ast.Ident{Name: node.Name}, "Macro", func() error {
+			return p.GenPkg.NewMacro(node)
+		})
+	case *ast.TypeDecl:
+		processDecl(node.DeclBase.Loc.File, node.Name, "TypeDecl", func() error {
+			return p.GenPkg.NewTypeDecl(node)
+		})
+	case *ast.EnumTypeDecl:
+		processDecl(node.DeclBase.Loc.File, node.Name, "EnumTypeDecl", func() error {
+			return p.GenPkg.NewEnumTypeDecl(node)
+		})
+	case *ast.TypedefDecl:
+		processDecl(node.DeclBase.Loc.File, node.Name, "TypedefDecl", func() error {
+			return p.GenPkg.NewTypedefDecl(node)
+		})
+	case *ast.InstantiationDecl:
+		processDecl(node.DeclBase.Loc.File, node.Name, "InstantiationDecl", func() error {
+			return p.GenPkg.NewInstantiationDecl(node)
+		})
+	case *ast.FuncDecl:
+		processDecl(node.DeclBase.Loc.File, node.Name, "FuncDecl", func() error {
+			return p.GenPkg.NewFuncDecl(node)
+		})
+	case *ast.VarDecl:
+		processDecl(node.DeclBase.Loc.File, node.Name, "VarDecl", func() error {
+			return p.GenPkg.NewVarDecl(node)
 		})
-	}
-
-	for _, decl := range p.Pkg.File.Decls {
-		switch decl := decl.(type) {
-		case *ast.TypeDecl:
-			processDecl(decl.DeclBase.Loc.File, decl.Name, "TypeDecl", func() error {
-				return p.GenPkg.NewTypeDecl(decl)
-			})
-		case *ast.EnumTypeDecl:
-			processDecl(decl.DeclBase.Loc.File, decl.Name, "EnumTypeDecl", func() error {
-				return p.GenPkg.NewEnumTypeDecl(decl)
-			})
-		case *ast.TypedefDecl:
-			processDecl(decl.DeclBase.Loc.File, decl.Name, "TypedefDecl", func() error {
-				return p.GenPkg.NewTypedefDecl(decl)
-			})
-		case *ast.FuncDecl:
-			processDecl(decl.DeclBase.Loc.File, decl.Name, "FuncDecl", func() error {
-				return p.GenPkg.NewFuncDecl(decl)
-			})
-		}
 	}
 }
 
@@ -1,7 +1,9 @@
 package convert_test
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"path/filepath"
@@ -10,10 +12,14 @@ import (
 
 	"github.com/goplus/llcppg/_xtool/llcppsymg/args"
 	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/cmp"
 	"github.com/goplus/llcppg/cmd/gogensig/config"
 	"github.com/goplus/llcppg/cmd/gogensig/convert"
 	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	"github.com/goplus/llcppg/cmd/gogensig/marshal"
 	"github.com/goplus/llcppg/cmd/gogensig/unmarshal"
+	"github.com/goplus/llcppg/llcppg"
+	"github.com/goplus/llcppg/token"
 	"github.com/goplus/llgo/xtool/env"
 )
 
@@ -256,6 +262,292 @@ func testFrom(t *testing.T, name, dir string, gen bool, validateFunc func(t *tes
 	}
 }
 
+// the types used before they are declared in a stream are resolved as in a whole Pkg
+func TestConvertStream(t *testing.T) {
+	loc := &ast.Location{File: "/path/to/temp.go"}
+	pkg := &llcppg.Pkg{
+		File: &ast.File{
+			Decls: []ast.Decl{
+				&ast.TypeDecl{
+					DeclBase: ast.DeclBase{Loc: loc},
+					Name:     &ast.Ident{Name: "Foo"},
+					Type: &ast.RecordType{
+						Tag: ast.Struct,
+						Fields: &ast.FieldList{List: []*ast.Field{{
+							Names: []*ast.Ident{{Name: "bar"}},
+							Type:  &ast.PointerType{X: &ast.TagExpr{Tag: ast.Struct, Name: &ast.Ident{Name: "Bar"}}},
+						}}},
+					},
+				},
+				&ast.TypeDecl{
+					DeclBase: ast.DeclBase{Loc: loc},
+					Name:     &ast.Ident{Name: "Bar"},
+					Type: &ast.RecordType{
+						Tag: ast.Struct,
+						Fields: &ast.FieldList{List: []*ast.Field{{
+							Names: []*ast.Ident{{Name: "x"}},
+							Type:  &ast.BuiltinType{Kind: ast.Int},
+						}}},
+					},
+				},
+			},
+			Macros: []*ast.Macro{{
+				Loc:    loc,
+				Name:   "VERSION",
+				Tokens: []*ast.Token{{Token: token.IDENT, Lit: "VERSION"}, {Token: token.LITERAL, Lit: "2"}},
+			}},
+		},
+		FileMap: map[string]*llcppg.FileInfo{loc.File: {FileType: llcppg.Inter}},
+	}
+	data, err := marshal.PkgNDJSON(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := unmarshal.NewPkgStream(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cvt, err := convert.NewConverter(&convert.Config{
+		PkgName: "test",
+		Pkg:     &llcppg.Pkg{File: &ast.File{}, FileMap: stream.FileMap},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cvt.ProcessStream(stream, stream.Names); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, cvt.GenPkg, `
+package test
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+const VERSION = 2
+
+type Foo struct {
+	Bar *Bar
+}
+
+type Bar struct {
+	X c.Int
+}`)
+}
+
+// nodeList reads the nodes like an unmarshal.PkgStream of llcppsigfetch -ndjson=true,
+// which writes them in the order they are parsed.
+type nodeList []ast.Node
+
+func (l *nodeList) Next() (ast.Node, error) {
+	if len(*l) == 0 {
+		return nil, io.EOF
+	}
+	node := (*l)[0]
+	*l = (*l)[1:]
+	return node, nil
+}
+
+// a stream, whose macros and decls are mixed, gives the same Go names as a whole Pkg
+func TestConvertStreamSameAsPkg(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), args.LLCPPG_CFG)
+	if err := os.WriteFile(cfgFile, []byte(`{"name": "test", "namespaceNaming": "drop"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loc := &ast.Location{File: "/path/to/temp.go"}
+	widget := func(scope string) *ast.TypeDecl {
+		decl := &ast.TypeDecl{
+			DeclBase: ast.DeclBase{Loc: loc},
+			Name:     &ast.Ident{Name: "Widget"},
+			Type: &ast.RecordType{Tag: ast.Struct, Fields: &ast.FieldList{List: []*ast.Field{
+				{Names: []*ast.Ident{{Name: "n"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+			}}},
+		}
+		if scope != "" {
+			decl.Parent = &ast.Ident{Name: scope}
+		}
+		return decl
+	}
+	// namespace ns { struct Widget { int n; }; }
+	// #define VERSION 2
+	// struct Widget { int n; };
+	nodes := []ast.Node{
+		widget("ns"),
+		&ast.Macro{
+			Loc:    loc,
+			Name:   "VERSION",
+			Tokens: []*ast.Token{{Token: token.IDENT, Lit: "VERSION"}, {Token: token.LITERAL, Lit: "2"}},
+		},
+		widget(""),
+	}
+	file := &ast.File{}
+	for _, node := range nodes {
+		if macro, ok := node.(*ast.Macro); ok {
+			file.Macros = append(file.Macros, macro)
+		} else {
+			file.Decls = append(file.Decls, node.(ast.Decl))
+		}
+	}
+	fileMap := map[string]*llcppg.FileInfo{loc.File: {FileType: llcppg.Inter}}
+	output := func(stream bool) string {
+		pkg := &llcppg.Pkg{File: &ast.File{}, FileMap: fileMap}
+		if !stream {
+			pkg.File = file
+		}
+		cvt, err := convert.NewConverter(&convert.Config{PkgName: "test", CfgFile: cfgFile, Pkg: pkg})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stream {
+			list := nodeList(nodes)
+			if err := cvt.ProcessStream(&list, llcppg.DeclNames(file.Decls)); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			cvt.Process()
+		}
+		buf, err := cvt.GenPkg.WriteToBuffer("temp.go")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	for _, tc := range []struct {
+		stream bool
+		want   string
+	}{
+		{false, `
+package test
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+const VERSION = 2
+
+type NsWidget struct {
+	N c.Int
+}
+
+type Widget struct {
+	N c.Int
+}`},
+		// the nodes of a stream are converted in the order they are read
+		{true, `
+package test
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type NsWidget struct {
+	N c.Int
+}
+
+const VERSION = 2
+
+type Widget struct {
+	N c.Int
+}`},
+	} {
+		if eq, diff := cmp.EqualStringIgnoreSpace(output(tc.stream), tc.want); !eq {
+			t.Errorf("stream %v: %s", tc.stream, diff)
+		}
+	}
+}
+
+// hookReader calls hook with the number of the nodes read before reading each node.
+type hookReader struct {
+	r    convert.NodeReader
+	n    int
+	hook func(n int)
+}
+
+func (h *hookReader) Next() (ast.Node, error) {
+	h.hook(h.n)
+	h.n++
+	return h.r.Next()
+}
+
+// each decl of a stream is converted as soon as it's read, before the writer of
+// the stream is closed
+func TestConvertStreamPipe(t *testing.T) {
+	loc := &ast.Location{File: "/path/to/temp.go"}
+	record := func(name string) *ast.TypeDecl {
+		return &ast.TypeDecl{
+			DeclBase: ast.DeclBase{Loc: loc},
+			Name:     &ast.Ident{Name: name},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{List: []*ast.Field{{
+					Names: []*ast.Ident{{Name: "x"}},
+					Type:  &ast.BuiltinType{Kind: ast.Int},
+				}}},
+			},
+		}
+	}
+	fileMap := map[string]*llcppg.FileInfo{loc.File: {FileType: llcppg.Inter}}
+	data, err := marshal.PkgNDJSON(&llcppg.Pkg{
+		File:    &ast.File{Decls: []ast.Decl{record("Foo"), record("Bar")}},
+		FileMap: fileMap,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the header, Foo and Bar
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	pr, pw := io.Pipe()
+	converted := make(chan struct{})
+	go func() {
+		pw.Write(lines[0])
+		pw.Write(lines[1])
+		<-converted
+		pw.Write(lines[2])
+		pw.Close()
+	}()
+	stream, err := unmarshal.NewPkgStream(pr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cvt, err := convert.NewConverter(&convert.Config{
+		PkgName: "test",
+		Pkg:     &llcppg.Pkg{File: &ast.File{}, FileMap: stream.FileMap},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := &hookReader{r: stream, hook: func(n int) {
+		if n != 1 {
+			return
+		}
+		if cvt.GenPkg.GetGenPackage().Types.Scope().Lookup("Foo") == nil {
+			t.Error("Foo is not converted before Bar is written")
+		}
+		close(converted)
+	}}
+	if err := cvt.ProcessStream(r, stream.Names); err != nil {
+		t.Fatal(err)
+	}
+	comparePackageOutput(t, cvt.GenPkg, `
+package test
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	X c.Int
+}
+
+type Bar struct {
+	X c.Int
+}`)
+}
+
 // ===========================error
 func TestNewConvert(t *testing.T) {
 	_, err := convert.NewConverter(&convert.Config{
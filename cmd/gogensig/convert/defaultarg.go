@@ -0,0 +1,239 @@
+/*
+This file is used to generate the wrappers of functions with C++ default arguments
+*/
+package convert
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	ctoken "github.com/goplus/llcppg/token"
+)
+
+// defaultArg is a default argument that can be passed to a Go parameter.
+type defaultArg struct {
+	val  interface{} // value pushed to the code builder
+	neg  bool        // if the value is negated
+	text string      // the default argument as written in C++
+}
+
+// newDefaultArgWrapper generates a wrapper of a function with trailing default arguments,
+// which calls the function with the defaults filled, like:
+//
+//	void open(const char* path, int mode = 0644);
+//
+//	func OpenDefault(path *c.Char) {
+//		Open(path, 0644)
+//	}
+//
+// Overloaded functions are not wrapped, since the wrappers may be ambiguous.
+func (p *Package) newDefaultArgWrapper(fnSpec *GoFuncSpec, sig *types.Signature, funcDecl *ast.FuncDecl) error {
+	params := sig.Params()
+	if sig.Variadic() || funcDecl.Type.Params == nil {
+		return nil
+	}
+	fields := funcDecl.Type.Params.List
+	offset := len(fields) - params.Len()
+
+	var defaults []*defaultArg
+	first := params.Len()
+	for i := params.Len() - 1; i >= 0; i-- {
+		arg := toDefaultArg(fields[i+offset], params.At(i).Type(), p.cvt.sizes)
+		if arg == nil {
+			break
+		}
+		defaults = append([]*defaultArg{arg}, defaults...)
+		first = i
+	}
+	if len(defaults) == 0 || p.isOverloaded(funcDecl.MangledName) {
+		return nil
+	}
+
+	name := fnSpec.FnName + "Default"
+	if p.funcDefined(sig.Recv(), name) {
+		if dbg.GetDebugLog() {
+			log.Printf("newDefaultArgWrapper: %s is already defined\n", name)
+		}
+		return nil
+	}
+
+	var recv *types.Var
+	if sig.Recv() != nil {
+		recv = p.p.NewParam(token.NoPos, "recv_", sig.Recv().Type())
+	}
+	var vars []*types.Var
+	for i := 0; i < first; i++ {
+		param := params.At(i)
+		paramName := param.Name()
+		if paramName == "" {
+			paramName = fmt.Sprintf("__llgo_arg_%d", i)
+		}
+		vars = append(vars, p.p.NewParam(token.NoPos, paramName, param.Type()))
+	}
+	wrapperSig := types.NewSignatureType(recv, nil, nil, types.NewTuple(vars...), sig.Results(), false)
+	decl := p.p.NewFuncDecl(token.NoPos, name, wrapperSig)
+
+	cb := decl.BodyStart(p.p)
+	if recv != nil {
+		cb.Val(recv).MemberVal(fnSpec.FnName)
+	} else {
+		cb.Val(p.p.Types.Scope().Lookup(fnSpec.FnName))
+	}
+	for _, v := range vars {
+		cb.Val(v)
+	}
+	var texts []string
+	for i, arg := range defaults {
+		cb.Val(arg.val)
+		if arg.neg {
+			cb.UnaryOp(token.SUB)
+		}
+		texts = append(texts, params.At(first+i).Name()+" = "+arg.text)
+	}
+	if err := cb.CallWithEx(len(vars)+len(defaults), 0); err != nil {
+		return err
+	}
+	if sig.Results().Len() > 0 {
+		cb.Return(1)
+	} else {
+		cb.EndStmt()
+	}
+	cb.End()
+
+	decl.SetComments(p.p, &goast.CommentGroup{List: []*goast.Comment{
+		{Text: "// " + name + " calls " + fnSpec.FnName + " with the default arguments: " + strings.Join(texts, ", ") + "."},
+	}})
+	return nil
+}
+
+func (p *Package) isOverloaded(mangledName string) bool {
+	symb, err := p.conf.SymbolTable.LookupSymbol(mangledName)
+	if err != nil {
+		return false
+	}
+	return p.conf.SymbolTable.IsOverloaded(symb)
+}
+
+func (p *Package) funcDefined(recv *types.Var, name string) bool {
+	if recv == nil {
+		return p.p.Types.Scope().Lookup(name) != nil
+	}
+	named := getNamedType(recv.Type())
+	if named == nil {
+		return false
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		if named.Method(i).Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// toDefaultArg returns the default argument of a parameter if it can be
+// passed to the Go parameter type; otherwise it returns nil.
+func toDefaultArg(field *ast.Field, typ types.Type, sizes types.Sizes) *defaultArg {
+	if field.DefaultTokens != nil {
+		if len(field.DefaultTokens) != 1 || field.DefaultTokens[0].Token == ctoken.LITERAL {
+			return nil
+		}
+		text := field.DefaultTokens[0].Lit
+		switch text {
+		case "nullptr", "NULL":
+			if isNilable(typ) && nullability(field.Type) != ast.Nonnull {
+				return &defaultArg{val: nil, text: text}
+			}
+		case "true", "false":
+			if isBasic(typ, types.IsBoolean) {
+				return &defaultArg{val: text == "true", text: text}
+			}
+		}
+		return nil
+	}
+	lit, ok := field.Default.(*ast.BasicLit)
+	if !ok {
+		return nil
+	}
+	value := strings.ReplaceAll(lit.Value, "'", "")
+	neg := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	arg := &defaultArg{neg: neg, text: lit.Value}
+	switch lit.Kind {
+	case ast.IntLit:
+		value = strings.TrimRight(value, "uUlL")
+		if value == "0" && !neg && isNilable(typ) && nullability(field.Type) != ast.Nonnull {
+			arg.val = nil
+			return arg
+		}
+		cv := constant.MakeFromLiteral(value, token.INT, 0)
+		if neg {
+			cv = constant.UnaryOp(token.SUB, cv, 0)
+		}
+		if !representable(cv, typ, sizes) {
+			return nil
+		}
+		arg.val = &goast.BasicLit{Kind: token.INT, Value: value}
+	case ast.FloatLit:
+		if !isBasic(typ, types.IsFloat) {
+			return nil
+		}
+		value = strings.TrimRight(value, "fFlL")
+		if constant.MakeFromLiteral(value, token.FLOAT, 0).Kind() == constant.Unknown {
+			return nil
+		}
+		arg.val = &goast.BasicLit{Kind: token.FLOAT, Value: value}
+	case ast.CharLit:
+		r, err := strconv.Unquote(lit.Value)
+		if err != nil || !representable(constant.MakeInt64(int64([]rune(r)[0])), typ, sizes) {
+			return nil
+		}
+		arg.val = &goast.BasicLit{Kind: token.CHAR, Value: lit.Value}
+	default:
+		return nil
+	}
+	return arg
+}
+
+// representable reports whether an integer constant fits the integer or float type.
+func representable(cv constant.Value, typ types.Type, sizes types.Sizes) bool {
+	if cv.Kind() != constant.Int {
+		return false
+	}
+	if isBasic(typ, types.IsFloat) {
+		return true
+	}
+	if !isBasic(typ, types.IsInteger) {
+		return false
+	}
+	bits := uint(sizes.Sizeof(typ) * 8)
+	if isBasic(typ, types.IsUnsigned) {
+		max := constant.Shift(constant.MakeInt64(1), token.SHL, bits)
+		return constant.Sign(cv) >= 0 && constant.Compare(cv, token.LSS, max)
+	}
+	max := constant.Shift(constant.MakeInt64(1), token.SHL, bits-1)
+	return constant.Compare(cv, token.LSS, max) &&
+		constant.Compare(cv, token.GEQ, constant.UnaryOp(token.SUB, max, 0))
+}
+
+func isBasic(typ types.Type, info types.BasicInfo) bool {
+	t, ok := typ.Underlying().(*types.Basic)
+	return ok && t.Info()&info != 0
+}
+
+func isNilable(typ types.Type) bool {
+	switch t := typ.Underlying().(type) {
+	case *types.Pointer, *types.Signature:
+		return true
+	case *types.Basic:
+		return t.Kind() == types.UnsafePointer
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+/*
+This file is used to render Doxygen comments as godoc comments
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llcppg/ast"
+)
+
+// docWidth is the width of the text of a doc line, after the leading "// ".
+const docWidth = 77
+
+var docIdentRe = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*(::[A-Za-z_][A-Za-z0-9_]*)*(\(\))?`)
+
+// docComments converts the documentation of a declaration to Go comments.
+// A Doxygen comment is rendered as a godoc comment unless keepRawComments is set,
+// and the C names in it are replaced by names, like the ones of the parameters.
+func (p *Package) docComments(doc *ast.CommentGroup, goName string, names map[string]string) *ConvertCommentGroup {
+	if doc == nil || doc.Doxygen == nil || p.CppgConf.KeepRawComments {
+		return CommentGroup(doc)
+	}
+	r := &docRenderer{pkg: p, goName: goName, names: names}
+	r.render(doc.Doxygen)
+	return &ConvertCommentGroup{CommentGroup: &goast.CommentGroup{List: r.list}}
+}
+
+// docAttrs leaves out the attributes that are described by the rendered doc.
+func (p *Package) docAttrs(doc *ast.CommentGroup, attrs []*ast.Attr) []*ast.Attr {
+	if doc == nil || doc.Doxygen == nil || doc.Doxygen.Deprecated == nil || p.CppgConf.KeepRawComments {
+		return attrs
+	}
+	var ret []*ast.Attr
+	for _, attr := range attrs {
+		if attr.Kind != ast.DeprecatedAttr {
+			ret = append(ret, attr)
+		}
+	}
+	return ret
+}
+
+// funcDocNames maps the C names of a function and its parameters to Go names.
+func funcDocNames(goName string, sig *types.Signature, funcDecl *ast.FuncDecl) map[string]string {
+	names := map[string]string{funcDecl.Name.Name: goName}
+	if funcDecl.Type.Params == nil {
+		return names
+	}
+	fields := funcDecl.Type.Params.List
+	// the first parameter is the receiver of a method converted from a C function
+	offset := len(fields) - sig.Params().Len()
+	if offset < 0 || offset > 1 || (offset == 1 && sig.Recv() == nil) {
+		return names
+	}
+	for i, field := range fields {
+		if len(field.Names) == 0 {
+			continue
+		}
+		if i < offset {
+			names[field.Names[0].Name] = sig.Recv().Name()
+		} else {
+			names[field.Names[0].Name] = sig.Params().At(i - offset).Name()
+		}
+	}
+	return names
+}
+
+// docLink returns the doc link of a type declared in the package, like [Foo],
+// or "" if name is not a type.
+func (p *Package) docLink(name string) string {
+	obj := p.p.Types.Scope().Lookup(name)
+	if obj == nil {
+		return ""
+	}
+	if subst, ok := obj.Type().(*gogen.TySubst); ok {
+		obj = subst.Real
+	}
+	if _, ok := obj.(*types.TypeName); !ok {
+		return ""
+	}
+	return "[" + obj.Name() + "]"
+}
+
+// funcDocLink returns the doc link of a function of the package, like [Foo] or [Foo.Bar],
+// or "" if name is not a function.
+func (p *Package) funcDocLink(name string) string {
+	fnSpec, err := p.LookupSymbol(name)
+	if err != nil {
+		return ""
+	}
+	if !fnSpec.IsMethod {
+		return "[" + fnSpec.GoSymbName + "]"
+	}
+	return "[" + strings.NewReplacer("(", "", ")", "", "*", "").Replace(fnSpec.GoSymbName) + "]"
+}
+
+type docRenderer struct {
+	pkg    *Package
+	goName string            // Go name of the declaration
+	names  map[string]string // Go names of the C names
+	list   []*goast.Comment
+}
+
+// render renders the sections of a Doxygen comment as godoc paragraphs, like:
+//
+//	// Foo returns the size of a [Widget].
+//	//
+//	// Parameters:
+//	//   - w: the widget.
+//	//
+//	// It returns 0 on error.
+func (r *docRenderer) render(doc *ast.DocComment) {
+	lead := true
+	if doc.Brief != "" {
+		r.para(wrapDoc(r.sentence(doc.Brief, true), "", ""))
+		lead = false
+	}
+	for _, block := range doc.Blocks {
+		if block.Code {
+			r.code(block.Text)
+			continue
+		}
+		r.para(wrapDoc(r.sentence(block.Text, lead), "", ""))
+		lead = false
+	}
+	if len(doc.Params) > 0 {
+		lines := []string{"Parameters:"}
+		for _, param := range doc.Params {
+			name, ok := r.names[param.Name]
+			if !ok {
+				name = param.Name
+			}
+			item := name
+			if param.Direction != "" {
+				item += " (" + param.Direction + ")"
+			}
+			if param.Text != "" {
+				item += ": " + r.sentence(param.Text, false)
+			}
+			lines = append(lines, wrapDoc(item, "  - ", "    ")...)
+		}
+		r.para(lines)
+	}
+	if doc.Return != "" {
+		ret := doc.Return
+		if first, rest, _ := strings.Cut(ret, " "); strings.EqualFold(first, "returns") || strings.EqualFold(first, "return") {
+			ret = rest
+		}
+		r.para(wrapDoc(r.sentence("It returns "+lowerFirst(ret), false), "", ""))
+	}
+	if len(doc.See) > 0 {
+		var see []string
+		for _, s := range doc.See {
+			see = append(see, r.text(s))
+		}
+		r.para(wrapDoc("See also "+strings.Join(see, ", ")+".", "", ""))
+	}
+	if doc.Deprecated != nil {
+		text := "this declaration is marked deprecated in C."
+		if doc.Deprecated.Text != "" {
+			text = r.sentence(doc.Deprecated.Text, false)
+		}
+		r.para(wrapDoc("Deprecated: "+text, "", ""))
+	}
+}
+
+// para adds a paragraph, which is separated from the previous one by an empty line.
+func (r *docRenderer) para(lines []string) {
+	r.sep()
+	for _, line := range lines {
+		r.list = append(r.list, &goast.Comment{Text: strings.TrimRight("// "+line, " ")})
+	}
+}
+
+// code adds a code block, whose lines are indented by a tab.
+func (r *docRenderer) code(text string) {
+	r.sep()
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			r.list = append(r.list, &goast.Comment{Text: "//"})
+		} else {
+			r.list = append(r.list, &goast.Comment{Text: "//\t" + line})
+		}
+	}
+}
+
+func (r *docRenderer) sep() {
+	if len(r.list) > 0 {
+		r.list = append(r.list, &goast.Comment{Text: "//"})
+	}
+}
+
+// text replaces the C names in a text by their Go names or doc links.
+func (r *docRenderer) text(s string) string {
+	return docIdentRe.ReplaceAllStringFunc(s, func(word string) string {
+		if name, ok := strings.CutSuffix(word, "()"); ok {
+			if goName, ok := r.names[name]; ok {
+				return goName
+			}
+			if link := r.pkg.funcDocLink(name); link != "" {
+				return link
+			}
+			return word
+		}
+		// a Go keyword is left as it is, it's more likely a word of the text
+		if goName, ok := r.names[word]; ok && !token.IsKeyword(word) {
+			return goName
+		}
+		if link := r.pkg.docLink(word); link != "" {
+			if link == "["+r.goName+"]" {
+				return r.goName
+			}
+			return link
+		}
+		return word
+	})
+}
+
+// sentence converts a text to a Go-style sentence, the leading sentence of a doc
+// begins with the declared name if it begins with a verb, like: Foo returns ...
+func (r *docRenderer) sentence(s string, lead bool) string {
+	s = strings.TrimSpace(r.text(s))
+	if s == "" {
+		return s
+	}
+	first, rest, _ := strings.Cut(s, " ")
+	if lead && r.goName != "" && first != r.goName && isThirdPersonVerb(first) {
+		s = r.goName + " " + lowerFirst(first)
+		if rest != "" {
+			s += " " + rest
+		}
+	} else if isLowerWord(first) {
+		s = strings.ToUpper(s[:1]) + s[1:]
+	}
+	if !strings.ContainsAny(s[len(s)-1:], ".!?:") {
+		s += "."
+	}
+	return s
+}
+
+// isThirdPersonVerb reports whether a capitalized word looks like a verb
+// which a sentence of a doc comment begins with, like: Returns, Creates.
+func isThirdPersonVerb(word string) bool {
+	if len(word) <= 3 || !unicode.IsUpper(rune(word[0])) || !isLowerWord(word[1:]) {
+		return false
+	}
+	switch word {
+	case "This", "Thus", "Always", "Various", "Unless", "Perhaps", "Sometimes":
+		return false
+	}
+	return strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is")
+}
+
+func isLowerWord(word string) bool {
+	if word == "" {
+		return false
+	}
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func lowerFirst(s string) string {
+	first, _, _ := strings.Cut(s, " ")
+	if len(first) > 1 && unicode.IsUpper(rune(first[0])) && isLowerWord(first[1:]) {
+		return strings.ToLower(s[:1]) + s[1:]
+	}
+	return s
+}
+
+// wrapDoc wraps a text to lines of docWidth, the first line is prefixed with
+// first and the others with rest.
+func wrapDoc(text, first, rest string) []string {
+	var lines []string
+	line := first
+	for _, word := range strings.Fields(text) {
+		if len(line) > len(rest) && line != first && len(line)+1+len(word) > docWidth {
+			lines = append(lines, line)
+			line = rest
+		}
+		if line != first && line != rest {
+			line += " "
+		}
+		line += word
+	}
+	return append(lines, line)
+}
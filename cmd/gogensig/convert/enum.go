@@ -0,0 +1,134 @@
+/*
+This file is used to name the items of enums by their common prefix and to
+generate the String methods of enums
+*/
+package convert
+
+import (
+	"go/token"
+	"go/types"
+	"strings"
+	"unicode"
+
+	"github.com/goplus/llcppg/_xtool/llcppsymg/names"
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/errs"
+)
+
+// enumItemPrefix returns the prefix common to the names of the items of an enum,
+// ended by '_', like CJSON_ of CJSON_Invalid and CJSON_False. It returns "" if
+// there is none, or if a name without it doesn't start with a letter.
+func enumItemPrefix(items []*ast.EnumItem) string {
+	if len(items) < 2 {
+		return ""
+	}
+	prefix := items[0].Name.Name
+	for _, item := range items[1:] {
+		name := item.Name.Name
+		n := 0
+		for n < len(prefix) && n < len(name) && prefix[n] == name[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	prefix = prefix[:strings.LastIndex(prefix, "_")+1]
+	if strings.Trim(prefix, "_") == "" {
+		return ""
+	}
+	for _, item := range items {
+		rest := item.Name.Name[len(prefix):]
+		if rest == "" || !unicode.IsLetter(rune(rest[0])) {
+			return ""
+		}
+	}
+	return prefix
+}
+
+// trimmedEnumPrefix returns the prefix trimmed from the names of the items of an
+// enum with trimEnumPrefix of llcppg.cfg, it's "" if a name without it is taken.
+func (p *Package) trimmedEnumPrefix(scope ast.Expr, items []*ast.EnumItem) string {
+	if !p.CppgConf.TrimEnumPrefix {
+		return ""
+	}
+	prefix := enumItemPrefix(items)
+	if prefix == "" {
+		return ""
+	}
+	for _, item := range items {
+		if !p.nameFree(trimmedItemName(scope, item, prefix)) {
+			return ""
+		}
+	}
+	return prefix
+}
+
+// trimmedItemName returns the c name of an enum item without the prefix.
+func trimmedItemName(scope ast.Expr, item *ast.EnumItem, prefix string) string {
+	return qualifiedIdent(scope, &ast.Ident{Name: item.Name.Name[len(prefix):]}).Name
+}
+
+// nameFree reports whether neither a c name nor its Go name is declared.
+func (p *Package) nameFree(name string) bool {
+	scope := p.p.Types.Scope()
+	return scope.Lookup(name) == nil && scope.Lookup(names.PubName(name)) == nil
+}
+
+// anonEnumName returns the c name an anonymous enum is declared with by typedAnonEnum
+// of llcppg.cfg, which is the prefix common to its items, like CJSON of CJSON_Invalid
+// and CJSON_False. It returns nil if the option is off, if the items have no prefix
+// or if its name is taken, then the items are of the integer type of the enum.
+func (p *Package) anonEnumName(scope ast.Expr, items []*ast.EnumItem) *ast.Ident {
+	if !p.CppgConf.TypedAnonEnum {
+		return nil
+	}
+	prefix := enumItemPrefix(items)
+	if prefix == "" {
+		return nil
+	}
+	name := qualifiedIdent(scope, &ast.Ident{Name: strings.TrimRight(prefix, "_")})
+	if !p.nameFree(name.Name) {
+		return nil
+	}
+	return name
+}
+
+// newEnumString generates the String method of an enum with enumString of llcppg.cfg,
+// items of the same value are named by the first of them, like:
+//
+//	func (e Color) String() string // Red, or Color(3) for a value of no item
+func (p *Package) newEnumString(named *types.Named, consts []*types.Const) error {
+	pkg := p.p.Types
+	recv := types.NewParam(token.NoPos, pkg, "e", named)
+	if p.funcDefined(recv, "String") {
+		return errs.NewFuncAlreadyDefinedError(named.Obj().Name() + ".String")
+	}
+	strType := types.Typ[types.String]
+	sig := types.NewSignatureType(recv, nil, nil, nil, types.NewTuple(types.NewParam(token.NoPos, pkg, "", strType)), false)
+	fn := p.p.NewFuncDecl(token.NoPos, "String", sig)
+	cb := fn.BodyStart(p.p)
+	// switch e { case Red: return "Red" ... }
+	cb.Switch().Val(recv).Then()
+	seen := make(map[string]bool)
+	for _, obj := range consts {
+		val := obj.Val().ExactString()
+		if seen[val] {
+			continue
+		}
+		seen[val] = true
+		cb.Case().Val(obj).Then().
+			Val(obj.Name()).Return(1).
+			End()
+	}
+	cb.End()
+	// return "Color(" + strconv.FormatInt(int64(e), 10) + ")"
+	format, intType := "FormatInt", types.Typ[types.Int64]
+	if basic, ok := named.Underlying().(*types.Basic); ok && basic.Info()&types.IsUnsigned != 0 {
+		format, intType = "FormatUint", types.Typ[types.Uint64]
+	}
+	cb.Val(named.Obj().Name() + "(").
+		Val(p.p.Import("strconv").Ref(format)).Typ(intType).Val(recv).Call(1).Val(10).Call(2).
+		BinaryOp(token.ADD).Val(")").BinaryOp(token.ADD).Return(1).
+		End()
+	fn.SetComments(p.p, flagEnumDoc("// String returns the name of the item of e, or "+named.Obj().Name()+"(e) if there is none."))
+	return nil
+}
@@ -0,0 +1,563 @@
+/*
+This file is used to convert the builtin types that Go has no type of the same
+size for, like __int128, _Float16, __float128 and long double when it's wider
+than double. Each is declared once in the package as an opaque type of its size,
+with helpers converting it from and to a Go type, in the file:
+
+	<name>_autogen_builtin.go
+
+The types of 16 bytes are aligned to 16 in C, but Go aligns them to 8, so a
+field of one is placed at its C offset by explicit padding.
+
+A function passing one of them by value is not converted, as Go can't pass it
+in the registers C uses.
+*/
+package convert
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/errs"
+)
+
+// Formats of long double.
+const (
+	longDoubleDouble       = "double"        // the same as double
+	longDoubleX87          = "x87"           // 80-bit x87 extended precision
+	longDoubleBinary128    = "binary128"     // IEEE quadruple precision
+	longDoubleDoubleDouble = "double-double" // IBM pair of doubles
+)
+
+// longDoubleFormat returns the format of long double on the target.
+func longDoubleFormat(goos, goarch string) string {
+	switch {
+	case goos == "windows", goos == "darwin" && goarch == "arm64", goos == "ios":
+		return longDoubleDouble
+	}
+	switch goarch {
+	case "386", "amd64":
+		return longDoubleX87
+	case "arm64", "riscv64", "s390x", "mips64", "mips64le", "loong64", "wasm":
+		return longDoubleBinary128
+	case "ppc64", "ppc64le":
+		return longDoubleDoubleDouble
+	}
+	return longDoubleDouble
+}
+
+// extBuiltin is a builtin type declared in the package as an opaque type.
+type extBuiltin struct {
+	name  string // Go name, like Int128
+	ctype string // C spelling, like __int128
+	doc   string // the end of the doc of the type, like: the low 64 bits first.
+	typ   types.Type
+	align int64 // alignment in C if it's more than Go gives typ, 0 otherwise
+	// newHelpers declares the functions converting the type from and to a Go type
+	newHelpers func(p *Package, named *types.Named)
+}
+
+// extBuiltinOf returns the opaque type of a builtin type, or nil if it has none.
+func extBuiltinOf(t *ast.BuiltinType, goarch, format string) *extBuiltin {
+	words := types.NewArray(types.Typ[types.Uint64], 2)
+	switch {
+	case t.Kind == ast.Int128 && t.Flags&ast.Unsigned != 0:
+		return &extBuiltin{name: "Uint128", ctype: "unsigned __int128", doc: "the low 64 bits first.",
+			typ: words, align: 16, newHelpers: (*Package).newUint128Helpers}
+	case t.Kind == ast.Int128:
+		return &extBuiltin{name: "Int128", ctype: "__int128", doc: "the low 64 bits first.",
+			typ: words, align: 16, newHelpers: (*Package).newInt128Helpers}
+	case t.Kind == ast.Float16:
+		return &extBuiltin{name: "Float16", ctype: "_Float16", doc: "a half precision float.",
+			typ: types.Typ[types.Uint16], newHelpers: (*Package).newFloat16Helpers}
+	case t.Kind == ast.Float128:
+		return &extBuiltin{name: "Float128", ctype: "__float128", doc: "an IEEE quadruple precision float, the low 64 bits first.",
+			typ: words, align: 16, newHelpers: (*Package).newBinary128Helpers}
+	case t.Kind == ast.Float && t.Flags == ast.Long|ast.Double:
+		ld := &extBuiltin{name: "LongDouble", ctype: "long double", typ: words, align: 16}
+		switch format {
+		case longDoubleX87:
+			ld.doc = "an x87 extended precision float, the 64-bit mantissa first."
+			is386 := goarch == "386"
+			if is386 {
+				// 12 bytes aligned to 4
+				ld.typ, ld.align = types.NewArray(types.Typ[types.Uint32], 3), 0
+			}
+			ld.newHelpers = func(p *Package, named *types.Named) { p.newX87Helpers(named, is386) }
+		case longDoubleBinary128:
+			ld.doc = "an IEEE quadruple precision float, the low 64 bits first."
+			ld.newHelpers = (*Package).newBinary128Helpers
+		case longDoubleDoubleDouble:
+			ld.doc = "a pair of doubles whose sum is its value."
+			ld.typ = types.NewArray(types.Typ[types.Float64], 2)
+			ld.newHelpers = (*Package).newDoubleDoubleHelpers
+		default:
+			return nil
+		}
+		return ld
+	}
+	return nil
+}
+
+// extBuiltinType returns the opaque type of a builtin type, which is declared
+// with its helpers in the builtin file of the package when it's first used.
+func (p *Package) extBuiltinType(t *ast.BuiltinType) (types.Type, error) {
+	target := p.conf.CppgConf.Target
+	ext := extBuiltinOf(t, target.Goarch(), longDoubleFormat(target.Goos(), target.Goarch()))
+	if ext == nil {
+		return nil, fmt.Errorf("%s", "not found in type map")
+	}
+	if named := p.extBuiltins[ext.name]; named != nil {
+		return named, nil
+	}
+	if obj := p.p.Types.Scope().Lookup(ext.name); obj != nil {
+		return nil, errs.NewTypeDefinedError(ext.name, ext.ctype)
+	}
+	old, err := p.p.SetCurFile(p.builtinFileName(), true)
+	if err != nil {
+		return nil, err
+	}
+	defer p.p.RestoreCurFile(old)
+
+	typeBlock := p.p.NewTypeDefs()
+	typeBlock.SetComments(builtinDoc(ext.name + " is the C " + ext.ctype + ", " + ext.doc))
+	decl := typeBlock.NewType(ext.name)
+	decl.InitType(p.p, ext.typ)
+	named := decl.Type()
+	if ext.align != 0 {
+		p.cvt.sizes.SetAlign(named, ext.align)
+	}
+	ext.newHelpers(p, named)
+	p.extBuiltins[ext.name] = named
+	p.byValueTypes[named] = ext.ctype
+	return named, nil
+}
+
+// byValueType returns the C spelling of the first parameter or result of sig
+// that is an opaque builtin type passed by value, or "" if there is none.
+func (p *Package) byValueType(sig *types.Signature) string {
+	for _, tuple := range []*types.Tuple{sig.Params(), sig.Results()} {
+		for i := 0; i < tuple.Len(); i++ {
+			if ctype, ok := p.byValueTypes[tuple.At(i).Type()]; ok {
+				return ctype
+			}
+		}
+	}
+	return ""
+}
+
+func (p *Package) builtinFileName() string {
+	return p.conf.Name + "_autogen_builtin.go"
+}
+
+// WriteBuiltinFile writes the declarations of the opaque builtin types used by
+// the package, if any.
+func (p *Package) WriteBuiltinFile() error {
+	fileName := p.builtinFileName()
+	if _, ok := p.p.File(fileName); !ok {
+		return nil
+	}
+	return p.writeToFile(fileName, filepath.Join(p.GetOutputDir(), fileName))
+}
+
+// newHelper declares a helper of an opaque builtin type, a method if recv is
+// not nil, and returns the code builder of its body.
+func (p *Package) newHelper(name, doc string, recv *types.Var, params []*types.Var, result types.Type) *gogen.CodeBuilder {
+	results := types.NewTuple(types.NewParam(token.NoPos, p.p.Types, "", result))
+	sig := types.NewSignatureType(recv, nil, nil, types.NewTuple(params...), results, false)
+	fn := p.p.NewFuncDecl(token.NoPos, name, sig)
+	fn.SetComments(p.p, builtinDoc(name+" "+doc))
+	return fn.BodyStart(p.p)
+}
+
+// newInt128Helpers generates the helpers of Int128:
+//
+//	func NewInt128(i int64) Int128
+//	func (v Int128) Int64() int64
+func (p *Package) newInt128Helpers(named *types.Named) {
+	pkg := p.p.Types
+	u64, i64 := types.Typ[types.Uint64], types.Typ[types.Int64]
+
+	// return Int128{uint64(i), uint64(i >> 63)}
+	i := types.NewParam(token.NoPos, pkg, "i", i64)
+	p.newHelper("NewInt128", "returns the Int128 of i.", nil, []*types.Var{i}, named).
+		Typ(u64).Val(i).Call(1).Typ(u64).Val(i).Val(63).BinaryOp(token.SHR).Call(1).ArrayLit(named, 2).Return(1).
+		End()
+
+	// return int64(v[0])
+	v := types.NewParam(token.NoPos, pkg, "v", named)
+	p.newHelper("Int64", "returns the low 64 bits of v.", v, nil, i64).
+		Typ(i64).Val(v).Val(0).Index(1, false).Call(1).Return(1).
+		End()
+}
+
+// newUint128Helpers generates the helpers of Uint128:
+//
+//	func NewUint128(u uint64) Uint128
+//	func (v Uint128) Uint64() uint64
+func (p *Package) newUint128Helpers(named *types.Named) {
+	pkg := p.p.Types
+	u64 := types.Typ[types.Uint64]
+
+	// return Uint128{u, 0}
+	u := types.NewParam(token.NoPos, pkg, "u", u64)
+	p.newHelper("NewUint128", "returns the Uint128 of u.", nil, []*types.Var{u}, named).
+		Val(u).Val(0).ArrayLit(named, 2).Return(1).
+		End()
+
+	// return v[0]
+	v := types.NewParam(token.NoPos, pkg, "v", named)
+	p.newHelper("Uint64", "returns the low 64 bits of v.", v, nil, u64).
+		Val(v).Val(0).Index(1, false).Return(1).
+		End()
+}
+
+// newFloat16Helpers generates the helpers of Float16:
+//
+//	func NewFloat16(f float32) Float16
+//	func (v Float16) Float32() float32
+func (p *Package) newFloat16Helpers(named *types.Named) {
+	pkg, math := p.p.Types, p.p.Import("math")
+	f32, f64, u16 := types.Typ[types.Float32], types.Typ[types.Float64], types.Typ[types.Uint16]
+
+	f := types.NewParam(token.NoPos, pkg, "f", f32)
+	cb := p.newHelper("NewFloat16", "returns f rounded to the nearest Float16.", nil, []*types.Var{f}, named)
+	// var sign uint16
+	// if math.Signbit(float64(f)) { sign = 0x8000 }
+	cb.NewVar(u16, "sign")
+	cb.If().Val(math.Ref("Signbit")).Typ(f64).Val(f).Call(1).Call(1).Then().
+		VarRef("sign").Val(hexLit(0x8000)).Assign(1).
+		End()
+	// abs := math.Abs(float64(f))
+	cb.DefineVarStart(token.NoPos, "abs").Val(math.Ref("Abs")).Typ(f64).Val(f).Call(1).Call(1).EndInit(1)
+	// switch {
+	// case math.IsNaN(abs): return Float16(sign | 0x7e00)
+	// case math.IsInf(abs, 0): return Float16(sign | 0x7c00)
+	// case abs == 0: return Float16(sign)
+	// }
+	cb.Switch().None().Then().
+		Case().Val(math.Ref("IsNaN")).VarVal("abs").Call(1).Then().
+		Typ(named).VarVal("sign").Val(hexLit(0x7e00)).BinaryOp(token.OR).Call(1).Return(1).
+		End().
+		Case().Val(math.Ref("IsInf")).VarVal("abs").Val(0).Call(2).Then().
+		Typ(named).VarVal("sign").Val(hexLit(0x7c00)).BinaryOp(token.OR).Call(1).Return(1).
+		End().
+		Case().VarVal("abs").Val(0).BinaryOp(token.EQL).Then().
+		Typ(named).VarVal("sign").Call(1).Return(1).
+		End().
+		End()
+	// exp := math.Ilogb(abs)
+	// if exp < -14 { exp = -14 }
+	cb.DefineVarStart(token.NoPos, "exp").Val(math.Ref("Ilogb")).VarVal("abs").Call(1).EndInit(1)
+	cb.If().VarVal("exp").Val(-14).BinaryOp(token.LSS).Then().
+		VarRef("exp").Val(-14).Assign(1).
+		End()
+	// The 10-bit mantissa is rounded in the binade of exp, or in the one of the
+	// subnormals, and a carry of it increments the exponent:
+	// h := math.RoundToEven(math.Ldexp(abs, 10-exp)) + float64(exp+14)*0x400
+	// return Float16(sign | uint16(math.Min(h, 0x7c00)))
+	cb.DefineVarStart(token.NoPos, "h").
+		Val(math.Ref("RoundToEven")).Val(math.Ref("Ldexp")).VarVal("abs").Val(10).VarVal("exp").BinaryOp(token.SUB).Call(2).Call(1).
+		Typ(f64).VarVal("exp").Val(14).BinaryOp(token.ADD).Call(1).Val(hexLit(0x400)).BinaryOp(token.MUL).
+		BinaryOp(token.ADD).EndInit(1)
+	cb.Typ(named).VarVal("sign").
+		Typ(u16).Val(math.Ref("Min")).VarVal("h").Val(hexLit(0x7c00)).Call(2).Call(1).
+		BinaryOp(token.OR).Call(1).Return(1).
+		End()
+
+	v := types.NewParam(token.NoPos, pkg, "v", named)
+	cb = p.newHelper("Float32", "returns v as a float32.", v, nil, f32)
+	// exp := int(v>>10) & 0x1f
+	// frac := float64(v & 0x3ff)
+	// f := math.Ldexp(frac+0x400, exp-25)
+	cb.DefineVarStart(token.NoPos, "exp").
+		Typ(types.Typ[types.Int]).Val(v).Val(10).BinaryOp(token.SHR).Call(1).Val(hexLit(0x1f)).BinaryOp(token.AND).EndInit(1)
+	cb.DefineVarStart(token.NoPos, "frac").Typ(f64).Val(v).Val(hexLit(0x3ff)).BinaryOp(token.AND).Call(1).EndInit(1)
+	cb.DefineVarStart(token.NoPos, "f").
+		Val(math.Ref("Ldexp")).VarVal("frac").Val(hexLit(0x400)).BinaryOp(token.ADD).VarVal("exp").Val(25).BinaryOp(token.SUB).Call(2).
+		EndInit(1)
+	// switch {
+	// case exp == 0x1f && frac != 0: return float32(math.NaN())
+	// case exp == 0x1f: f = math.Inf(1)
+	// case exp == 0: f = math.Ldexp(frac, -24)
+	// }
+	cb.Switch().None().Then().
+		Case().VarVal("exp").Val(hexLit(0x1f)).BinaryOp(token.EQL).VarVal("frac").Val(0).BinaryOp(token.NEQ).BinaryOp(token.LAND).Then().
+		Typ(f32).Val(math.Ref("NaN")).Call(0).Call(1).Return(1).
+		End().
+		Case().VarVal("exp").Val(hexLit(0x1f)).BinaryOp(token.EQL).Then().
+		VarRef("f").Val(math.Ref("Inf")).Val(1).Call(1).Assign(1).
+		End().
+		Case().VarVal("exp").Val(0).BinaryOp(token.EQL).Then().
+		VarRef("f").Val(math.Ref("Ldexp")).VarVal("frac").Val(-24).Call(2).Assign(1).
+		End().
+		End()
+	// if v&0x8000 != 0 { f = -f }
+	// return float32(f)
+	cb.If().Val(v).Val(hexLit(0x8000)).BinaryOp(token.AND).Val(0).BinaryOp(token.NEQ).Then().
+		VarRef("f").VarVal("f").UnaryOp(token.SUB).Assign(1).
+		End()
+	cb.Typ(f32).VarVal("f").Call(1).Return(1).
+		End()
+}
+
+// newBinary128Helpers generates the helpers of an IEEE quadruple precision float:
+//
+//	func NewFloat128(f float64) Float128
+//	func (v Float128) Float64() float64
+func (p *Package) newBinary128Helpers(named *types.Named) {
+	pkg, math := p.p.Types, p.p.Import("math")
+	f64, u64 := types.Typ[types.Float64], types.Typ[types.Uint64]
+	name := named.Obj().Name()
+
+	f := types.NewParam(token.NoPos, pkg, "f", f64)
+	cb := p.newHelper("New"+name, "returns the "+name+" of f.", nil, []*types.Var{f}, named)
+	// var hi uint64
+	// if math.Signbit(f) { hi = 0x8000000000000000 }
+	cb.NewVar(u64, "hi")
+	cb.If().Val(math.Ref("Signbit")).Val(f).Call(1).Then().
+		VarRef("hi").Val(hexLit(1 << 63)).Assign(1).
+		End()
+	// switch {
+	// case math.IsNaN(f): return Float128{0, 0x7fff800000000000}
+	// case math.IsInf(f, 0): return Float128{0, hi | 0x7fff000000000000}
+	// case f == 0: return Float128{0, hi}
+	// }
+	cb.Switch().None().Then().
+		Case().Val(math.Ref("IsNaN")).Val(f).Call(1).Then().
+		Val(0).Val(hexLit(0x7fff800000000000)).ArrayLit(named, 2).Return(1).
+		End().
+		Case().Val(math.Ref("IsInf")).Val(f).Val(0).Call(2).Then().
+		Val(0).VarVal("hi").Val(hexLit(0x7fff000000000000)).BinaryOp(token.OR).ArrayLit(named, 2).Return(1).
+		End().
+		Case().Val(f).Val(0).BinaryOp(token.EQL).Then().
+		Val(0).VarVal("hi").ArrayLit(named, 2).Return(1).
+		End().
+		End()
+	// frac, exp := math.Frexp(math.Abs(f))
+	// m := uint64(math.Ldexp(frac, 64))
+	p.defineMantissa(cb, f)
+	// return Float128{m << 49, hi | uint64(exp+16382)<<48 | m<<1>>16}
+	cb.VarVal("m").Val(49).BinaryOp(token.SHL).
+		VarVal("hi").
+		Typ(u64).VarVal("exp").Val(16382).BinaryOp(token.ADD).Call(1).Val(48).BinaryOp(token.SHL).BinaryOp(token.OR).
+		VarVal("m").Val(1).BinaryOp(token.SHL).Val(16).BinaryOp(token.SHR).BinaryOp(token.OR).
+		ArrayLit(named, 2).Return(1).
+		End()
+
+	v := types.NewParam(token.NoPos, pkg, "v", named)
+	cb = p.newHelper("Float64", "returns v rounded to the nearest float64.", v, nil, f64)
+	// exp := int(v[1]>>48) & 0x7fff
+	cb.DefineVarStart(token.NoPos, "exp").
+		Typ(types.Typ[types.Int]).Val(v).Val(1).Index(1, false).Val(48).BinaryOp(token.SHR).Call(1).
+		Val(hexLit(0x7fff)).BinaryOp(token.AND).EndInit(1)
+	// m := new(big.Int).SetUint64(v[1] & 0xffffffffffff)
+	// m.Lsh(m, 64).Or(m, new(big.Int).SetUint64(v[0]))
+	bigInt := p.p.Import("math/big").Ref("Int").Type()
+	cb.DefineVarStart(token.NoPos, "m").
+		Val(p.p.Builtin().Ref("new")).Typ(bigInt).Call(1).MemberVal("SetUint64").
+		Val(v).Val(1).Index(1, false).Val(hexLit(1<<48 - 1)).BinaryOp(token.AND).Call(1).EndInit(1)
+	cb.VarVal("m").MemberVal("Lsh").VarVal("m").Val(64).Call(2).MemberVal("Or").VarVal("m").
+		Val(p.p.Builtin().Ref("new")).Typ(bigInt).Call(1).MemberVal("SetUint64").Val(v).Val(0).Index(1, false).Call(1).
+		Call(2).EndStmt()
+	// f := math.Inf(1)
+	// switch {
+	// case exp == 0x7fff && m.Sign() != 0: return math.NaN()
+	// case exp == 0: f, _ = new(big.Float).SetMantExp(new(big.Float).SetInt(m), -16494).Float64()
+	// case exp != 0x7fff: f, _ = new(big.Float).SetMantExp(new(big.Float).SetInt(m.SetBit(m, 112, 1)), exp-16495).Float64()
+	// }
+	cb.DefineVarStart(token.NoPos, "f").Val(math.Ref("Inf")).Val(1).Call(1).EndInit(1)
+	cb.Switch().None().Then().
+		Case().VarVal("exp").Val(hexLit(0x7fff)).BinaryOp(token.EQL).
+		VarVal("m").MemberVal("Sign").Call(0).Val(0).BinaryOp(token.NEQ).BinaryOp(token.LAND).Then().
+		Val(math.Ref("NaN")).Call(0).Return(1).
+		End().
+		Case().VarVal("exp").Val(0).BinaryOp(token.EQL).Then()
+	p.assignFloat64(cb, func() {
+		p.newBigFloat(cb).MemberVal("SetInt").VarVal("m").Call(1)
+	}, func() {
+		cb.Val(-16494)
+	})
+	cb.End().
+		Case().VarVal("exp").Val(hexLit(0x7fff)).BinaryOp(token.NEQ).Then()
+	p.assignFloat64(cb, func() {
+		p.newBigFloat(cb).MemberVal("SetInt").VarVal("m").MemberVal("SetBit").VarVal("m").Val(112).Val(1).Call(3).Call(1)
+	}, func() {
+		cb.VarVal("exp").Val(16495).BinaryOp(token.SUB)
+	})
+	cb.End().
+		End()
+	// if v[1]>>63 != 0 { f = -f }
+	// return f
+	cb.If().Val(v).Val(1).Index(1, false).Val(63).BinaryOp(token.SHR).Val(0).BinaryOp(token.NEQ).Then().
+		VarRef("f").VarVal("f").UnaryOp(token.SUB).Assign(1).
+		End()
+	cb.VarVal("f").Return(1).
+		End()
+}
+
+// newX87Helpers generates the helpers of long double of the x87 extended
+// precision, which is 12 bytes on 386 and 16 bytes on amd64:
+//
+//	func NewLongDouble(f float64) LongDouble
+//	func (v LongDouble) Float64() float64
+func (p *Package) newX87Helpers(named *types.Named, is386 bool) {
+	pkg, math := p.p.Types, p.p.Import("math")
+	f64, u64, u32 := types.Typ[types.Float64], types.Typ[types.Uint64], types.Typ[types.Uint32]
+
+	f := types.NewParam(token.NoPos, pkg, "f", f64)
+	cb := p.newHelper("NewLongDouble", "returns the LongDouble of f.", nil, []*types.Var{f}, named)
+	// var se, m uint64
+	// if math.Signbit(f) { se = 0x8000 }
+	cb.NewVar(u64, "se", "m")
+	cb.If().Val(math.Ref("Signbit")).Val(f).Call(1).Then().
+		VarRef("se").Val(hexLit(0x8000)).Assign(1).
+		End()
+	// switch {
+	// case math.IsNaN(f): se, m = 0x7fff, 0xc000000000000000
+	// case math.IsInf(f, 0): se, m = se|0x7fff, 0x8000000000000000
+	// case f != 0:
+	//	frac, exp := math.Frexp(math.Abs(f))
+	//	se, m = se|uint64(exp+16382), uint64(math.Ldexp(frac, 64))
+	// }
+	cb.Switch().None().Then().
+		Case().Val(math.Ref("IsNaN")).Val(f).Call(1).Then().
+		VarRef("se").VarRef("m").Val(hexLit(0x7fff)).Val(hexLit(0xc000000000000000)).Assign(2).
+		End().
+		Case().Val(math.Ref("IsInf")).Val(f).Val(0).Call(2).Then().
+		VarRef("se").VarRef("m").VarVal("se").Val(hexLit(0x7fff)).BinaryOp(token.OR).Val(hexLit(1 << 63)).Assign(2).
+		End().
+		Case().Val(f).Val(0).BinaryOp(token.NEQ).Then()
+	cb.DefineVarStart(token.NoPos, "frac", "exp").Val(math.Ref("Frexp")).Val(math.Ref("Abs")).Val(f).Call(1).Call(1).EndInit(1)
+	cb.VarRef("se").VarRef("m").
+		VarVal("se").Typ(u64).VarVal("exp").Val(16382).BinaryOp(token.ADD).Call(1).BinaryOp(token.OR).
+		Typ(u64).Val(math.Ref("Ldexp")).VarVal("frac").Val(64).Call(2).Call(1).
+		Assign(2)
+	cb.End().
+		End()
+	if is386 {
+		// return LongDouble{uint32(m), uint32(m >> 32), uint32(se)}
+		cb.Typ(u32).VarVal("m").Call(1).
+			Typ(u32).VarVal("m").Val(32).BinaryOp(token.SHR).Call(1).
+			Typ(u32).VarVal("se").Call(1).
+			ArrayLit(named, 3).Return(1).
+			End()
+	} else {
+		// return LongDouble{m, se}
+		cb.VarVal("m").VarVal("se").ArrayLit(named, 2).Return(1).
+			End()
+	}
+
+	v := types.NewParam(token.NoPos, pkg, "v", named)
+	cb = p.newHelper("Float64", "returns v rounded to the nearest float64.", v, nil, f64)
+	cb.DefineVarStart(token.NoPos, "m", "se")
+	if is386 {
+		// m, se := uint64(v[1])<<32 | uint64(v[0]), v[2]
+		cb.Typ(u64).Val(v).Val(1).Index(1, false).Call(1).Val(32).BinaryOp(token.SHL).
+			Typ(u64).Val(v).Val(0).Index(1, false).Call(1).BinaryOp(token.OR).
+			Val(v).Val(2).Index(1, false)
+	} else {
+		// m, se := v[0], v[1]
+		cb.Val(v).Val(0).Index(1, false).Val(v).Val(1).Index(1, false)
+	}
+	cb.EndInit(2)
+	// exp := int(se) & 0x7fff
+	// f := math.Inf(1)
+	// switch {
+	// case exp == 0x7fff && m<<1 != 0: return math.NaN()
+	// case exp == 0: f, _ = new(big.Float).SetMantExp(new(big.Float).SetUint64(m), -16445).Float64()
+	// case exp != 0x7fff: f, _ = new(big.Float).SetMantExp(new(big.Float).SetUint64(m), exp-16446).Float64()
+	// }
+	cb.DefineVarStart(token.NoPos, "exp").
+		Typ(types.Typ[types.Int]).VarVal("se").Call(1).Val(hexLit(0x7fff)).BinaryOp(token.AND).EndInit(1)
+	cb.DefineVarStart(token.NoPos, "f").Val(math.Ref("Inf")).Val(1).Call(1).EndInit(1)
+	mantissa := func() {
+		p.newBigFloat(cb).MemberVal("SetUint64").VarVal("m").Call(1)
+	}
+	cb.Switch().None().Then().
+		Case().VarVal("exp").Val(hexLit(0x7fff)).BinaryOp(token.EQL).
+		VarVal("m").Val(1).BinaryOp(token.SHL).Val(0).BinaryOp(token.NEQ).BinaryOp(token.LAND).Then().
+		Val(math.Ref("NaN")).Call(0).Return(1).
+		End().
+		Case().VarVal("exp").Val(0).BinaryOp(token.EQL).Then()
+	p.assignFloat64(cb, mantissa, func() {
+		cb.Val(-16445)
+	})
+	cb.End().
+		Case().VarVal("exp").Val(hexLit(0x7fff)).BinaryOp(token.NEQ).Then()
+	p.assignFloat64(cb, mantissa, func() {
+		cb.VarVal("exp").Val(16446).BinaryOp(token.SUB)
+	})
+	cb.End().
+		End()
+	// if se&0x8000 != 0 { f = -f }
+	// return f
+	cb.If().VarVal("se").Val(hexLit(0x8000)).BinaryOp(token.AND).Val(0).BinaryOp(token.NEQ).Then().
+		VarRef("f").VarVal("f").UnaryOp(token.SUB).Assign(1).
+		End()
+	cb.VarVal("f").Return(1).
+		End()
+}
+
+// newDoubleDoubleHelpers generates the helpers of long double of a pair of doubles:
+//
+//	func NewLongDouble(f float64) LongDouble
+//	func (v LongDouble) Float64() float64
+func (p *Package) newDoubleDoubleHelpers(named *types.Named) {
+	pkg, f64 := p.p.Types, types.Typ[types.Float64]
+
+	// return LongDouble{f, 0}
+	f := types.NewParam(token.NoPos, pkg, "f", f64)
+	p.newHelper("NewLongDouble", "returns the LongDouble of f.", nil, []*types.Var{f}, named).
+		Val(f).Val(0).ArrayLit(named, 2).Return(1).
+		End()
+
+	// return v[0] + v[1]
+	v := types.NewParam(token.NoPos, pkg, "v", named)
+	p.newHelper("Float64", "returns v rounded to a float64.", v, nil, f64).
+		Val(v).Val(0).Index(1, false).Val(v).Val(1).Index(1, false).BinaryOp(token.ADD).Return(1).
+		End()
+}
+
+// defineMantissa defines the mantissa of a non-zero finite float64 f, whose
+// top bit is set, and its exponent, so |f| is m×2^(exp-64):
+//
+//	frac, exp := math.Frexp(math.Abs(f))
+//	m := uint64(math.Ldexp(frac, 64))
+func (p *Package) defineMantissa(cb *gogen.CodeBuilder, f *types.Var) {
+	math := p.p.Import("math")
+	cb.DefineVarStart(token.NoPos, "frac", "exp").Val(math.Ref("Frexp")).Val(math.Ref("Abs")).Val(f).Call(1).Call(1).EndInit(1)
+	cb.DefineVarStart(token.NoPos, "m").
+		Typ(types.Typ[types.Uint64]).Val(math.Ref("Ldexp")).VarVal("frac").Val(64).Call(2).Call(1).EndInit(1)
+}
+
+// assignFloat64 assigns the float64 nearest to the mantissa and the exponent
+// pushed by mant and exp, which math/big rounds, to f:
+//
+//	f, _ = new(big.Float).SetMantExp(mant, exp).Float64()
+func (p *Package) assignFloat64(cb *gogen.CodeBuilder, mant, exp func()) {
+	cb.VarRef("f").VarRef(nil)
+	p.newBigFloat(cb).MemberVal("SetMantExp")
+	mant()
+	exp()
+	cb.Call(2).MemberVal("Float64").Call(0).Assign(2, 1)
+}
+
+// newBigFloat pushes new(big.Float).
+func (p *Package) newBigFloat(cb *gogen.CodeBuilder) *gogen.CodeBuilder {
+	return cb.Val(p.p.Builtin().Ref("new")).Typ(p.p.Import("math/big").Ref("Float").Type()).Call(1)
+}
+
+func hexLit(v uint64) *goast.BasicLit {
+	return &goast.BasicLit{Kind: token.INT, Value: "0x" + strconv.FormatUint(v, 16)}
+}
+
+func builtinDoc(text string) *goast.CommentGroup {
+	return &goast.CommentGroup{List: []*goast.Comment{{Text: "// " + text}}}
+}
@@ -0,0 +1,138 @@
+/*
+This file is used to generate the helpers of bit-flag enums
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/errs"
+)
+
+// isFlagEnum reports whether an enum is a set of bit flags: it is marked with
+// flag_enum, or it has at least 3 single-bit items and the other items are
+// combinations of them, like:
+//
+//	enum Mode { None = 0, Read = 1, Write = 2, Exec = 4, All = Read | Write | Exec };
+func isFlagEnum(attrs []*ast.Attr, consts []*types.Const) bool {
+	if hasAttr(attrs, ast.FlagEnumAttr) {
+		return true
+	}
+	mask, flags := flagMask(consts)
+	if flags < 3 {
+		return false
+	}
+	for _, obj := range consts {
+		v, ok := constant.Uint64Val(obj.Val())
+		if !ok || v&^mask != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// flagMask returns the bits of the single-bit items and the number of them.
+func flagMask(consts []*types.Const) (mask uint64, flags int) {
+	for _, obj := range consts {
+		if v, ok := constant.Uint64Val(obj.Val()); ok && isFlag(v) && mask&v == 0 {
+			mask |= v
+			flags++
+		}
+	}
+	return
+}
+
+func isFlag(v uint64) bool {
+	return v != 0 && v&(v-1) == 0
+}
+
+// newFlagEnumMethods generates the helpers of a bit-flag enum, like:
+//
+//	func (f Mode) Has(flag Mode) bool
+//	func (f *Mode) Set(flag Mode)
+//	func (f Mode) String() string // Read|Write, or 0x8 for unknown bits
+func (p *Package) newFlagEnumMethods(named *types.Named, consts []*types.Const) error {
+	for _, name := range []string{"Has", "Set", "String"} {
+		if p.funcDefined(types.NewParam(token.NoPos, p.p.Types, "f", named), name) {
+			return errs.NewFuncAlreadyDefinedError(named.Obj().Name() + "." + name)
+		}
+	}
+	pkg := p.p.Types
+	boolType := types.Typ[types.Bool]
+	strType := types.Typ[types.String]
+
+	// func (f Mode) Has(flag Mode) bool { return f&flag == flag }
+	recv := types.NewParam(token.NoPos, pkg, "f", named)
+	flag := types.NewParam(token.NoPos, pkg, "flag", named)
+	sig := types.NewSignatureType(recv, nil, nil, types.NewTuple(flag), types.NewTuple(types.NewParam(token.NoPos, pkg, "", boolType)), false)
+	fn := p.p.NewFuncDecl(token.NoPos, "Has", sig)
+	fn.BodyStart(p.p).
+		Val(recv).Val(flag).BinaryOp(token.AND).Val(flag).BinaryOp(token.EQL).Return(1).
+		End()
+	fn.SetComments(p.p, flagEnumDoc("// Has reports whether all bits of flag are set in f."))
+
+	// func (f *Mode) Set(flag Mode) { *f |= flag }
+	recv = types.NewParam(token.NoPos, pkg, "f", types.NewPointer(named))
+	flag = types.NewParam(token.NoPos, pkg, "flag", named)
+	sig = types.NewSignatureType(recv, nil, nil, types.NewTuple(flag), nil, false)
+	fn = p.p.NewFuncDecl(token.NoPos, "Set", sig)
+	fn.BodyStart(p.p).
+		Val(recv).ElemRef().Val(flag).AssignOp(token.OR_ASSIGN).
+		End()
+	fn.SetComments(p.p, flagEnumDoc("// Set sets the bits of flag in f."))
+
+	// func (f Mode) String() string
+	recv = types.NewParam(token.NoPos, pkg, "f", named)
+	sig = types.NewSignatureType(recv, nil, nil, nil, types.NewTuple(types.NewParam(token.NoPos, pkg, "", strType)), false)
+	fn = p.p.NewFuncDecl(token.NoPos, "String", sig)
+	cb := fn.BodyStart(p.p)
+	cb.NewVar(strType, "s")
+	s := cb.Scope().Lookup("s")
+	var mask uint64
+	var zero *types.Const
+	for _, obj := range consts {
+		v, ok := constant.Uint64Val(obj.Val())
+		if ok && v == 0 && zero == nil {
+			zero = obj
+		}
+		if !ok || !isFlag(v) || mask&v != 0 {
+			continue
+		}
+		mask |= v
+		// if f&Read != 0 { s += "|Read" }
+		cb.If().Val(recv).Val(obj).BinaryOp(token.AND).Val(0).BinaryOp(token.NEQ).Then().
+			VarRef(s).Val("|" + obj.Name()).AssignOp(token.ADD_ASSIGN).
+			End()
+	}
+	// if uint64(f)&^mask != 0 { s += "|0x" + strconv.FormatUint(uint64(f)&^mask, 16) }
+	maskLit := &goast.BasicLit{Kind: token.INT, Value: "0x" + strconv.FormatUint(mask, 16)}
+	formatUint := p.p.Import("strconv").Ref("FormatUint")
+	uint64Type := types.Typ[types.Uint64]
+	cb.If().
+		Typ(uint64Type).Val(recv).Call(1).Val(maskLit).BinaryOp(token.AND_NOT).Val(0).BinaryOp(token.NEQ).Then().
+		VarRef(s).Val("|0x").
+		Val(formatUint).Typ(uint64Type).Val(recv).Call(1).Val(maskLit).BinaryOp(token.AND_NOT).Val(16).Call(2).
+		BinaryOp(token.ADD).AssignOp(token.ADD_ASSIGN).
+		End()
+	// if s == "" { return "0" }
+	zeroName := "0"
+	if zero != nil {
+		zeroName = zero.Name()
+	}
+	cb.If().Val(s).Val("").BinaryOp(token.EQL).Then().
+		Val(zeroName).Return(1).
+		End()
+	// return s[1:]
+	cb.Val(s).Val(1).None().Slice(false).Return(1).End()
+	fn.SetComments(p.p, flagEnumDoc(`// String returns the names of the bits set in f, joined by "|".`))
+	return nil
+}
+
+func flagEnumDoc(text string) *goast.CommentGroup {
+	return &goast.CommentGroup{List: []*goast.Comment{{Text: text}}}
+}
@@ -0,0 +1,340 @@
+/*
+This file is used to match the layout of generated structs with the C records,
+a record that can't be matched, like a packed one, is stored in a byte array
+whose fields are accessed by methods:
+
+	struct __attribute__((packed)) Foo { char a; int b; };
+
+	type Foo struct {
+		_ [5]uint8
+	}
+
+	func (recv_ *Foo) B() *c.Int {
+		return (*c.Int)(unsafe.Add(unsafe.Pointer(recv_), 1))
+	}
+
+A named union is stored the same way, its members also get setters:
+
+	union Bar { int i; float f; };
+
+	func (recv_ *Bar) SetF(v c.Float) {
+		*(*c.Float)(unsafe.Pointer(recv_)) = v
+	}
+*/
+package convert
+
+import (
+	"fmt"
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"log"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+)
+
+// recordLayout is the layout of a record that the fields of its Go struct can't
+// express, which is explained in the doc of the type.
+type recordLayout struct {
+	notice  string       // like: is packed in C, ...
+	fields  []*types.Var // fields reached by accessors if the struct is a storage
+	offsets []int64      // byte offsets of the fields
+	members []*ast.Field // C fields of the fields, whose docs are kept by the accessors
+	union   bool         // if the fields also get setters
+}
+
+// layoutStruct builds the struct of a record whose layout is known from clang.
+// Explicit padding is inserted where the C offsets are beyond the Go ones,
+// static members are left out as they have no storage in the record,
+// the Go offsets follow the sizes of the types in Go, whose alignments are less
+// than the C ones for the types aligned to 16, like __int128,
+// and ErrLayout is returned if the result still doesn't match the C layout.
+func (p *TypeConv) layoutStruct(recordType *ast.RecordType, fields []*types.Var) (types.Type, error) {
+	offsets := make([]int64, len(fields))
+	static := make([]bool, len(fields))
+	if recordType.Tag != ast.Union {
+		// the vtable pointer at offset 0 and the embedded base class come before the fields
+		lead := len(fields) - len(recordType.Fields.List)
+		vptrs := lead - len(recordType.Bases)
+		for i, base := range recordType.Bases {
+			offsets[vptrs+i] = base.Offset / 8
+		}
+		for i, field := range recordType.Fields.List {
+			static[lead+i] = field.IsStatic
+			offsets[lead+i] = field.Offset / 8
+		}
+	}
+	for i, field := range fields {
+		if !static[i] && !layoutKnown(field.Type()) {
+			return types.NewStruct(fields, nil), nil
+		}
+	}
+
+	goSizes := p.sizes.Go()
+	var padded []*types.Var
+	var index []int // index of the fields in padded
+	var off, align int64 = 0, 1
+	for i, field := range fields {
+		if static[i] {
+			index = append(index, -1)
+			continue
+		}
+		fieldAlign := goSizes.Alignof(field.Type())
+		if fieldAlign > align {
+			align = fieldAlign
+		}
+		if offsets[i] > alignUp(off, fieldAlign) {
+			padded = append(padded, p.paddingField(offsets[i]-off))
+			off = offsets[i]
+		} else {
+			off = alignUp(off, fieldAlign)
+		}
+		index = append(index, len(padded))
+		padded = append(padded, field)
+		off += goSizes.Sizeof(field.Type())
+	}
+	if alignUp(off, align) != recordType.Size && recordType.Size > off {
+		padded = append(padded, p.paddingField(recordType.Size-off))
+	}
+	// a record aligned more than its fields starts with a zero-size field of its alignment
+	if recordType.Align > align {
+		if typ := p.alignedType(recordType.Align); typ != nil {
+			padded = append([]*types.Var{types.NewVar(token.NoPos, p.types(), "_", types.NewArray(typ, 0))}, padded...)
+			for i := range index {
+				if index[i] >= 0 {
+					index[i]++
+				}
+			}
+		}
+	}
+
+	structType := types.NewStruct(padded, nil)
+	goOffsets := goSizes.Offsetsof(padded)
+	for i, field := range fields {
+		if static[i] {
+			continue
+		}
+		if got := goOffsets[index[i]]; got != offsets[i] {
+			return structType, fmt.Errorf("%w: field %s at offset %d, want %d", ErrLayout, field.Name(), got, offsets[i])
+		}
+	}
+	if size := goSizes.Sizeof(structType); size != recordType.Size {
+		return structType, fmt.Errorf("%w: size %d, want %d", ErrLayout, size, recordType.Size)
+	}
+	return structType, nil
+}
+
+// storable reports whether the fields of a named record can be reached by
+// accessors of a storage struct, that is it has no base and all its fields are known.
+func storable(name string, recordType *ast.RecordType, fields []*types.Var) bool {
+	return name != "" && len(recordType.Bases) == 0 && !recordType.Polymorphic &&
+		recordType.Fields != nil && len(fields) == len(recordType.Fields.List)
+}
+
+// storageStruct builds the struct of a record whose fields Go can't lay out at
+// the C offsets, or of a union, which stores the record in an array of its size
+// and alignment. err is the reason the fields can't be laid out, nil for a union.
+func (p *TypeConv) storageStruct(name string, recordType *ast.RecordType, fields []*types.Var, err error) *types.Struct {
+	elem, n := types.Type(types.Typ[types.Byte]), recordType.Size
+	if typ := p.alignedType(recordType.Align); typ != nil && recordType.Size%recordType.Align == 0 {
+		elem, n = typ, recordType.Size/recordType.Align
+	}
+	structType := types.NewStruct([]*types.Var{types.NewVar(token.NoPos, p.types(), "_", types.NewArray(elem, n))}, nil)
+	layout := &recordLayout{
+		notice:  "has a layout in C that Go can't match, so its fields are accessed by methods.",
+		fields:  fields,
+		members: recordType.Fields.List,
+		union:   recordType.Tag == ast.Union,
+	}
+	switch {
+	case layout.union:
+		layout.notice = "is a union in C, so its members are accessed by methods."
+	case recordType.Packed:
+		layout.notice = "is packed in C, Go can't lay out its fields at the same offsets, so they are accessed by methods."
+	}
+	for _, field := range recordType.Fields.List {
+		layout.offsets = append(layout.offsets, field.Offset/8)
+	}
+	p.pkg.layouts[structType] = layout
+	if err != nil {
+		log.Printf("%s: %v, its fields are accessed by methods\n", name, err)
+	}
+	return structType
+}
+
+// alignedType returns the unsigned integer type of the size and alignment,
+// or nil if there is none.
+func (p *TypeConv) alignedType(align int64) types.Type {
+	for _, kind := range []types.BasicKind{types.Uint8, types.Uint16, types.Uint32, types.Uint64} {
+		typ := types.Typ[kind]
+		if p.sizes.Sizeof(typ) == align && p.sizes.Alignof(typ) == align {
+			return typ
+		}
+	}
+	return nil
+}
+
+func (p *TypeConv) paddingField(size int64) *types.Var {
+	return types.NewVar(token.NoPos, p.types(), "_", types.NewArray(types.Typ[types.Byte], size))
+}
+
+// layoutKnown reports whether the size of a type can be computed,
+// that is it doesn't embed a type whose declaration is not initialized yet.
+func layoutKnown(typ types.Type) bool {
+	switch t := typ.(type) {
+	case *types.Named:
+		if t.Underlying() == nil {
+			return false
+		}
+		return layoutKnown(t.Underlying())
+	case *types.Array:
+		return layoutKnown(t.Elem())
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			if !layoutKnown(t.Field(i).Type()) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// unionLayout returns a copy of a union of no recorded size, sized and aligned
+// by its largest and most aligned members, or nil if the layout of one of them
+// is unknown.
+func (p *TypeConv) unionLayout(recordType *ast.RecordType, fields []*types.Var) *ast.RecordType {
+	var size, align int64 = 0, 1
+	for _, field := range fields {
+		if !layoutKnown(field.Type()) {
+			return nil
+		}
+		if s := p.sizes.Sizeof(field.Type()); s > size {
+			size = s
+		}
+		if a := p.sizes.Alignof(field.Type()); a > align {
+			align = a
+		}
+	}
+	union := *recordType
+	union.Size, union.Align = alignUp(size, align), align
+	return &union
+}
+
+func alignUp(n, align int64) int64 {
+	return (n + align - 1) / align * align
+}
+
+// newRecordAccessors generates the accessors of a record, and returns its fields
+// that are reached directly and the members that are reached by accessors.
+// A record with a layout notice gets it appended to the doc of its type.
+func (p *Package) newRecordAccessors(named *types.Named, fields []*types.Var, union bool) ([]*types.Var, []*types.Var) {
+	layout := p.layouts[named.Underlying()]
+	if layout == nil {
+		return fields, p.newAnonMemberAccessors(named, fields, union)
+	}
+	if doc := p.typeDocs[named]; doc != nil {
+		if len(doc.List) > 0 {
+			doc.List = append(doc.List, &goast.Comment{Text: "//"})
+		}
+		doc.List = append(doc.List, &goast.Comment{Text: "// " + named.Obj().Name() + " " + layout.notice})
+	}
+	if layout.fields == nil {
+		return fields, p.newAnonMemberAccessors(named, fields, union)
+	}
+	var accessed []*types.Var
+	for i, field := range layout.fields {
+		member := layout.members[i]
+		if member.IsStatic {
+			continue
+		}
+		doc := p.memberDoc(member, field.Name())
+		if p.newFieldAccessor(named, field, layout.offsets[i], doc) {
+			accessed = append(accessed, field)
+		}
+		if layout.union {
+			p.newFieldSetter(named, field, layout.offsets[i], doc)
+		}
+	}
+	if layout.union {
+		// the members of an anonymous member are at the start of a union too
+		accessed = append(accessed, p.newAnonMemberAccessors(named, layout.fields, true)...)
+	}
+	return nil, accessed
+}
+
+// memberDoc returns the doc of the accessors of a member, which is the doc and
+// the line comment of the member in C.
+func (p *Package) memberDoc(member *ast.Field, goName string) *goast.CommentGroup {
+	doc := p.docComments(member.Doc, goName, nil)
+	if member.Comment != nil {
+		doc.AddCommentGroup(CommentGroup(member.Comment).CommentGroup)
+	}
+	return doc.CommentGroup
+}
+
+// newFieldAccessor generates the accessor of a field at the byte offset of a
+// storage struct, like:
+//
+//	func (recv_ *Foo) B() *c.Int {
+//		return (*c.Int)(unsafe.Add(unsafe.Pointer(recv_), 1))
+//	}
+func (p *Package) newFieldAccessor(named *types.Named, field *types.Var, offset int64, doc *goast.CommentGroup) bool {
+	name := field.Name()
+	recv := p.p.NewParam(token.NoPos, "recv_", types.NewPointer(named))
+	if name == "" || name == "_" || p.funcDefined(recv, name) {
+		if dbg.GetDebugLog() {
+			log.Printf("newFieldAccessor: %s.%s is already defined\n", named.Obj().Name(), name)
+		}
+		return false
+	}
+	ret := types.NewPointer(field.Type())
+	results := types.NewTuple(p.p.NewParam(token.NoPos, "", ret))
+	sig := types.NewSignatureType(recv, nil, nil, nil, results, false)
+	fn := p.p.NewFuncDecl(token.NoPos, name, sig)
+	if len(doc.List) > 0 {
+		fn.SetComments(p.p, doc)
+	}
+	cb := fn.BodyStart(p.p)
+	cb.Typ(ret)
+	p.pushFieldPointer(cb, recv, offset)
+	cb.Call(1).Return(1).End()
+	return true
+}
+
+// newFieldSetter generates the setter of a member at the byte offset of a union, like:
+//
+//	func (recv_ *Bar) SetF(v c.Float) {
+//		*(*c.Float)(unsafe.Pointer(recv_)) = v
+//	}
+func (p *Package) newFieldSetter(named *types.Named, field *types.Var, offset int64, doc *goast.CommentGroup) {
+	name := "Set" + field.Name()
+	recv := p.p.NewParam(token.NoPos, "recv_", types.NewPointer(named))
+	if field.Name() == "" || field.Name() == "_" || p.funcDefined(recv, name) {
+		if dbg.GetDebugLog() {
+			log.Printf("newFieldSetter: %s.%s is already defined\n", named.Obj().Name(), name)
+		}
+		return
+	}
+	v := p.p.NewParam(token.NoPos, "v", field.Type())
+	sig := types.NewSignatureType(recv, nil, nil, types.NewTuple(v), nil, false)
+	fn := p.p.NewFuncDecl(token.NoPos, name, sig)
+	if len(doc.List) > 0 {
+		fn.SetComments(p.p, doc)
+	}
+	cb := fn.BodyStart(p.p)
+	cb.Typ(types.NewPointer(field.Type()))
+	p.pushFieldPointer(cb, recv, offset)
+	cb.Call(1).ElemRef().Val(v).Assign(1).End()
+}
+
+// pushFieldPointer pushes the unsafe.Pointer to the byte offset of the receiver.
+func (p *Package) pushFieldPointer(cb *gogen.CodeBuilder, recv *types.Var, offset int64) {
+	unsafePointer := types.Typ[types.UnsafePointer]
+	if offset == 0 {
+		cb.Typ(unsafePointer).Val(recv).Call(1)
+	} else {
+		cb.Val(p.p.Unsafe().Ref("Add")).Typ(unsafePointer).Val(recv).Call(1).Val(int(offset)).Call(2)
+	}
+}
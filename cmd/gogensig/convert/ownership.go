@@ -0,0 +1,90 @@
+/*
+This file is used to pair the functions allocating and releasing memory, by their ownership attributes
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/types"
+
+	"github.com/goplus/gogen"
+	"github.com/goplus/llcppg/ast"
+)
+
+// ownerFunc is a generated function that allocates or releases the memory of
+// an ownership module.
+type ownerFunc struct {
+	decl *gogen.Func
+	doc  *goast.CommentGroup
+	link string // doc link of the function, like [FooNew] or [Foo.Free]
+}
+
+// ownership is the pair of functions of an ownership module, like:
+//
+//	Foo *foo_new(void) __attribute__((ownership_returns(foo)));
+//	void foo_free(Foo *) __attribute__((ownership_takes(foo, 1)));
+type ownership struct {
+	alloc *ownerFunc
+	free  *ownerFunc
+}
+
+// pairOwnership records a function by the modules of its ownership attributes,
+// and links the docs of the functions allocating and releasing the memory of
+// a module to each other. The first function of each kind is paired.
+func (p *Package) pairOwnership(attrs []*ast.Attr, fn *ownerFunc) {
+	for _, attr := range attrs {
+		if len(attr.Args) == 0 {
+			continue
+		}
+		module := attr.Args[0]
+		pair := p.ownerships[module]
+		if pair == nil {
+			pair = &ownership{}
+			p.ownerships[module] = pair
+		}
+		switch attr.Kind {
+		case ast.OwnershipReturnsAttr:
+			if pair.alloc != nil {
+				continue
+			}
+			pair.alloc = fn
+		case ast.OwnershipTakesAttr:
+			if pair.free != nil {
+				continue
+			}
+			pair.free = fn
+		default:
+			continue
+		}
+		if pair.alloc != nil && pair.free != nil {
+			pair.alloc.addDoc(p, "// The result must be released with "+pair.free.link+".")
+			pair.free.addDoc(p, "// It releases the memory returned by "+pair.alloc.link+".")
+		}
+	}
+}
+
+// addDoc adds a paragraph to the doc of the function, before its link directive.
+func (fn *ownerFunc) addDoc(p *Package, text string) {
+	list := fn.doc.List
+	link := list[len(list)-1]
+	body := list[:len(list)-1]
+	if n := len(body); n > 0 && body[n-1].Text == "//" {
+		body = body[:n-1]
+	}
+	if len(body) > 0 {
+		body = append(body, &goast.Comment{Text: "//"})
+	}
+	body = append(body, &goast.Comment{Text: text}, &goast.Comment{Text: "//"}, link)
+	fn.doc.List = body
+	fn.decl.SetComments(p.p, fn.doc)
+}
+
+// docLinkName returns the doc link of a generated function, like [FooNew] or [Foo.Free].
+func docLinkName(sig *types.Signature, fnSpec *GoFuncSpec) string {
+	if sig.Recv() != nil {
+		if named := getNamedType(sig.Recv().Type()); named != nil {
+			return "[" + named.Obj().Name() + "." + fnSpec.FnName + "]"
+		}
+	}
+	return "[" + fnSpec.FnName + "]"
+}
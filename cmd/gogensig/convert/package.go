@@ -15,6 +15,7 @@ import (
 	"github.com/goplus/llcppg/_xtool/llcppsymg/names"
 	"github.com/goplus/llcppg/ast"
 	"github.com/goplus/llcppg/cmd/gogensig/config"
+	"github.com/goplus/llcppg/cmd/gogensig/convert/sizes"
 	"github.com/goplus/llcppg/cmd/gogensig/dbg"
 	"github.com/goplus/llcppg/cmd/gogensig/errs"
 	"github.com/goplus/llcppg/llcppg"
@@ -50,6 +51,17 @@ type Package struct {
 	// type definitions are available.
 	incompleteTypes *IncompleteTypes
 
+	anonRecords map[*types.Named]*anonRecord         // named types of nested anonymous records
+	layouts     map[types.Type]*recordLayout         // structs of records whose layout has a notice
+	typeDocs    map[*types.Named]*goast.CommentGroup // docs of the type declarations, to add notices
+	polyClasses map[*types.Named]*polyClass          // named types of polymorphic C++ classes
+	ownerships  map[string]*ownership                // paired functions by ownership module
+
+	extBuiltins  map[string]*types.Named // opaque builtin types declared in the package, by Go name
+	byValueTypes map[types.Type]string   // types that can't be passed by value, to their C spelling
+
+	layoutChecks []*layoutCheck // generated types whose layout is verified by VerifyLayout
+
 	nameMapper *names.NameMapper // handles name mapping and uniqueness
 }
 
@@ -68,6 +80,13 @@ func NewPackage(config *PackageConfig) *Package {
 		p:               gogen.NewPackage(config.PkgPath, config.Name, config.GenConf),
 		conf:            config,
 		incompleteTypes: NewIncompleteTypes(),
+		anonRecords:     make(map[*types.Named]*anonRecord),
+		layouts:         make(map[types.Type]*recordLayout),
+		typeDocs:        make(map[*types.Named]*goast.CommentGroup),
+		polyClasses:     make(map[*types.Named]*polyClass),
+		ownerships:      make(map[string]*ownership),
+		extBuiltins:     make(map[string]*types.Named),
+		byValueTypes:    make(map[types.Type]string),
 		locMap:          NewThirdTypeLoc(),
 		nameMapper:      names.NewNameMapper(),
 	}
@@ -89,6 +108,13 @@ func NewPackage(config *PackageConfig) *Package {
 		log.Panicf("failed to load mod: %s", err.Error())
 	}
 
+	// the sizes of the generated types are the ones of the target
+	target := config.CppgConf.Target
+	targetSizes, err := sizes.For(target.Goos(), target.Goarch())
+	if err != nil {
+		log.Panicf("failed to set target: %s", err.Error())
+	}
+
 	p.PkgInfo = NewPkgInfo(config.PkgPath, config.OutputDir, config.CppgConf, config.Pubs)
 	for name, goName := range config.Pubs {
 		p.nameMapper.SetMapping(name, goName)
@@ -100,7 +126,7 @@ func NewPackage(config *PackageConfig) *Package {
 		log.Panicf("failed to init deps: %s", err.Error())
 	}
 
-	p.cvt = NewConv(p)
+	p.cvt = NewConv(p, targetSizes)
 	return p
 }
 
@@ -194,7 +220,9 @@ func (p *Package) bodyStart(decl *gogen.Func, ret ast.Expr) error {
 func (p *Package) handleFuncDecl(fnSpec *GoFuncSpec, sig *types.Signature, funcDecl *ast.FuncDecl) error {
 	var decl *gogen.Func
 	fnPubName := fnSpec.GoSymbName
+	goName := fnPubName
 	if fnSpec.IsMethod {
+		goName = fnSpec.FnName
 		decl = p.p.NewFuncDecl(token.NoPos, fnSpec.FnName, sig)
 		err := p.bodyStart(decl, funcDecl.Type.Ret)
 		if err != nil {
@@ -207,9 +235,18 @@ func (p *Package) handleFuncDecl(fnSpec *GoFuncSpec, sig *types.Signature, funcD
 		decl = p.p.NewFuncDecl(token.NoPos, fnPubName, sig)
 	}
 
-	doc := CommentGroup(funcDecl.Doc)
+	doc := p.docComments(funcDecl.Doc, goName, funcDocNames(goName, sig, funcDecl))
+	attrDoc := NewAttrDocComments(p.docAttrs(funcDecl.Doc, funcDecl.Attrs), len(doc.List) > 0)
+	nilDoc := NewNilDocComments(sig, funcDecl, len(doc.List) > 0 || len(attrDoc.List) > 0)
+	attrDoc.List = append(attrDoc.List, nilDoc.List...)
+	if len(attrDoc.List) > 0 {
+		// keep the link directive out of the Deprecated paragraph
+		attrDoc.List = append(attrDoc.List, &goast.Comment{Text: "//"})
+		doc.AddCommentGroup(attrDoc)
+	}
 	doc.AddCommentGroup(NewFuncDocComments(funcDecl.Name.Name, fnPubName))
 	decl.SetComments(p.p, doc.CommentGroup)
+	p.pairOwnership(funcDecl.Attrs, &ownerFunc{decl: decl, doc: doc.CommentGroup, link: docLinkName(sig, fnSpec)})
 	return nil
 }
 
@@ -261,6 +298,12 @@ func (p *Package) NewFuncDecl(funcDecl *ast.FuncDecl) error {
 	if anony {
 		return errs.NewAnonymousFuncNotSupportError()
 	}
+	if hasAttr(funcDecl.Attrs, ast.UnavailableAttr) {
+		if dbg.GetDebugLog() {
+			log.Printf("NewFuncDecl: %v is unavailable\n", funcDecl.Name)
+		}
+		return nil
+	}
 
 	fnSpec, err := p.LookupSymbol(funcDecl.MangledName)
 	if err != nil {
@@ -277,7 +320,25 @@ func (p *Package) NewFuncDecl(funcDecl *ast.FuncDecl) error {
 	if err != nil {
 		return err
 	}
-	return p.handleFuncDecl(fnSpec, sig, funcDecl)
+	if ctype := p.byValueType(sig); ctype != "" {
+		return errs.NewUnsupportedByValueError(funcDecl.Name.Name, ctype)
+	}
+	if _, ok := names.Operator(funcDecl.Name.Name); ok || p.isCppLinkage(funcDecl) {
+		// an operator, or a function of C++ linkage, can only be linked by its mangled name
+		link := *funcDecl
+		link.Name = &ast.Ident{Name: funcDecl.MangledName}
+		funcDecl = &link
+	}
+	if err := p.handleFuncDecl(fnSpec, sig, funcDecl); err != nil {
+		return err
+	}
+	return p.newDefaultArgWrapper(fnSpec, sig, funcDecl)
+}
+
+// isCppLinkage reports whether a function of a C++ package has C++ language
+// linkage, so its symbol is mangled, unlike a function declared in an extern "C" block.
+func (p *Package) isCppLinkage(funcDecl *ast.FuncDecl) bool {
+	return p.CppgConf.Cplusplus && !funcDecl.IsExternC && funcDecl.MangledName != funcDecl.Name.Name
 }
 
 func (p *Package) funcIsDefined(fnSpec *GoFuncSpec, funcDecl *ast.FuncDecl) (recv *types.Var, err error) {
@@ -305,7 +366,8 @@ func (p *Package) funcIsDefined(fnSpec *GoFuncSpec, funcDecl *ast.FuncDecl) (rec
 // - Forward declarations: Pre-registers incomplete types for later definition
 // - Self-referential types: Handles types that reference themselves (like linked lists)
 func (p *Package) NewTypeDecl(typeDecl *ast.TypeDecl) error {
-	skip, anony := p.handleType(typeDecl.Name, typeDecl.Loc)
+	ident := qualifiedIdent(typeDecl.Parent, typeDecl.Name)
+	skip, anony := p.handleType(ident, typeDecl.Loc)
 	if skip {
 		if dbg.GetDebugLog() {
 			log.Printf("NewTypeDecl: %s type of third header\n", typeDecl.Name)
@@ -322,7 +384,7 @@ func (p *Package) NewTypeDecl(typeDecl *ast.TypeDecl) error {
 		return nil
 	}
 
-	cname := typeDecl.Name.Name
+	cname := ident.Name
 	isForward := p.cvt.inComplete(typeDecl.Type)
 	name, changed, err := p.DeclName(cname, true)
 	if err != nil {
@@ -340,19 +402,109 @@ func (p *Package) NewTypeDecl(typeDecl *ast.TypeDecl) error {
 	}
 
 	if !isForward {
-		if err := p.handleCompleteType(incom, typeDecl.Type, cname); err != nil {
+		fields, err := p.handleCompleteType(incom, typeDecl.Type, cname)
+		if err != nil {
+			return err
+		}
+		p.addLayoutCheck(cTypeName(typeDecl.Type.Tag, cname), incom.decl.Type(), typeDecl.Type, fields)
+		return p.newClassMethods(incom.decl.Type(), typeDecl.Type)
+	}
+	return nil
+}
+
+// NewInstantiationDecl converts a class template instantiation, like Foo<int>,
+// to a concrete Go type named after its spelling, like FooInt.
+// The instantiated methods are generated as methods of the pointer receiver.
+func (p *Package) NewInstantiationDecl(instDecl *ast.InstantiationDecl) error {
+	skip, _ := p.handleType(instDecl.Name, instDecl.Loc)
+	if skip {
+		if dbg.GetDebugLog() {
+			log.Printf("NewInstantiationDecl: %s instantiation of third header\n", instDecl.Name)
+		}
+		return nil
+	}
+	if dbg.GetDebugLog() {
+		log.Printf("NewInstantiationDecl: %v\n", instDecl.Name)
+	}
+
+	// the spelling of a type in the template arguments depends on how it is written,
+	// so the instantiation is identified by its template and arguments
+	cname := instKey(instDecl.Template)
+	incom, exists := p.incompleteTypes.Lookup(cname)
+	if !exists {
+		if obj := p.p.Types.Scope().Lookup(cname); obj != nil {
+			return errs.NewTypeDefinedError(obj.Name(), cname)
+		}
+		name, _, err := p.DeclName(instDecl.Name.Name, true)
+		if err != nil {
+			return err
+		}
+		incom = &Incomplete{
+			cname: cname,
+			file:  p.curFile,
+			decl:  p.emptyTypeDecl(name, &instDecl.DeclBase),
+			getType: func() (types.Type, error) {
+				return types.NewStruct(p.cvt.defaultRecordField(), nil), nil
+			},
+		}
+		p.incompleteTypes.Add(incom)
+	}
+	obj := incom.decl.Type().Obj()
+	p.CollectNameMapping(cname, obj.Name())
+	substObj(p.p.Types, p.p.Types.Scope(), cname, obj)
+
+	if !p.cvt.inComplete(instDecl.Type) {
+		if _, err := p.handleCompleteType(incom, instDecl.Type, cname); err != nil {
 			return err
 		}
 	}
+
+	recvType := types.NewPointer(incom.decl.Type())
+	for _, method := range instDecl.Type.Methods {
+		if err := p.newMethod(recvType, method); err != nil {
+			log.Printf("ConvertFuncDecl %s::%s Fail: %s", instDecl.Name.Name, method.Name.Name, err.Error())
+		}
+	}
 	return nil
 }
 
+// newMethod generates a method declared in a class or a class template instantiation,
+// the parameters of a method declared in the class don't contain the receiver.
+func (p *Package) newMethod(recvType types.Type, method *ast.FuncDecl) error {
+	if method.IsStatic {
+		return fmt.Errorf("static member %s is not supported", method.Name.Name)
+	}
+	fnSpec, err := p.LookupSymbol(method.MangledName)
+	if err != nil {
+		return err
+	}
+	if !fnSpec.IsMethod {
+		return fmt.Errorf("%s is not a method", fnSpec.GoSymbName)
+	}
+	named := getNamedType(recvType)
+	for i := 0; i < named.NumMethods(); i++ {
+		if named.Method(i).Name() == fnSpec.FnName {
+			return errs.NewFuncAlreadyDefinedError(fnSpec.GoSymbName)
+		}
+	}
+	sig, err := p.cvt.ToSignature(method.Type, nil)
+	if err != nil {
+		return err
+	}
+	recv := p.p.NewParam(token.NoPos, "recv_", recvType)
+	sig = types.NewSignatureType(recv, nil, nil, sig.Params(), sig.Results(), sig.Variadic())
+	// a member declared in the class can only be linked by its mangled name
+	link := *method
+	link.Name = &ast.Ident{Name: method.MangledName}
+	return p.handleFuncDecl(fnSpec, sig, &link)
+}
+
 // handleTypeDecl creates a new type declaration or retrieves existing one
 func (p *Package) handleTypeDecl(pubname string, cname string, typeDecl *ast.TypeDecl) *Incomplete {
 	if existDecl, exists := p.incompleteTypes.Lookup(cname); exists {
 		return existDecl
 	}
-	decl := p.emptyTypeDecl(pubname, typeDecl.Doc)
+	decl := p.emptyTypeDecl(pubname, &typeDecl.DeclBase)
 	inc := &Incomplete{
 		cname: cname,
 		file:  p.curFile,
@@ -365,19 +517,22 @@ func (p *Package) handleTypeDecl(pubname string, cname string, typeDecl *ast.Typ
 	return inc
 }
 
-func (p *Package) handleCompleteType(incom *Incomplete, typ *ast.RecordType, name string) error {
+// handleCompleteType defines the type of a record, and returns the fields of its members.
+func (p *Package) handleCompleteType(incom *Incomplete, typ *ast.RecordType, name string) ([]*types.Var, error) {
 	// defer delete(p.incomplete, name)
 	defer p.incompleteTypes.Complete(name)
 	defer p.SetCurFile(p.curFile)
 	p.SetCurFile(incom.file)
-	structType, err := p.cvt.RecordTypeToStruct(typ)
+	named := incom.decl.Type()
+	structType, fields, err := p.cvt.recordToStruct(named.Obj().Name(), typ)
 	if err != nil {
 		// For incomplete type's conerter error, we use default struct type
 		incom.decl.InitType(p.p, types.NewStruct(p.cvt.defaultRecordField(), nil))
-		return err
+		return nil, err
 	}
 	incom.decl.InitType(p.p, structType)
-	return nil
+	p.newRecordAccessors(named, fields, typ.Tag == ast.Union)
+	return fields, nil
 }
 
 // handleImplicitForwardDecl handles type references that cannot be found in the current scope.
@@ -388,7 +543,7 @@ func (p *Package) handleImplicitForwardDecl(name string) *gogen.TypeDecl {
 		return decl.decl
 	}
 
-	pubName, _ := p.nameMapper.GetUniqueGoName(name, p.trimPrefixes(), true)
+	pubName, _ := p.uniqueGoName(name, true)
 	decl := p.emptyTypeDecl(pubName, nil)
 	inc := &Incomplete{
 		cname: name,
@@ -403,14 +558,22 @@ func (p *Package) handleImplicitForwardDecl(name string) *gogen.TypeDecl {
 	return decl
 }
 
-func (p *Package) emptyTypeDecl(name string, doc *ast.CommentGroup) *gogen.TypeDecl {
+func (p *Package) emptyTypeDecl(name string, base *ast.DeclBase) *gogen.TypeDecl {
 	typeBlock := p.p.NewTypeDefs()
-	typeBlock.SetComments(CommentGroup(doc).CommentGroup)
-	return typeBlock.NewType(name)
+	doc := CommentGroup(nil)
+	if base != nil {
+		doc = p.docComments(base.Doc, name, nil)
+		doc.AddCommentGroup(NewAttrDocComments(p.docAttrs(base.Doc, base.Attrs), len(doc.List) > 0))
+	}
+	typeBlock.SetComments(doc.CommentGroup)
+	decl := typeBlock.NewType(name)
+	p.typeDocs[decl.Type()] = doc.CommentGroup
+	return decl
 }
 
 func (p *Package) NewTypedefDecl(typedefDecl *ast.TypedefDecl) error {
-	skip, _ := p.handleType(typedefDecl.Name, typedefDecl.Loc)
+	ident := qualifiedIdent(typedefDecl.Parent, typedefDecl.Name)
+	skip, _ := p.handleType(ident, typedefDecl.Loc)
 	if skip {
 		if dbg.GetDebugLog() {
 			log.Printf("NewTypedefDecl: %v is a typedef of third header file\n", typedefDecl.Name)
@@ -420,20 +583,26 @@ func (p *Package) NewTypedefDecl(typedefDecl *ast.TypedefDecl) error {
 	if dbg.GetDebugLog() {
 		log.Printf("NewTypedefDecl: %v\n", typedefDecl.Name)
 	}
-	name, changed, err := p.DeclName(typedefDecl.Name.Name, true)
+	name, changed, err := p.DeclName(ident.Name, true)
 	if err != nil {
 		return err
 	}
-	p.CollectNameMapping(typedefDecl.Name.Name, name)
+	p.CollectNameMapping(ident.Name, name)
 
 	genDecl := p.p.NewTypeDefs()
 	typeSpecdecl := genDecl.NewType(name)
+	doc := &goast.CommentGroup{}
+	if hasAttr(typedefDecl.Attrs, ast.DeprecatedAttr) {
+		doc = NewAttrDocComments(typedefDecl.Attrs, false)
+	}
+	genDecl.SetComments(doc)
+	p.typeDocs[typeSpecdecl.Type()] = doc
 
 	if changed {
-		substObj(p.p.Types, p.p.Types.Scope(), typedefDecl.Name.Name, typeSpecdecl.Type().Obj())
+		substObj(p.p.Types, p.p.Types.Scope(), ident.Name, typeSpecdecl.Type().Obj())
 	}
 
-	deferInit := p.handleTyperefIncomplete(typedefDecl.Type, typeSpecdecl, typedefDecl.Name.Name)
+	deferInit := p.handleTyperefIncomplete(typedefDecl.Type, typeSpecdecl, ident.Name)
 	if deferInit {
 		if dbg.GetDebugLog() {
 			log.Printf("NewTypedefDecl: %s defer init\n", name)
@@ -441,15 +610,34 @@ func (p *Package) NewTypedefDecl(typedefDecl *ast.TypedefDecl) error {
 		return nil
 	}
 
-	typ, err := p.ToType(typedefDecl.Type)
+	var typ types.Type
+	var fields []*types.Var
+	recordType, isRecord := typedefDecl.Type.(*ast.RecordType)
+	if isRecord {
+		typ, fields, err = p.cvt.recordToStruct(name, recordType)
+	} else {
+		typ, err = p.ToType(typedefDecl.Type)
+	}
 	if err != nil {
 		typeSpecdecl.InitType(p.p, types.NewStruct(p.cvt.defaultRecordField(), nil))
 		return err
 	}
 
 	typeSpecdecl.InitType(p.p, typ)
+	if ctype, ok := p.byValueTypes[typ]; ok {
+		p.byValueTypes[typeSpecdecl.Type()] = ctype
+	}
+	if isRecord {
+		p.newRecordAccessors(typeSpecdecl.Type(), fields, recordType.Tag == ast.Union)
+		p.addLayoutCheck(ident.Name, typeSpecdecl.Type(), recordType, fields)
+	}
 	if _, ok := typ.(*types.Signature); ok {
-		genDecl.SetComments(NewTypecDocComments())
+		doc := NewAttrDocComments(typedefDecl.Attrs, false)
+		if len(doc.List) > 0 {
+			doc.List = append(doc.List, &goast.Comment{Text: "//"})
+		}
+		doc.List = append(doc.List, NewTypecDocComments().List...)
+		genDecl.SetComments(doc)
 	}
 
 	return nil
@@ -457,15 +645,9 @@ func (p *Package) NewTypedefDecl(typedefDecl *ast.TypedefDecl) error {
 
 func (p *Package) handleTyperefIncomplete(typeRef ast.Expr, typeSpecdecl *gogen.TypeDecl, namedName string) bool {
 	var name string
-	switch expr := typeRef.(type) {
-	case *ast.TagExpr:
-		if n, ok := expr.Name.(*ast.Ident); ok {
-			name = n.Name
-		} else {
-			panic("todo:scoping expr not supported")
-		}
-	case *ast.Ident:
-		name = expr.Name
+	switch typeRef.(type) {
+	case *ast.TagExpr, *ast.Ident, *ast.ScopingExpr, *ast.InstantiationType:
+		name = typeKey(typeRef)
 	default:
 		return false
 	}
@@ -501,7 +683,8 @@ func (p *Package) NewTypedefs(name string, typ types.Type) *gogen.TypeDecl {
 }
 
 func (p *Package) NewEnumTypeDecl(enumTypeDecl *ast.EnumTypeDecl) error {
-	skip, _ := p.handleType(enumTypeDecl.Name, enumTypeDecl.Loc)
+	ident := qualifiedIdent(enumTypeDecl.Parent, enumTypeDecl.Name)
+	skip, anony := p.handleType(ident, enumTypeDecl.Loc)
 	if skip {
 		if dbg.GetDebugLog() {
 			log.Printf("NewEnumTypeDecl: %v is a enum type of system header file\n", enumTypeDecl.Name)
@@ -511,20 +694,42 @@ func (p *Package) NewEnumTypeDecl(enumTypeDecl *ast.EnumTypeDecl) error {
 	if dbg.GetDebugLog() {
 		log.Printf("NewEnumTypeDecl: %v\n", enumTypeDecl.Name)
 	}
-	enumType, err := p.createEnumType(enumTypeDecl.Name)
+	enumName := ident
+	if anony {
+		enumName = p.anonEnumName(enumTypeDecl.Parent, enumTypeDecl.Type.Items)
+	}
+	enumType, err := p.createEnumType(enumName, enumTypeDecl.Type, !anony)
 	if err != nil {
 		return err
 	}
+	if named, ok := enumType.(*types.Named); ok && !anony && !forwardEnum(enumTypeDecl.Type) {
+		p.addLayoutCheck(cTypeName(ast.Enum, ident.Name), named, nil, nil)
+	}
 	if len(enumTypeDecl.Type.Items) > 0 {
-		err = p.createEnumItems(enumTypeDecl.Type.Items, enumType)
+		scope, scopeName := enumTypeDecl.Parent, ""
+		if named, ok := enumType.(*types.Named); ok && enumTypeDecl.Type.Scoped {
+			scope = &ast.ScopingExpr{Parent: enumTypeDecl.Parent, X: enumTypeDecl.Name}
+			scopeName = named.Obj().Name()
+		}
+		prefix := p.trimmedEnumPrefix(scope, enumTypeDecl.Type.Items)
+		consts, err := p.createEnumItems(scope, scopeName, enumTypeDecl.Type.Items, enumType, prefix)
 		if err != nil {
 			return err
 		}
+		named, ok := enumType.(*types.Named)
+		if ok && isFlagEnum(enumTypeDecl.Attrs, consts) {
+			return p.newFlagEnumMethods(named, consts)
+		}
+		if ok && p.CppgConf.EnumString {
+			return p.newEnumString(named, consts)
+		}
 	}
 	return nil
 }
 
-func (p *Package) createEnumType(enumName *ast.Ident) (types.Type, error) {
+// createEnumType declares the type of an enum, the name of an anonymous enum
+// named by its items is not collected, as it's no c type.
+func (p *Package) createEnumType(enumName *ast.Ident, typ *ast.EnumType, collect bool) (types.Type, error) {
 	var name string
 	var changed bool
 	var err error
@@ -534,9 +739,14 @@ func (p *Package) createEnumType(enumName *ast.Ident) (types.Type, error) {
 		if err != nil {
 			return nil, errs.NewTypeDefinedError(name, enumName.Name)
 		}
-		p.CollectNameMapping(enumName.Name, name)
+		if collect {
+			p.CollectNameMapping(enumName.Name, name)
+		}
+	}
+	enumType, err := p.cvt.ToEnumType(typ)
+	if err != nil {
+		return nil, err
 	}
-	enumType := p.cvt.ToDefaultEnumType()
 	if name != "" {
 		t = p.NewTypedefs(name, enumType)
 		enumType = p.p.Types.Scope().Lookup(name).Type()
@@ -547,25 +757,76 @@ func (p *Package) createEnumType(enumName *ast.Ident) (types.Type, error) {
 	return enumType, nil
 }
 
-func (p *Package) createEnumItems(items []*ast.EnumItem, enumType types.Type) error {
+// the items of an unscoped enum are declared in the scope of the enum, the items
+// of a scoped enum are qualified by scopeName, the Go name of the enum,like:
+// enum class Color { None }; -> ColorNone, without the prefix if it's not empty
+func (p *Package) createEnumItems(scope ast.Expr, scopeName string, items []*ast.EnumItem, enumType types.Type, prefix string) ([]*types.Const, error) {
 	defs := p.NewConstGroup()
+	var names []string
 	for _, item := range items {
-		name, changed, err := p.DeclName(item.Name.Name, true)
+		ident := qualifiedIdent(scope, item.Name)
+		declName := ident.Name
+		if prefix != "" {
+			declName = trimmedItemName(scope, item, prefix)
+		}
+		var name string
+		var changed bool
+		var err error
+		if scopeName != "" {
+			name, err = p.scopedItemName(scopeName, declName, item.Name.Name[len(prefix):])
+			changed = true
+		} else {
+			name, changed, err = p.DeclName(declName, true)
+		}
 		if err != nil {
-			return errs.NewTypeDefinedError(name, item.Name.Name)
+			return nil, errs.NewTypeDefinedError(name, declName)
 		}
-		val, err := Expr(item.Value).ToInt()
+		changed = changed || declName != ident.Name
+		val, err := enumItemValue(item.Value)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		defs.New(val, enumType, name)
 		if changed {
 			if obj := p.p.Types.Scope().Lookup(name); obj != nil {
-				substObj(p.p.Types, p.p.Types.Scope(), item.Name.Name, obj)
+				substObj(p.p.Types, p.p.Types.Scope(), ident.Name, obj)
 			}
 		}
+		names = append(names, name)
 	}
-	return nil
+	var consts []*types.Const
+	for _, name := range names {
+		if obj, ok := p.p.Types.Scope().Lookup(name).(*types.Const); ok {
+			consts = append(consts, obj)
+		}
+	}
+	return consts, nil
+}
+
+// scopedItemName returns the Go name of the item of a scoped enum, whose c name
+// is cname, it's qualified by the Go name of the enum instead of the scopes of
+// cname, then the items of the same name in different enums don't collide.
+func (p *Package) scopedItemName(enumName, cname, item string) (string, error) {
+	if p.p.Types.Scope().Lookup(cname) != nil {
+		return "", errs.NewTypeDefinedError(enumName, cname)
+	}
+	name, _ := p.nameMapper.GetUniqueGoName(enumName+"_"+item, nil, true)
+	return name, nil
+}
+
+// enumItemValue returns the value of an enum item, which can be beyond int64
+// for an enum of unsigned long long.
+func enumItemValue(value ast.Expr) (any, error) {
+	val, err := Expr(value).ToInt()
+	if err == nil {
+		return val, nil
+	}
+	if lit, ok := value.(*ast.BasicLit); ok && lit.Kind == ast.IntLit {
+		if _, uerr := litToUint(lit.Value); uerr == nil {
+			return &goast.BasicLit{Kind: token.INT, Value: lit.Value}, nil
+		}
+	}
+	return nil, err
 }
 
 func (p *Package) NewMacro(macro *ast.Macro) error {
@@ -639,7 +900,10 @@ func (p *Package) WritePkgFiles() error {
 		}
 	}
 
-	return p.WriteAutogenFile()
+	if err := p.WriteAutogenFile(); err != nil {
+		return err
+	}
+	return p.WriteBuiltinFile()
 }
 
 // Write generates a Go file based on the package content.
@@ -727,7 +991,7 @@ func (p *Package) WritePubFile() error {
 
 // For a decl name, it should be unique
 func (p *Package) DeclName(name string, toCamel bool) (pubName string, changed bool, err error) {
-	pubName, changed = p.nameMapper.GetUniqueGoName(name, p.trimPrefixes(), toCamel)
+	pubName, changed = p.uniqueGoName(name, toCamel)
 	// if the type is incomplete,it's ok to have the same name
 	obj := p.p.Types.Scope().Lookup(name)
 	_, ok := p.incompleteTypes.Lookup(name)
@@ -737,6 +1001,37 @@ func (p *Package) DeclName(name string, toCamel bool) (pubName string, changed b
 	return pubName, changed, nil
 }
 
+// uniqueGoName returns a unique Go name for a c name,
+// a C++ qualified name is named by the namespaceNaming of llcppg.cfg.
+func (p *Package) uniqueGoName(name string, toCamel bool) (string, bool) {
+	if toCamel && p.CppgConf.NamespaceNaming == llcppg.NamespaceDrop {
+		return p.nameMapper.GetUniqueUnscopedGoName(name, p.trimPrefixes())
+	}
+	return p.nameMapper.GetUniqueGoName(name, p.trimPrefixes(), toCamel)
+}
+
+// ReserveNames reserves the qualified c names declared in the current file, like
+// ns::Widget, before any is declared, then with the drop namespaceNaming of
+// llcppg.cfg, the Go names of the C++ qualified names that are the same without
+// their scopes keep the scopes, whatever order they are declared in.
+func (p *Package) ReserveNames(names []string) {
+	if p.CppgConf.NamespaceNaming != llcppg.NamespaceDrop || !p.curFile.InCurPkg() {
+		return
+	}
+	for _, name := range names {
+		p.nameMapper.ReserveUnscopedName(name, p.trimPrefixes())
+	}
+}
+
+// qualifiedIdent returns the c name of a declaration, a C++ declaration in a namespace
+// or class is qualified by its scopes,like: ns::Widget
+func qualifiedIdent(scope ast.Expr, name *ast.Ident) *ast.Ident {
+	if scope == nil || name == nil {
+		return name
+	}
+	return &ast.Ident{Name: typeKey(scope) + "::" + name.Name}
+}
+
 func (p *Package) trimPrefixes() []string {
 	if p.curFile.InCurPkg() {
 		return p.CppgConf.TrimPrefixes
@@ -3,6 +3,7 @@ package convert
 import (
 	"errors"
 	"go/types"
+	"strings"
 	"testing"
 
 	"github.com/goplus/gogen"
@@ -60,18 +61,20 @@ func TestTypeRefIncompleteFail(t *testing.T) {
 		pkg.incompleteTypes.Complete("Bar")
 		pkg.WritePkgFiles()
 	})
-	t.Run("ref tag incomplete fail", func(t *testing.T) {
-		defer func() {
-			if r := recover(); r == nil {
-				t.Fatal("Expected panic, got nil")
-			}
-		}()
-		pkg.handleTyperefIncomplete(&ast.TagExpr{
+	t.Run("ref scoped tag incomplete", func(t *testing.T) {
+		pkg.incompleteTypes.Add(&Incomplete{cname: "ns::Bar"})
+		deferInit := pkg.handleTyperefIncomplete(&ast.TagExpr{
 			Tag: 0,
 			Name: &ast.ScopingExpr{
-				X: &ast.Ident{Name: "Bar"},
+				Parent: &ast.Ident{Name: "ns"},
+				X:      &ast.Ident{Name: "Bar"},
 			},
 		}, nil, "NewBar")
+		if !deferInit {
+			t.Fatal("Expected the typedef of ns::Bar to be deferred")
+		}
+		pkg.incompleteTypes.Complete("ns::Bar")
+		pkg.incompleteTypes.Complete("NewBar")
 	})
 }
 
@@ -149,3 +152,109 @@ func TestTrimPrefixes(t *testing.T) {
 		t.Errorf("Expected Empty TrimPrefix")
 	}
 }
+
+func TestLayoutProbe(t *testing.T) {
+	pkg := NewPackage(&PackageConfig{
+		PkgBase: PkgBase{
+			PkgPath:  ".",
+			CppgConf: &llcppg.Config{},
+			Pubs:     make(map[string]string),
+		},
+		Name:        "testpkg",
+		GenConf:     &gogen.Config{},
+		SymbolTable: cfg.CreateSymbolTable([]cfg.SymbolEntry{}),
+	})
+	pkg.SetCurFile(&HeaderFile{File: "temp.h", FileType: llcppg.Inter})
+	decls := []ast.Decl{
+		// typedef struct { short x; char c; } Point;
+		&ast.TypedefDecl{
+			Name: &ast.Ident{Name: "Point"},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "x"}}, Type: &ast.BuiltinType{Kind: ast.Int, Flags: ast.Short}},
+					{Names: []*ast.Ident{{Name: "c"}}, Type: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Offset: 16},
+				}},
+				Size:  4,
+				Align: 2,
+			},
+		},
+		// typedef struct Opaque Opaque;, its Go type is a placeholder
+		&ast.TypedefDecl{
+			Name: &ast.Ident{Name: "Opaque"},
+			Type: &ast.RecordType{Tag: ast.Struct, Fields: &ast.FieldList{}, IsForward: true},
+		},
+		// enum Color { Red, Green };
+		&ast.EnumTypeDecl{
+			Name: &ast.Ident{Name: "Color"},
+			Type: &ast.EnumType{Items: []*ast.EnumItem{
+				{Name: &ast.Ident{Name: "Red"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+				{Name: &ast.Ident{Name: "Green"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+			}},
+		},
+		// enum Later;, it is incomplete in C
+		&ast.EnumTypeDecl{
+			Name: &ast.Ident{Name: "Later"},
+			Type: &ast.EnumType{},
+		},
+	}
+	for _, decl := range decls {
+		var err error
+		switch decl := decl.(type) {
+		case *ast.TypedefDecl:
+			err = pkg.NewTypedefDecl(decl)
+		case *ast.EnumTypeDecl:
+			err = pkg.NewEnumTypeDecl(decl)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	probe := layoutProbe(pkg.layoutChecks, &LayoutConfig{Include: []string{"temp.h"}})
+	want := `#include <stddef.h>
+#include <stdio.h>
+#include <temp.h>
+
+int main(void) {
+	printf("t %d %zu %zu\n", 0, sizeof(Point), (size_t)_Alignof(Point));
+	printf("f %d %d %zu\n", 0, 0, offsetof(Point, x));
+	printf("f %d %d %zu\n", 0, 1, offsetof(Point, c));
+	printf("t %d %zu %zu\n", 1, sizeof(enum Color), (size_t)_Alignof(enum Color));
+	return 0;
+}
+`
+	if probe != want {
+		t.Errorf("layoutProbe:\n%s\nwant:\n%s", probe, want)
+	}
+
+	mismatches, err := pkg.compareLayout([]byte("t 0 4 2\nf 0 0 0\nf 0 1 2\nt 1 4 4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mismatches) != 0 {
+		t.Errorf("compareLayout: unexpected mismatches %v", mismatches)
+	}
+
+	// the C layout of a short enum and of a Point whose c is at offset 3
+	mismatches, err = pkg.compareLayout([]byte("t 0 4 2\nf 0 0 0\nf 0 1 3\nt 1 1 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, m.String())
+	}
+	wantMismatches := []string{
+		"Point.c (Point): offset 3 in C, 2 in Go",
+		"Color (enum Color): size 1 in C, 4 in Go",
+		"Color (enum Color): align 1 in C, 4 in Go",
+	}
+	if strings.Join(got, "\n") != strings.Join(wantMismatches, "\n") {
+		t.Errorf("compareLayout:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(wantMismatches, "\n"))
+	}
+
+	if _, err := pkg.compareLayout([]byte("t 2 4 4\n")); err == nil {
+		t.Error("compareLayout: expected an error for an unknown type")
+	}
+}
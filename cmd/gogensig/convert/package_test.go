@@ -2,8 +2,15 @@ package convert_test
 
 import (
 	"bytes"
+	goast "go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 
@@ -15,6 +22,7 @@ import (
 	"github.com/goplus/llcppg/cmd/gogensig/convert"
 	"github.com/goplus/llcppg/cmd/gogensig/dbg"
 	"github.com/goplus/llcppg/llcppg"
+	ctoken "github.com/goplus/llcppg/token"
 	"github.com/goplus/mod/gopmod"
 )
 
@@ -88,10 +96,143 @@ func TestUnionDecl(t *testing.T) {
 			expected: `package testpkg
 import (
 	"github.com/goplus/llgo/c"
-	_ "unsafe"
+	"unsafe"
 )
+// U is a union in C, so its members are accessed by methods.
 type U struct {
-	B c.Long
+	_ [1]uint64
+}
+func (recv_ *U) A() *c.Int {
+	return (*c.Int)(unsafe.Pointer(recv_))
+}
+func (recv_ *U) SetA(v c.Int) {
+	*(*c.Int)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *U) B() *c.Long {
+	return (*c.Long)(unsafe.Pointer(recv_))
+}
+func (recv_ *U) SetB(v c.Long) {
+	*(*c.Long)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *U) C() *c.Long {
+	return (*c.Long)(unsafe.Pointer(recv_))
+}
+func (recv_ *U) SetC(v c.Long) {
+	*(*c.Long)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *U) F() *bool {
+	return (*bool)(unsafe.Pointer(recv_))
+}
+func (recv_ *U) SetF(v bool) {
+	*(*bool)(unsafe.Pointer(recv_)) = v
+}`,
+		},
+		{
+			// the largest member is not the most aligned one
+			name: "union Value{int i; char s[6];};",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Value"},
+				Type: &ast.RecordType{
+					Tag: ast.Union,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Doc:   &ast.CommentGroup{List: []*ast.Comment{{Text: "/// the value as an int"}}},
+								Names: []*ast.Ident{{Name: "i"}},
+								Type:  &ast.BuiltinType{Kind: ast.Int},
+							},
+							{
+								Names: []*ast.Ident{{Name: "s"}},
+								Type: &ast.ArrayType{
+									Elt: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed},
+									Len: &ast.BasicLit{Kind: ast.IntLit, Value: "6"},
+								},
+								Comment: &ast.CommentGroup{List: []*ast.Comment{{Text: "// the value as chars"}}},
+							},
+						},
+					},
+					Size:  8,
+					Align: 4,
+				},
+			},
+			expected: `package testpkg
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+// Value is a union in C, so its members are accessed by methods.
+type Value struct {
+	_ [2]uint32
+}
+/// the value as an int
+func (recv_ *Value) I() *c.Int {
+	return (*c.Int)(unsafe.Pointer(recv_))
+}
+/// the value as an int
+func (recv_ *Value) SetI(v c.Int) {
+	*(*c.Int)(unsafe.Pointer(recv_)) = v
+}
+// the value as chars
+func (recv_ *Value) S() *[6]int8 {
+	return (*[6]int8)(unsafe.Pointer(recv_))
+}
+// the value as chars
+func (recv_ *Value) SetS(v [6]int8) {
+	*(*[6]int8)(unsafe.Pointer(recv_)) = v
+}`,
+		},
+		{
+			// struct Holder { union { int i; float f; } u; };
+			name: "union nested in a struct",
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Holder"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "u"}},
+								Type: &ast.RecordType{
+									Tag: ast.Union,
+									Fields: &ast.FieldList{
+										List: []*ast.Field{
+											{Names: []*ast.Ident{{Name: "i"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+											{Names: []*ast.Ident{{Name: "f"}}, Type: &ast.BuiltinType{Kind: ast.Float}},
+										},
+									},
+									Size:  4,
+									Align: 4,
+								},
+							},
+						},
+					},
+					Size:  4,
+					Align: 4,
+				},
+			},
+			expected: `package testpkg
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+type Holder struct {
+	U Holder_U
+}
+// Holder_U is a union in C, so its members are accessed by methods.
+type Holder_U struct {
+	_ [1]uint32
+}
+func (recv_ *Holder_U) I() *c.Int {
+	return (*c.Int)(unsafe.Pointer(recv_))
+}
+func (recv_ *Holder_U) SetI(v c.Int) {
+	*(*c.Int)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Holder_U) F() *float32 {
+	return (*float32)(unsafe.Pointer(recv_))
+}
+func (recv_ *Holder_U) SetF(v float32) {
+	*(*float32)(unsafe.Pointer(recv_)) = v
 }`,
 		},
 	}
@@ -187,7 +328,7 @@ func TestToType(t *testing.T) {
 		{"Bool", &ast.BuiltinType{Kind: ast.Bool}, "bool"},
 		{"Char_S", &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, "int8"},
 		{"Char_U", &ast.BuiltinType{Kind: ast.Char, Flags: ast.Unsigned}, "int8"},
-		{"WChar", &ast.BuiltinType{Kind: ast.WChar}, "int16"},
+		{"WChar", &ast.BuiltinType{Kind: ast.WChar}, "int32"},
 		{"Char16", &ast.BuiltinType{Kind: ast.Char16}, "int16"},
 		{"Char32", &ast.BuiltinType{Kind: ast.Char32}, "int32"},
 		{"Short", &ast.BuiltinType{Kind: ast.Int, Flags: ast.Short}, "int16"},
@@ -1445,185 +1586,43 @@ const (
 	Green c.Int = 1
 	Blue  c.Int = 2
 )`,
-		},
-	}
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			testGenDecl(t, tc)
-		})
-	}
-}
-
-func TestIdentRefer(t *testing.T) {
-	pkg := createTestPkg(t, &convert.PackageConfig{})
-	pkg.SetCurFile(&convert.HeaderFile{
-		File:     "/path/to/stdio.h",
-		FileType: llcppg.Third,
-	})
-	pkg.NewTypedefDecl(&ast.TypedefDecl{
-		DeclBase: ast.DeclBase{
-			Loc: &ast.Location{File: "/path/to/stdio.h"},
-		},
-		Name: &ast.Ident{Name: "undefType"},
-		Type: &ast.BuiltinType{
-			Kind:  ast.Char,
-			Flags: ast.Signed,
-		},
-	})
-	pkg.SetCurFile(&convert.HeaderFile{
-		File:     "/path/to/notsys.h",
-		FileType: llcppg.Inter,
-	})
-	t.Run("undef sys ident ref", func(t *testing.T) {
-		defer func() {
-			if r := recover(); r == nil {
-				t.Fatalf("expected Panic")
-			}
-		}()
-		pkg.NewTypeDecl(&ast.TypeDecl{
-			DeclBase: ast.DeclBase{
-				Loc: &ast.Location{File: "/path/to/notsys.h"},
-			},
-			Name: &ast.Ident{Name: "Foo"},
-			Type: &ast.RecordType{
-				Tag: ast.Struct,
-				Fields: &ast.FieldList{
-					List: []*ast.Field{
-						{
-							Names: []*ast.Ident{{Name: "notfound"}},
-							Type: &ast.Ident{
-								Name: "undefType",
-							},
-						},
-					},
-				},
-			},
-		})
-	})
-	t.Run("undef tag ident ref", func(t *testing.T) {
-		defer func() {
-			if r := recover(); r == nil {
-				t.Fatalf("expected Panic")
-			}
-		}()
-		pkg.NewTypeDecl(&ast.TypeDecl{
-			Name: &ast.Ident{Name: "Bar"},
-			Type: &ast.RecordType{
-				Tag: ast.Struct,
-				Fields: &ast.FieldList{
-					List: []*ast.Field{
-						{
-							Names: []*ast.Ident{{Name: "notfound"}},
-							Type: &ast.TagExpr{
-								Tag: ast.Class,
-								Name: &ast.Ident{
-									Name: "undefType",
-								},
-							},
-						},
-					},
-				},
-			},
-		})
-	})
-	t.Run("type alias", func(t *testing.T) {
-		pkg := createTestPkg(t, &convert.PackageConfig{
-			PkgBase: convert.PkgBase{
-				CppgConf: &llcppg.Config{},
-			},
-		})
-		pkg.SetCurFile(tempFile)
-		err := pkg.NewTypedefDecl(&ast.TypedefDecl{
-			Name: &ast.Ident{Name: "typ_int8_t"},
-			Type: &ast.BuiltinType{
-				Kind:  ast.Char,
-				Flags: ast.Signed,
-			},
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
-		err = pkg.NewTypeDecl(&ast.TypeDecl{
-			Name: &ast.Ident{Name: "Foo"},
-			Type: &ast.RecordType{
-				Tag: ast.Struct,
-				Fields: &ast.FieldList{
-					List: []*ast.Field{
-						{
-							Names: []*ast.Ident{{Name: "a"}},
-							Type: &ast.Ident{
-								Name: "typ_int8_t",
-							},
-						},
+		}, {
+			name: "enum with integer type",
+			// enum class Small : unsigned char { A, B };
+			decl: &ast.EnumTypeDecl{
+				Name: &ast.Ident{Name: "Small"},
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "A"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+						{Name: &ast.Ident{Name: "B"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
 					},
+					IntType: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Unsigned},
 				},
 			},
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
-		comparePackageOutput(t, pkg, `
-		package testpkg
-		import _ "unsafe"
-		type TypInt8T int8
-		type Foo struct {
-			A TypInt8T
-		}
-		`)
-	})
-}
+			expected: `
+package testpkg
 
-func TestForwardDecl(t *testing.T) {
-	pkg := createTestPkg(t, &convert.PackageConfig{
-		OutputDir: "",
-		SymbolTable: config.CreateSymbolTable(
-			[]config.SymbolEntry{
-				{CppName: "Bar", MangleName: "Bar", GoName: "Bar"},
-			},
-		),
-	})
-	pkg.SetCurFile(tempFile)
+import _ "unsafe"
 
-	forwardDecl := &ast.TypeDecl{
-		Name: &ast.Ident{Name: "Foo"},
-		Type: &ast.RecordType{
-			Tag:    ast.Struct,
-			Fields: &ast.FieldList{},
+type Small int8
+const (
+	A Small = 0
+	B Small = 1
+)`,
 		},
-	}
-	// forward decl
-	err := pkg.NewTypeDecl(forwardDecl)
-	if err != nil {
-		t.Fatalf("Forward decl failed: %v", err)
-	}
-
-	// complete decl
-	err = pkg.NewTypeDecl(&ast.TypeDecl{
-		Name: &ast.Ident{Name: "Foo"},
-		Type: &ast.RecordType{
-			Tag: ast.Struct,
-			Fields: &ast.FieldList{
-				List: []*ast.Field{
-					{
-						Names: []*ast.Ident{{Name: "a"}},
-						Type:  &ast.BuiltinType{Kind: ast.Int},
+		{
+			name: "enum with unsigned long long values",
+			// enum Big : unsigned long long { Max = 0xffffffffffffffff };
+			decl: &ast.EnumTypeDecl{
+				Name: &ast.Ident{Name: "Big"},
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "Max"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "18446744073709551615"}},
 					},
+					IntType: &ast.BuiltinType{Kind: ast.Int, Flags: ast.Unsigned | ast.LongLong},
 				},
 			},
-		},
-	})
-
-	if err != nil {
-		t.Fatalf("NewTypeDecl failed: %v", err)
-	}
-
-	err = pkg.NewTypeDecl(forwardDecl)
-
-	if err != nil {
-		t.Fatalf("NewTypeDecl failed: %v", err)
-	}
-
-	expect := `
+			expected: `
 package testpkg
 
 import (
@@ -1631,11 +1630,3259 @@ import (
 	_ "unsafe"
 )
 
-type Foo struct {
-	A c.Int
-}
-`
-	comparePackageOutput(t, pkg, expect)
+type Big c.UlongLong
+
+const Max Big = 18446744073709551615`,
+		},
+		{
+			name: "flag enum",
+			// enum Mode { None = 0, Read = 1, Write = 2, Exec = 4, All = 7 };
+			decl: &ast.EnumTypeDecl{
+				Name: &ast.Ident{Name: "Mode"},
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "None"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+						{Name: &ast.Ident{Name: "Read"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+						{Name: &ast.Ident{Name: "Write"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "2"}},
+						{Name: &ast.Ident{Name: "Exec"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "4"}},
+						{Name: &ast.Ident{Name: "All"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "7"}},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"strconv"
+	_ "unsafe"
+)
+
+type Mode c.Int
+const (
+	None  Mode = 0
+	Read  Mode = 1
+	Write Mode = 2
+	Exec  Mode = 4
+	All   Mode = 7
+)
+// Has reports whether all bits of flag are set in f.
+func (f Mode) Has(flag Mode) bool {
+	return f&flag == flag
+}
+// Set sets the bits of flag in f.
+func (f *Mode) Set(flag Mode) {
+	*f |= flag
+}
+// String returns the names of the bits set in f, joined by "|".
+func (f Mode) String() string {
+	var s string
+	if f&Read != 0 {
+		s += "|Read"
+	}
+	if f&Write != 0 {
+		s += "|Write"
+	}
+	if f&Exec != 0 {
+		s += "|Exec"
+	}
+	if uint64(f)&^0x7 != 0 {
+		s += "|0x" + strconv.FormatUint(uint64(f)&^0x7, 16)
+	}
+	if s == "" {
+		return "None"
+	}
+	return s[1:]
+}`,
+		},
+		{
+			name: "flag_enum attribute",
+			// enum __attribute__((flag_enum)) Opt { OptA = 1, OptB = 2 };
+			decl: &ast.EnumTypeDecl{
+				DeclBase: ast.DeclBase{Attrs: []*ast.Attr{{Kind: ast.FlagEnumAttr}}},
+				Name:     &ast.Ident{Name: "Opt"},
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "OptA"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+						{Name: &ast.Ident{Name: "OptB"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "2"}},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"strconv"
+	_ "unsafe"
+)
+
+type Opt c.Int
+const (
+	OptA Opt = 1
+	OptB Opt = 2
+)
+// Has reports whether all bits of flag are set in f.
+func (f Opt) Has(flag Opt) bool {
+	return f&flag == flag
+}
+// Set sets the bits of flag in f.
+func (f *Opt) Set(flag Opt) {
+	*f |= flag
+}
+// String returns the names of the bits set in f, joined by "|".
+func (f Opt) String() string {
+	var s string
+	if f&OptA != 0 {
+		s += "|OptA"
+	}
+	if f&OptB != 0 {
+		s += "|OptB"
+	}
+	if uint64(f)&^0x3 != 0 {
+		s += "|0x" + strconv.FormatUint(uint64(f)&^0x3, 16)
+	}
+	if s == "" {
+		return "0"
+	}
+	return s[1:]
+}`,
+		},
+		{
+			name: "anonymous enum with prefix",
+			// enum { CJSON_Invalid, CJSON_False };
+			decl: &ast.EnumTypeDecl{
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "CJSON_Invalid"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+						{Name: &ast.Ident{Name: "CJSON_False"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+					},
+				},
+			},
+			cppgconf: &llcppg.Config{
+				TypedAnonEnum: true,
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type CJSON c.Int
+const (
+	CJSONInvalid CJSON = 0
+	CJSONFalse   CJSON = 1
+)`,
+		},
+		{
+			name: "anonymous enum with prefix untyped",
+			// enum { CJSON_Invalid, CJSON_False };
+			decl: &ast.EnumTypeDecl{
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "CJSON_Invalid"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+						{Name: &ast.Ident{Name: "CJSON_False"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+const (
+	CJSONInvalid c.Int = 0
+	CJSONFalse   c.Int = 1
+)`,
+		},
+		{
+			name: "anonymous enum without prefix typed",
+			// enum { Red, Green };
+			decl: &ast.EnumTypeDecl{
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "Red"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+						{Name: &ast.Ident{Name: "Green"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+					},
+				},
+			},
+			cppgconf: &llcppg.Config{
+				TypedAnonEnum: true,
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+const (
+	Red   c.Int = 0
+	Green c.Int = 1
+)`,
+		},
+		{
+			name: "enum string",
+			// enum Color { COLOR_RED, COLOR_GREEN, COLOR_CRIMSON = COLOR_RED };
+			decl: &ast.EnumTypeDecl{
+				Name: &ast.Ident{Name: "Color"},
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "COLOR_RED"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+						{Name: &ast.Ident{Name: "COLOR_GREEN"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+						{Name: &ast.Ident{Name: "COLOR_CRIMSON"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+					},
+				},
+			},
+			cppgconf: &llcppg.Config{
+				EnumString:     true,
+				TrimEnumPrefix: true,
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"strconv"
+	_ "unsafe"
+)
+
+type Color c.Int
+const (
+	RED     Color = 0
+	GREEN   Color = 1
+	CRIMSON Color = 0
+)
+// String returns the name of the item of e, or Color(e) if there is none.
+func (e Color) String() string {
+	switch e {
+	case RED:
+		return "RED"
+	case GREEN:
+		return "GREEN"
+	}
+	return "Color(" + strconv.FormatInt(int64(e), 10) + ")"
+}`,
+		},
+		{
+			name: "unsigned enum string",
+			// enum Level : unsigned { Low, High };
+			decl: &ast.EnumTypeDecl{
+				Name: &ast.Ident{Name: "Level"},
+				Type: &ast.EnumType{
+					Items: []*ast.EnumItem{
+						{Name: &ast.Ident{Name: "Low"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+						{Name: &ast.Ident{Name: "High"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+					},
+					IntType: &ast.BuiltinType{Kind: ast.Int, Flags: ast.Unsigned},
+				},
+			},
+			cppgconf: &llcppg.Config{
+				EnumString:     true,
+				TrimEnumPrefix: true,
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"strconv"
+	_ "unsafe"
+)
+
+type Level c.Uint
+const (
+	Low  Level = 0
+	High Level = 1
+)
+// String returns the name of the item of e, or Level(e) if there is none.
+func (e Level) String() string {
+	switch e {
+	case Low:
+		return "Low"
+	case High:
+		return "High"
+	}
+	return "Level(" + strconv.FormatUint(uint64(e), 10) + ")"
+}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestTrimEnumPrefix(t *testing.T) {
+	enum := func(name string, items ...string) *ast.EnumTypeDecl {
+		decl := &ast.EnumTypeDecl{Name: &ast.Ident{Name: name}, Type: &ast.EnumType{}}
+		for i, item := range items {
+			decl.Type.Items = append(decl.Type.Items, &ast.EnumItem{
+				Name:  &ast.Ident{Name: item},
+				Value: &ast.BasicLit{Kind: ast.IntLit, Value: strconv.Itoa(i)},
+			})
+		}
+		return decl
+	}
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		PkgBase: convert.PkgBase{
+			CppgConf: &llcppg.Config{TrimEnumPrefix: true},
+		},
+	})
+	pkg.SetCurFile(tempFile)
+	for _, decl := range []*ast.EnumTypeDecl{
+		enum("Mode", "MODE_NONE", "MODE_READ"),
+		// NONE is taken, so the items keep the prefix
+		enum("Kind", "KIND_NONE", "KIND_FILE"),
+		// a name without the prefix would start with a digit
+		enum("Size", "SIZE_1X", "SIZE_2X"),
+	} {
+		if err := pkg.NewEnumTypeDecl(decl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Mode c.Int
+const (
+	NONE Mode = 0
+	READ Mode = 1
+)
+type Kind c.Int
+const (
+	KINDNONE Kind = 0
+	KINDFILE Kind = 1
+)
+type Size c.Int
+const (
+	SIZE1X Size = 0
+	SIZE2X Size = 1
+)`)
+}
+
+func TestIdentRefer(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:     "/path/to/stdio.h",
+		FileType: llcppg.Third,
+	})
+	pkg.NewTypedefDecl(&ast.TypedefDecl{
+		DeclBase: ast.DeclBase{
+			Loc: &ast.Location{File: "/path/to/stdio.h"},
+		},
+		Name: &ast.Ident{Name: "undefType"},
+		Type: &ast.BuiltinType{
+			Kind:  ast.Char,
+			Flags: ast.Signed,
+		},
+	})
+	pkg.SetCurFile(&convert.HeaderFile{
+		File:     "/path/to/notsys.h",
+		FileType: llcppg.Inter,
+	})
+	t.Run("undef sys ident ref", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected Panic")
+			}
+		}()
+		pkg.NewTypeDecl(&ast.TypeDecl{
+			DeclBase: ast.DeclBase{
+				Loc: &ast.Location{File: "/path/to/notsys.h"},
+			},
+			Name: &ast.Ident{Name: "Foo"},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Names: []*ast.Ident{{Name: "notfound"}},
+							Type: &ast.Ident{
+								Name: "undefType",
+							},
+						},
+					},
+				},
+			},
+		})
+	})
+	t.Run("undef tag ident ref", func(t *testing.T) {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatalf("expected Panic")
+			}
+		}()
+		pkg.NewTypeDecl(&ast.TypeDecl{
+			Name: &ast.Ident{Name: "Bar"},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Names: []*ast.Ident{{Name: "notfound"}},
+							Type: &ast.TagExpr{
+								Tag: ast.Class,
+								Name: &ast.Ident{
+									Name: "undefType",
+								},
+							},
+						},
+					},
+				},
+			},
+		})
+	})
+	t.Run("type alias", func(t *testing.T) {
+		pkg := createTestPkg(t, &convert.PackageConfig{
+			PkgBase: convert.PkgBase{
+				CppgConf: &llcppg.Config{},
+			},
+		})
+		pkg.SetCurFile(tempFile)
+		err := pkg.NewTypedefDecl(&ast.TypedefDecl{
+			Name: &ast.Ident{Name: "typ_int8_t"},
+			Type: &ast.BuiltinType{
+				Kind:  ast.Char,
+				Flags: ast.Signed,
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pkg.NewTypeDecl(&ast.TypeDecl{
+			Name: &ast.Ident{Name: "Foo"},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Names: []*ast.Ident{{Name: "a"}},
+							Type: &ast.Ident{
+								Name: "typ_int8_t",
+							},
+						},
+					},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		comparePackageOutput(t, pkg, `
+		package testpkg
+		import _ "unsafe"
+		type TypInt8T int8
+		type Foo struct {
+			A TypInt8T
+		}
+		`)
+	})
+}
+
+func TestForwardDecl(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		OutputDir: "",
+		SymbolTable: config.CreateSymbolTable(
+			[]config.SymbolEntry{
+				{CppName: "Bar", MangleName: "Bar", GoName: "Bar"},
+			},
+		),
+	})
+	pkg.SetCurFile(tempFile)
+
+	forwardDecl := &ast.TypeDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.RecordType{
+			Tag:       ast.Struct,
+			Fields:    &ast.FieldList{},
+			IsForward: true,
+		},
+	}
+	// forward decl
+	err := pkg.NewTypeDecl(forwardDecl)
+	if err != nil {
+		t.Fatalf("Forward decl failed: %v", err)
+	}
+
+	// complete decl
+	err = pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "Foo"},
+		Type: &ast.RecordType{
+			Tag: ast.Struct,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{
+						Names: []*ast.Ident{{Name: "a"}},
+						Type:  &ast.BuiltinType{Kind: ast.Int},
+					},
+				},
+			},
+		},
+	})
+
+	if err != nil {
+		t.Fatalf("NewTypeDecl failed: %v", err)
+	}
+
+	err = pkg.NewTypeDecl(forwardDecl)
+
+	if err != nil {
+		t.Fatalf("NewTypeDecl failed: %v", err)
+	}
+
+	expect := `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A c.Int
+}
+`
+	comparePackageOutput(t, pkg, expect)
+}
+
+func TestEmptyRecord(t *testing.T) {
+	testCases := []genDeclTestCase{
+		{
+			name: "empty struct",
+			// struct Foo {};
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag:    ast.Struct,
+					Fields: &ast.FieldList{},
+				},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+type Foo struct {
+}`,
+		},
+		{
+			name: "empty class",
+			// class Foo {};
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag:    ast.Class,
+					Fields: &ast.FieldList{},
+					Size:   1,
+					Align:  1,
+				},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+type Foo struct {
+	_ [1]uint8
+}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestInstantiationDecl(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		SymbolTable: config.CreateSymbolTable(
+			[]config.SymbolEntry{
+				{CppName: "Foo<int>::Foo()", MangleName: "_ZN3FooIiEC1Ev", GoName: "(*FooInt).Init"},
+				{CppName: "Foo<int>::get()", MangleName: "_ZNK3FooIiE3getEv", GoName: "(*FooInt).Get"},
+				{CppName: "Foo<int>::set(int)", MangleName: "_ZN3FooIiE3setEi", GoName: "(*FooInt).Set"},
+			},
+		),
+	})
+	pkg.SetCurFile(tempFile)
+
+	fooInt := &ast.InstantiationType{
+		Template: &ast.Ident{Name: "Foo"},
+		Args: &ast.FieldList{
+			List: []*ast.Field{{Type: &ast.BuiltinType{Kind: ast.Int}}},
+		},
+	}
+	err := pkg.NewInstantiationDecl(&ast.InstantiationDecl{
+		Name:     &ast.Ident{Name: "Foo<int>"},
+		Template: fooInt,
+		Type: &ast.RecordType{
+			Tag: ast.Class,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{
+						Names:  []*ast.Ident{{Name: "value"}},
+						Type:   &ast.BuiltinType{Kind: ast.Int},
+						Access: ast.Public,
+					},
+					{
+						Names:  []*ast.Ident{{Name: "next"}},
+						Type:   &ast.PointerType{X: fooInt},
+						Access: ast.Public,
+					},
+				},
+			},
+			Methods: []*ast.FuncDecl{
+				{
+					Name:        &ast.Ident{Name: "Foo"},
+					MangledName: "_ZN3FooIiEC1Ev",
+					Type: &ast.FuncType{
+						Params: &ast.FieldList{},
+						Ret:    &ast.BuiltinType{Kind: ast.Void},
+					},
+				},
+				{
+					Name:        &ast.Ident{Name: "get"},
+					MangledName: "_ZNK3FooIiE3getEv",
+					Type: &ast.FuncType{
+						Params: &ast.FieldList{},
+						Ret:    &ast.BuiltinType{Kind: ast.Int},
+					},
+				},
+				{
+					Name:        &ast.Ident{Name: "set"},
+					MangledName: "_ZN3FooIiE3setEi",
+					Type: &ast.FuncType{
+						Params: &ast.FieldList{
+							List: []*ast.Field{
+								{
+									Names: []*ast.Ident{{Name: "v"}},
+									Type:  &ast.BuiltinType{Kind: ast.Int},
+								},
+							},
+						},
+						Ret: &ast.BuiltinType{Kind: ast.Void},
+					},
+				},
+				// not in the symbol table
+				{
+					Name:        &ast.Ident{Name: "reset"},
+					MangledName: "_ZN3FooIiE5resetEv",
+					Type: &ast.FuncType{
+						Params: &ast.FieldList{},
+						Ret:    &ast.BuiltinType{Kind: ast.Void},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewInstantiationDecl failed: %v", err)
+	}
+
+	// typedef Foo<int> IntFoo;
+	err = pkg.NewTypedefDecl(&ast.TypedefDecl{
+		Name: &ast.Ident{Name: "IntFoo"},
+		Type: fooInt,
+	})
+	if err != nil {
+		t.Fatalf("NewTypedefDecl failed: %v", err)
+	}
+
+	expect := `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type FooInt struct {
+	Value c.Int
+	Next  *FooInt
+}
+// llgo:link (*FooInt).Init C._ZN3FooIiEC1Ev
+func (recv_ *FooInt) Init() {
+}
+// llgo:link (*FooInt).Get C._ZNK3FooIiE3getEv
+func (recv_ *FooInt) Get() c.Int {
+	return 0
+}
+// llgo:link (*FooInt).Set C._ZN3FooIiE3setEi
+func (recv_ *FooInt) Set(v c.Int) {
+}
+
+type IntFoo FooInt
+`
+	comparePackageOutput(t, pkg, expect)
+	if goName := pkg.Pubs["Foo<int>"]; goName != "FooInt" {
+		t.Errorf("Pubs[%q] = %q, want %q", "Foo<int>", goName, "FooInt")
+	}
+}
+
+func TestOperator(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		SymbolTable: config.CreateSymbolTable(
+			[]config.SymbolEntry{
+				{CppName: "operator*(const Vec &, float)", MangleName: "_ZmlRK3Vecf", GoName: "(*Vec).Mul"},
+				{CppName: "operator==(const Vec &, const Vec &)", MangleName: "_ZeqRK3VecS1_", GoName: "(*Vec).Eq"},
+			},
+		),
+	})
+	pkg.SetCurFile(tempFile)
+
+	vecRef := &ast.LvalueRefType{X: &ast.Ident{Name: "Vec"}}
+	err := pkg.NewTypeDecl(&ast.TypeDecl{
+		Name: &ast.Ident{Name: "Vec"},
+		Type: &ast.RecordType{
+			Tag: ast.Class,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "x"}}, Type: &ast.BuiltinType{Kind: ast.Float}, Access: ast.Public},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTypeDecl failed: %v", err)
+	}
+	// Vec operator*(const Vec &v, float s);
+	err = pkg.NewFuncDecl(&ast.FuncDecl{
+		Name:        &ast.Ident{Name: "operator*"},
+		MangledName: "_ZmlRK3Vecf",
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "v"}}, Type: vecRef},
+					{Names: []*ast.Ident{{Name: "s"}}, Type: &ast.BuiltinType{Kind: ast.Float}},
+				},
+			},
+			Ret: &ast.Ident{Name: "Vec"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFuncDecl failed: %v", err)
+	}
+	// bool operator==(const Vec &a, const Vec &b);
+	err = pkg.NewFuncDecl(&ast.FuncDecl{
+		Name:        &ast.Ident{Name: "operator=="},
+		MangledName: "_ZeqRK3VecS1_",
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "a"}}, Type: vecRef},
+					{Names: []*ast.Ident{{Name: "b"}}, Type: vecRef},
+				},
+			},
+			Ret: &ast.BuiltinType{Kind: ast.Bool},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFuncDecl failed: %v", err)
+	}
+
+	expect := `
+package testpkg
+
+import _ "unsafe"
+
+type Vec struct {
+	X float32
+}
+// llgo:link (*Vec).Mul C._ZmlRK3Vecf
+func (recv_ *Vec) Mul(s float32) Vec {
+	return struct {
+		X float32
+	}{}
+}
+// llgo:link (*Vec).Eq C._ZeqRK3VecS1_
+func (recv_ *Vec) Eq(b *Vec) bool {
+	return false
+}
+`
+	comparePackageOutput(t, pkg, expect)
+}
+
+func TestInheritance(t *testing.T) {
+	intField := func(name string, offset int64) *ast.Field {
+		return &ast.Field{Names: []*ast.Ident{{Name: name}}, Type: &ast.BuiltinType{Kind: ast.Int}, Access: ast.Public, Offset: offset}
+	}
+	class := func(name string, bases []*ast.BaseSpec, fields ...*ast.Field) *ast.TypeDecl {
+		return &ast.TypeDecl{
+			Name: &ast.Ident{Name: name},
+			Type: &ast.RecordType{
+				Tag:     ast.Class,
+				Fields:  &ast.FieldList{List: fields},
+				Methods: []*ast.FuncDecl{},
+				Bases:   bases,
+				Size:    int64(len(fields)+len(bases)) * 4,
+				Align:   4,
+			},
+		}
+	}
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		SymbolTable: config.CreateSymbolTable(
+			[]config.SymbolEntry{
+				{CppName: "Base::get()", MangleName: "_ZN4Base3getEv", GoName: "(*Base).Get"},
+			},
+		),
+	})
+	pkg.SetCurFile(tempFile)
+	for _, decl := range []*ast.TypeDecl{
+		class("Base", nil, intField("a", 0)),
+		// class Derived : public Base { public: int b; };
+		class("Derived", []*ast.BaseSpec{{Type: &ast.Ident{Name: "Base"}, Access: ast.Public}}, intField("b", 32)),
+		// class Empty : public Base {};
+		class("Empty", []*ast.BaseSpec{{Type: &ast.Ident{Name: "Base"}, Access: ast.Public}}),
+	} {
+		if err := pkg.NewTypeDecl(decl); err != nil {
+			t.Fatalf("NewTypeDecl %s failed: %v", decl.Name.Name, err)
+		}
+	}
+	err := pkg.NewFuncDecl(&ast.FuncDecl{
+		Name:        &ast.Ident{Name: "get"},
+		MangledName: "_ZN4Base3getEv",
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{{Names: []*ast.Ident{{Name: "this"}}, Type: &ast.PointerType{X: &ast.Ident{Name: "Base"}}}},
+			},
+			Ret: &ast.BuiltinType{Kind: ast.Int},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewFuncDecl failed: %v", err)
+	}
+
+	expect := `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Base struct {
+	A c.Int
+}
+
+type Derived struct {
+	Base
+	B c.Int
+}
+
+type Empty struct {
+	Base
+}
+// llgo:link (*Base).Get C.get
+func (recv_ *Base) Get() c.Int {
+	return 0
+}
+`
+	comparePackageOutput(t, pkg, expect)
+
+	// the methods of the base are promoted to the derived class
+	derived := pkg.GetGenPackage().Types.Scope().Lookup("Derived").Type()
+	if obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(derived), true, nil, "Get"); obj == nil {
+		t.Errorf("method Get of Base is not promoted to *Derived")
+	}
+
+	testCases := []struct {
+		name  string
+		bases []*ast.BaseSpec
+		err   string
+	}{
+		{
+			name: "Multiple",
+			bases: []*ast.BaseSpec{
+				{Type: &ast.Ident{Name: "Base"}, Access: ast.Public},
+				{Type: &ast.Ident{Name: "Derived"}, Access: ast.Public, Offset: 32},
+			},
+			err: "unsupported inheritance of Multiple: multiple inheritance from Base, Derived",
+		},
+		{
+			name:  "Virtual",
+			bases: []*ast.BaseSpec{{Type: &ast.Ident{Name: "Base"}, Access: ast.Public, Virtual: true}},
+			err:   "unsupported inheritance of Virtual: virtual inheritance from Base",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			compareError(t, pkg.NewTypeDecl(class(tc.name, tc.bases, intField("c", 0))), tc.err)
+		})
+	}
+}
+
+func TestPolymorphic(t *testing.T) {
+	field := func(name string, kind ast.TypeKind, offset int64) *ast.Field {
+		return &ast.Field{Names: []*ast.Ident{{Name: name}}, Type: &ast.BuiltinType{Kind: kind}, Access: ast.Public, Offset: offset}
+	}
+	method := func(name, mangled string, ret ast.Expr) *ast.FuncDecl {
+		return &ast.FuncDecl{
+			Name:        &ast.Ident{Name: name},
+			MangledName: mangled,
+			Type:        &ast.FuncType{Params: &ast.FieldList{}, Ret: ret},
+			IsVirtual:   true,
+		}
+	}
+	double := &ast.BuiltinType{Kind: ast.Float, Flags: ast.Double}
+	void := &ast.BuiltinType{Kind: ast.Void}
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		SymbolTable: config.CreateSymbolTable(
+			[]config.SymbolEntry{
+				{CppName: "Shape::~Shape()", MangleName: "_ZN5ShapeD1Ev", GoName: "(*Shape).Dispose"},
+				{CppName: "Circle::area()", MangleName: "_ZN6Circle4areaEv", GoName: "(*Circle).Area"},
+				{CppName: "Node::visit()", MangleName: "_ZN4Node5visitEv", GoName: "(*Node).Visit"},
+			},
+		),
+	})
+	pkg.SetCurFile(tempFile)
+	dtor := method("~Shape", "_ZN5ShapeD1Ev", void)
+	dtor.IsDestructor = true
+	for _, decl := range []*ast.TypeDecl{
+		// class Shape { public: virtual ~Shape(); virtual int area() = 0; int id; };
+		{
+			Name: &ast.Ident{Name: "Shape"},
+			Type: &ast.RecordType{
+				Tag:         ast.Class,
+				Fields:      &ast.FieldList{List: []*ast.Field{field("id", ast.Int, 64)}},
+				Methods:     []*ast.FuncDecl{dtor, method("area", "_ZN5Shape4areaEv", &ast.BuiltinType{Kind: ast.Int})},
+				Polymorphic: true,
+				Abstract:    true,
+				Size:        16,
+				Align:       8,
+			},
+		},
+		// class Circle : public Shape { public: int area() override; double r; };
+		{
+			Name: &ast.Ident{Name: "Circle"},
+			Type: &ast.RecordType{
+				Tag:         ast.Class,
+				Fields:      &ast.FieldList{List: []*ast.Field{&ast.Field{Names: []*ast.Ident{{Name: "r"}}, Type: double, Access: ast.Public, Offset: 128}}},
+				Methods:     []*ast.FuncDecl{method("area", "_ZN6Circle4areaEv", &ast.BuiltinType{Kind: ast.Int})},
+				Bases:       []*ast.BaseSpec{{Type: &ast.Ident{Name: "Shape"}, Access: ast.Public}},
+				Polymorphic: true,
+				Size:        24,
+				Align:       8,
+			},
+		},
+		// class Tagged { public: int tag; };
+		{
+			Name: &ast.Ident{Name: "Tagged"},
+			Type: &ast.RecordType{
+				Tag:     ast.Class,
+				Fields:  &ast.FieldList{List: []*ast.Field{field("tag", ast.Int, 0)}},
+				Methods: []*ast.FuncDecl{},
+				Size:    4,
+				Align:   4,
+			},
+		},
+		// class Node : public Tagged { public: virtual void visit(); };
+		{
+			Name: &ast.Ident{Name: "Node"},
+			Type: &ast.RecordType{
+				Tag:         ast.Class,
+				Fields:      &ast.FieldList{},
+				Methods:     []*ast.FuncDecl{method("visit", "_ZN4Node5visitEv", void)},
+				Bases:       []*ast.BaseSpec{{Type: &ast.Ident{Name: "Tagged"}, Access: ast.Public, Offset: 64}},
+				Polymorphic: true,
+				Size:        16,
+				Align:       8,
+			},
+		},
+	} {
+		if err := pkg.NewTypeDecl(decl); err != nil {
+			t.Fatalf("NewTypeDecl %s failed: %v", decl.Name.Name, err)
+		}
+	}
+
+	expect := `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+type Shape struct {
+	_  unsafe.Pointer
+	Id c.Int
+}
+// llgo:link (*Shape).Dispose C._ZN5ShapeD1Ev
+func (recv_ *Shape) Dispose() {
+}
+// ShapeInterface is implemented by the classes derived from Shape.
+type ShapeInterface interface {
+	Area() c.Int
+}
+
+type Circle struct {
+	Shape
+	R float64
+}
+// llgo:link (*Circle).Area C._ZN6Circle4areaEv
+func (recv_ *Circle) Area() c.Int {
+	return 0
+}
+
+type Tagged struct {
+	Tag c.Int
+}
+
+type Node struct {
+	_ unsafe.Pointer
+	Tagged
+}
+// llgo:link (*Node).Visit C._ZN4Node5visitEv
+func (recv_ *Node) Visit() {
+}
+`
+	comparePackageOutput(t, pkg, expect)
+
+	scope := pkg.GetGenPackage().Types.Scope()
+	iface := scope.Lookup("ShapeInterface").Type().Underlying().(*types.Interface)
+	if circle := scope.Lookup("Circle").Type(); !types.Implements(types.NewPointer(circle), iface) {
+		t.Errorf("*Circle doesn't implement ShapeInterface")
+	}
+}
+
+func TestBaseLayoutMismatch(t *testing.T) {
+	pkg := createTestPkg(t, &convert.PackageConfig{})
+	pkg.SetCurFile(tempFile)
+	field := func(name string, kind ast.TypeKind, offset int64) *ast.Field {
+		typ := &ast.BuiltinType{Kind: kind}
+		if kind == ast.Char {
+			typ.Flags = ast.Signed
+		}
+		return &ast.Field{
+			Names:  []*ast.Ident{{Name: name}},
+			Type:   typ,
+			Access: ast.Public,
+			Offset: offset,
+		}
+	}
+	for _, decl := range []*ast.TypeDecl{
+		// class Base { public: Base(); int a; char b; };
+		{
+			Name: &ast.Ident{Name: "Base"},
+			Type: &ast.RecordType{
+				Tag:     ast.Class,
+				Fields:  &ast.FieldList{List: []*ast.Field{field("a", ast.Int, 0), field("b", ast.Char, 32)}},
+				Methods: []*ast.FuncDecl{},
+				Size:    8,
+				Align:   4,
+			},
+		},
+		// class Derived : public Base { public: char c; };
+		// c is in the tail padding of Base, where Go can't put it
+		{
+			Name: &ast.Ident{Name: "Derived"},
+			Type: &ast.RecordType{
+				Tag:     ast.Class,
+				Fields:  &ast.FieldList{List: []*ast.Field{field("c", ast.Char, 40)}},
+				Methods: []*ast.FuncDecl{},
+				Bases:   []*ast.BaseSpec{{Type: &ast.Ident{Name: "Base"}, Access: ast.Public}},
+				Size:    8,
+				Align:   4,
+			},
+		},
+	} {
+		if err := pkg.NewTypeDecl(decl); err != nil {
+			t.Fatalf("NewTypeDecl %s failed: %v", decl.Name.Name, err)
+		}
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Base struct {
+	A c.Int
+	B int8
+}
+// Derived doesn't match its layout in C: field C at offset 8, want 5.
+type Derived struct {
+	Base
+	C int8
+}`)
+}
+
+func TestNamespace(t *testing.T) {
+	ns := func(names ...string) ast.Expr {
+		var expr ast.Expr = &ast.Ident{Name: names[0]}
+		for _, name := range names[1:] {
+			expr = &ast.ScopingExpr{Parent: expr, X: &ast.Ident{Name: name}}
+		}
+		return expr
+	}
+	record := func(field string) *ast.RecordType {
+		return &ast.RecordType{
+			Tag: ast.Class,
+			Fields: &ast.FieldList{
+				List: []*ast.Field{
+					{
+						Names:  []*ast.Ident{{Name: field}},
+						Type:   &ast.BuiltinType{Kind: ast.Int},
+						Access: ast.Public,
+					},
+				},
+			},
+		}
+	}
+	decls := []ast.Decl{
+		// namespace ns1 { class Widget { public: int a; }; }
+		&ast.TypeDecl{
+			DeclBase: ast.DeclBase{Parent: ns("ns1")},
+			Name:     &ast.Ident{Name: "Widget"},
+			Type:     record("a"),
+		},
+		// namespace ns2 { class Widget { public: int b; }; }
+		&ast.TypeDecl{
+			DeclBase: ast.DeclBase{Parent: ns("ns2")},
+			Name:     &ast.Ident{Name: "Widget"},
+			Type:     record("b"),
+		},
+		// namespace ns1 { typedef Widget *Handle; }
+		&ast.TypedefDecl{
+			DeclBase: ast.DeclBase{Parent: ns("ns1")},
+			Name:     &ast.Ident{Name: "Handle"},
+			Type:     &ast.PointerType{X: ns("ns1", "Widget")},
+		},
+		// namespace ns1 { enum Color { Red }; }
+		&ast.EnumTypeDecl{
+			DeclBase: ast.DeclBase{Parent: ns("ns1")},
+			Name:     &ast.Ident{Name: "Color"},
+			Type: &ast.EnumType{
+				Items: []*ast.EnumItem{
+					{Name: &ast.Ident{Name: "Red"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+				},
+			},
+		},
+		// struct Holder { class ns2::Widget w; ns1::Handle h; ns1::Color c; };
+		&ast.TypeDecl{
+			Name: &ast.Ident{Name: "Holder"},
+			Type: &ast.RecordType{
+				Tag: ast.Struct,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Names: []*ast.Ident{{Name: "w"}},
+							Type:  &ast.TagExpr{Tag: ast.Class, Name: ns("ns2", "Widget")},
+						},
+						{
+							Names: []*ast.Ident{{Name: "h"}},
+							Type:  ns("ns1", "Handle"),
+						},
+						{
+							Names: []*ast.Ident{{Name: "c"}},
+							Type:  ns("ns1", "Color"),
+						},
+					},
+				},
+			},
+		},
+	}
+	testCases := []struct {
+		name     string
+		naming   llcppg.NamespaceNaming
+		expected string
+	}{
+		{
+			name:   "prefix",
+			naming: "",
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Ns1Widget struct {
+	A c.Int
+}
+
+type Ns2Widget struct {
+	B c.Int
+}
+type Ns1Handle *Ns1Widget
+type Ns1Color c.Int
+
+const Ns1Red Ns1Color = 0
+
+type Holder struct {
+	W Ns2Widget
+	H Ns1Handle
+	C Ns1Color
+}
+`,
+		},
+		{
+			name:   "drop",
+			naming: llcppg.NamespaceDrop,
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Ns1Widget struct {
+	A c.Int
+}
+
+type Ns2Widget struct {
+	B c.Int
+}
+type Handle *Ns1Widget
+type Color c.Int
+
+const Red Color = 0
+
+type Holder struct {
+	W Ns2Widget
+	H Handle
+	C Color
+}
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pkg := createTestPkg(t, &convert.PackageConfig{
+				PkgBase: convert.PkgBase{
+					CppgConf: &llcppg.Config{NamespaceNaming: tc.naming},
+				},
+			})
+			pkg.SetCurFile(tempFile)
+			for _, names := range llcppg.DeclNames(decls) {
+				pkg.ReserveNames(names)
+			}
+			for _, decl := range decls {
+				var err error
+				switch d := decl.(type) {
+				case *ast.TypeDecl:
+					err = pkg.NewTypeDecl(d)
+				case *ast.TypedefDecl:
+					err = pkg.NewTypedefDecl(d)
+				case *ast.EnumTypeDecl:
+					err = pkg.NewEnumTypeDecl(d)
+				}
+				if err != nil {
+					t.Fatalf("%T failed: %v", decl, err)
+				}
+			}
+			comparePackageOutput(t, pkg, tc.expected)
+			if goName := pkg.Pubs["ns2::Widget"]; goName != "Ns2Widget" {
+				t.Errorf("Pubs[%q] = %q, want %q", "ns2::Widget", goName, "Ns2Widget")
+			}
+		})
+	}
+}
+
+func TestNamespaceDropOrder(t *testing.T) {
+	widget := func(scope string) *ast.TypeDecl {
+		decl := &ast.TypeDecl{
+			Name: &ast.Ident{Name: "Widget"},
+			Type: &ast.RecordType{Tag: ast.Struct, Fields: &ast.FieldList{}},
+		}
+		if scope != "" {
+			decl.Parent = &ast.Ident{Name: scope}
+		}
+		return decl
+	}
+	// the Go names are the same whatever order the names are declared in
+	for _, order := range [][]string{{"ns1", "ns2", "", "ns3::Gadget"}, {"ns3::Gadget", "", "ns2", "ns1"}} {
+		pkg := createTestPkg(t, &convert.PackageConfig{
+			PkgBase: convert.PkgBase{
+				CppgConf: &llcppg.Config{NamespaceNaming: llcppg.NamespaceDrop},
+			},
+		})
+		pkg.SetCurFile(tempFile)
+		var decls []*ast.TypeDecl
+		for _, scope := range order {
+			decl := widget(scope)
+			if scope, name, ok := strings.Cut(scope, "::"); ok {
+				decl = widget(scope)
+				decl.Name.Name = name
+			}
+			decls = append(decls, decl)
+			for _, names := range llcppg.DeclNames([]ast.Decl{decl}) {
+				pkg.ReserveNames(names)
+			}
+		}
+		for _, decl := range decls {
+			if err := pkg.NewTypeDecl(decl); err != nil {
+				t.Fatal(err)
+			}
+		}
+		scope := pkg.GetGenPackage().Types.Scope()
+		for _, name := range []string{"Ns1Widget", "Ns2Widget", "Widget", "Gadget"} {
+			if _, ok := scope.Lookup(name).(*types.TypeName); !ok {
+				t.Errorf("%v: %s is not declared", order, name)
+			}
+		}
+	}
+}
+
+func TestScopedEnum(t *testing.T) {
+	// namespace ns { enum class Color : unsigned char { None, Red }; enum class Shape { None }; }
+	ns := &ast.Ident{Name: "ns"}
+	item := func(name, value string) *ast.EnumItem {
+		return &ast.EnumItem{Name: &ast.Ident{Name: name}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: value}}
+	}
+	decls := []*ast.EnumTypeDecl{
+		{
+			DeclBase: ast.DeclBase{Parent: ns},
+			Name:     &ast.Ident{Name: "Color"},
+			Type: &ast.EnumType{
+				Items:   []*ast.EnumItem{item("None", "0"), item("Red", "1")},
+				IntType: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Unsigned},
+				Scoped:  true,
+			},
+		},
+		{
+			DeclBase: ast.DeclBase{Parent: ns},
+			Name:     &ast.Ident{Name: "Shape"},
+			Type: &ast.EnumType{
+				Items:  []*ast.EnumItem{item("None", "0")},
+				Scoped: true,
+			},
+		},
+	}
+	testCases := []struct {
+		name     string
+		naming   llcppg.NamespaceNaming
+		expected string
+	}{
+		{
+			name:   "prefix",
+			naming: "",
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type NsColor int8
+
+const (
+	NsColorNone NsColor = 0
+	NsColorRed  NsColor = 1
+)
+
+type NsShape c.Int
+
+const NsShapeNone NsShape = 0
+`,
+		},
+		{
+			name:   "drop",
+			naming: llcppg.NamespaceDrop,
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Color int8
+
+const (
+	ColorNone Color = 0
+	ColorRed  Color = 1
+)
+
+type Shape c.Int
+
+const ShapeNone Shape = 0
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pkg := createTestPkg(t, &convert.PackageConfig{
+				PkgBase: convert.PkgBase{
+					CppgConf: &llcppg.Config{NamespaceNaming: tc.naming},
+				},
+			})
+			pkg.SetCurFile(tempFile)
+			for _, decl := range decls {
+				for _, names := range llcppg.DeclNames([]ast.Decl{decl}) {
+					pkg.ReserveNames(names)
+				}
+			}
+			for _, decl := range decls {
+				if err := pkg.NewEnumTypeDecl(decl); err != nil {
+					t.Fatal(err)
+				}
+			}
+			comparePackageOutput(t, pkg, tc.expected)
+			if obj := pkg.GetGenPackage().Types.Scope().Lookup("ns::Shape::None"); obj == nil {
+				t.Errorf("ns::Shape::None is not declared")
+			}
+		})
+	}
+}
+
+func TestExternC(t *testing.T) {
+	params := &ast.FieldList{
+		List: []*ast.Field{
+			{Names: []*ast.Ident{{Name: "a"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+			{Names: []*ast.Ident{{Name: "b"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+		},
+	}
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		PkgBase: convert.PkgBase{
+			CppgConf: &llcppg.Config{Cplusplus: true},
+		},
+		SymbolTable: config.CreateSymbolTable(
+			[]config.SymbolEntry{
+				{CppName: "add(int, int)", MangleName: "add", GoName: "Add"},
+				{CppName: "mul(int, int)", MangleName: "_Z3mulii", GoName: "Mul"},
+			},
+		),
+	})
+	pkg.SetCurFile(tempFile)
+	for _, decl := range []*ast.FuncDecl{
+		// extern "C" int add(int a, int b);
+		{
+			Name:        &ast.Ident{Name: "add"},
+			MangledName: "add",
+			Type:        &ast.FuncType{Params: params, Ret: &ast.BuiltinType{Kind: ast.Int}},
+			IsExternC:   true,
+		},
+		// int mul(int a, int b);
+		{
+			Name:        &ast.Ident{Name: "mul"},
+			MangledName: "_Z3mulii",
+			Type:        &ast.FuncType{Params: params, Ret: &ast.BuiltinType{Kind: ast.Int}},
+		},
+	} {
+		if err := pkg.NewFuncDecl(decl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+//go:linkname Add C.add
+func Add(a c.Int, b c.Int) c.Int
+
+//go:linkname Mul C._Z3mulii
+func Mul(a c.Int, b c.Int) c.Int`)
+}
+
+func TestAttrs(t *testing.T) {
+	fooSymb := []config.SymbolEntry{
+		{CppName: "foo", MangleName: "foo", GoName: "Foo"},
+	}
+	testCases := []genDeclTestCase{
+		{
+			name: "deprecated func",
+			decl: &ast.FuncDecl{
+				DeclBase: ast.DeclBase{
+					Doc: &ast.CommentGroup{List: []*ast.Comment{{Text: "/// Foo comment"}}},
+					Attrs: []*ast.Attr{
+						{Kind: ast.DeprecatedAttr, Args: []string{"use bar instead"}},
+					},
+				},
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "foo",
+				Type:        &ast.FuncType{Ret: &ast.BuiltinType{Kind: ast.Void}},
+			},
+			symbs: fooSymb,
+			expected: `
+package testpkg
+import _ "unsafe"
+/// Foo comment
+//
+// Deprecated: use bar instead
+//
+//go:linkname Foo C.foo
+func Foo()`,
+		},
+		{
+			name: "unavailable func",
+			decl: &ast.FuncDecl{
+				DeclBase: ast.DeclBase{
+					Attrs: []*ast.Attr{{Kind: ast.UnavailableAttr}},
+				},
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "foo",
+				Type:        &ast.FuncType{Ret: &ast.BuiltinType{Kind: ast.Void}},
+			},
+			symbs: fooSymb,
+			expected: `
+package testpkg
+import _ "unsafe"`,
+		},
+		{
+			name: "nonnull func",
+			decl: &ast.FuncDecl{
+				DeclBase: ast.DeclBase{
+					Attrs: []*ast.Attr{
+						{Kind: ast.NonNullAttr, Args: []string{"1"}},
+						{Kind: ast.WarnUnusedResultAttr},
+					},
+				},
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "foo",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "a"}},
+								Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}},
+							},
+						},
+					},
+					Ret: &ast.BuiltinType{Kind: ast.Int},
+				},
+			},
+			symbs: fooSymb,
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+// The pointer argument at position 1 must not be nil.
+//
+// The result should not be ignored.
+//
+//go:linkname Foo C.foo
+func Foo(a *int8) c.Int`,
+		},
+		{
+			name: "format noreturn func",
+			decl: &ast.FuncDecl{
+				DeclBase: ast.DeclBase{
+					Doc: &ast.CommentGroup{List: []*ast.Comment{{Text: "/// Foo comment"}}},
+					Attrs: []*ast.Attr{
+						{Kind: ast.FormatAttr, Args: []string{"printf", "1", "2"}},
+						{Kind: ast.NoReturnAttr},
+					},
+				},
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "foo",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "fmt"}},
+								Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}},
+							},
+							{Type: &ast.Variadic{}},
+						},
+					},
+					Ret: &ast.BuiltinType{Kind: ast.Void},
+				},
+			},
+			symbs: fooSymb,
+			expected: `
+package testpkg
+import _ "unsafe"
+/// Foo comment
+//
+// The argument at position 1 is a printf format string for the arguments from position 2.
+//
+// It never returns.
+//
+//go:linkname Foo C.foo
+func Foo(fmt *int8, __llgo_va_list ...interface{})`,
+		},
+		{
+			name: "deprecated struct",
+			decl: &ast.TypeDecl{
+				DeclBase: ast.DeclBase{
+					Attrs: []*ast.Attr{{Kind: ast.DeprecatedAttr}},
+				},
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{Names: []*ast.Ident{{Name: "a"}}, Type: &ast.BuiltinType{Kind: ast.Int}},
+						},
+					},
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+// Deprecated: this declaration is marked deprecated in C.
+type Foo struct {
+	A c.Int
+}`,
+		},
+		{
+			name: "deprecated typedef",
+			decl: &ast.TypedefDecl{
+				DeclBase: ast.DeclBase{
+					Attrs: []*ast.Attr{{Kind: ast.DeprecatedAttr, Args: []string{"use Bar"}}},
+				},
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.BuiltinType{Kind: ast.Int},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+// Deprecated: use Bar
+type Foo c.Int`,
+		},
+		{
+			name: "nullability func",
+			// int *_Nullable foo(char *_Nonnull a, char *_Nullable b);
+			decl: &ast.FuncDecl{
+				DeclBase: ast.DeclBase{
+					Doc: &ast.CommentGroup{List: []*ast.Comment{{Text: "/// Foo comment"}}},
+				},
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "foo",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names: []*ast.Ident{{Name: "a"}},
+								Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Nullability: ast.Nonnull},
+							},
+							{
+								Names: []*ast.Ident{{Name: "b"}},
+								Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Nullability: ast.Nullable},
+							},
+						},
+					},
+					Ret: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Int}, Nullability: ast.Nullable},
+				},
+			},
+			symbs: fooSymb,
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+/// Foo comment
+//
+// The pointer argument a must not be nil.
+// The pointer argument b may be nil.
+// The returned pointer may be nil.
+//
+//go:linkname Foo C.foo
+func Foo(a *int8, b *int8) *c.Int`,
+		},
+		{
+			name: "nonnull pointers",
+			// char *_Nonnull foo(char *_Nonnull, char *_Nonnull b);
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "foo",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							{Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Nullability: ast.Nonnull}},
+							{
+								Names: []*ast.Ident{{Name: "b"}},
+								Type:  &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Nullability: ast.Nonnull},
+							},
+						},
+					},
+					Ret: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Nullability: ast.Nonnull},
+				},
+			},
+			symbs: fooSymb,
+			expected: `
+package testpkg
+import _ "unsafe"
+// The pointer arguments #1, b must not be nil.
+// The returned pointer is never nil.
+//
+//go:linkname Foo C.foo
+func Foo(__llgo_arg_0 *int8, b *int8) *int8`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	voidPtr := &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}
+	pkg := createTestPkg(t, &convert.PackageConfig{
+		SymbolTable: config.CreateSymbolTable(
+			[]config.SymbolEntry{
+				{CppName: "foo_new", MangleName: "foo_new", GoName: "FooNew"},
+				{CppName: "foo_free", MangleName: "foo_free", GoName: "FooFree"},
+			},
+		),
+	})
+	pkg.SetCurFile(tempFile)
+	for _, decl := range []*ast.FuncDecl{
+		// void *foo_new(void) __attribute__((ownership_returns(foo)));
+		{
+			DeclBase: ast.DeclBase{
+				Doc:   &ast.CommentGroup{List: []*ast.Comment{{Text: "/// Create a foo"}}},
+				Attrs: []*ast.Attr{{Kind: ast.OwnershipReturnsAttr, Args: []string{"foo"}}},
+			},
+			Name:        &ast.Ident{Name: "foo_new"},
+			MangledName: "foo_new",
+			Type:        &ast.FuncType{Ret: voidPtr},
+		},
+		// void foo_free(void *p) __attribute__((ownership_takes(foo, 1)));
+		{
+			DeclBase: ast.DeclBase{
+				Attrs: []*ast.Attr{{Kind: ast.OwnershipTakesAttr, Args: []string{"foo", "1"}}},
+			},
+			Name:        &ast.Ident{Name: "foo_free"},
+			MangledName: "foo_free",
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{{Name: "p"}}, Type: voidPtr}}},
+				Ret:    &ast.BuiltinType{Kind: ast.Void},
+			},
+		},
+	} {
+		if err := pkg.NewFuncDecl(decl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	comparePackageOutput(t, pkg, `
+package testpkg
+
+import "unsafe"
+/// Create a foo
+//
+// The result must be released with [FooFree].
+//
+//go:linkname FooNew C.foo_new
+func FooNew() unsafe.Pointer
+// It releases the memory returned by [FooNew].
+//
+//go:linkname FooFree C.foo_free
+func FooFree(p unsafe.Pointer)`)
+}
+
+func TestVarDecl(t *testing.T) {
+	testCases := []genDeclTestCase{
+		{
+			name: "int constant",
+			// static const int kMaxSize = 64;
+			decl: &ast.VarDecl{
+				Name:        &ast.Ident{Name: "kMaxSize"},
+				MangledName: "kMaxSize",
+				Type:        &ast.BuiltinType{Kind: ast.Int},
+				Value:       &ast.BasicLit{Kind: ast.IntLit, Value: "64"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+const KMaxSize c.Int = 64`,
+		},
+		{
+			name: "negative constant",
+			// constexpr long kMin = -1;
+			decl: &ast.VarDecl{
+				Name:        &ast.Ident{Name: "kMin"},
+				MangledName: "_ZL4kMin",
+				Type:        &ast.BuiltinType{Kind: ast.Int, Flags: ast.Long},
+				Value:       &ast.BasicLit{Kind: ast.IntLit, Value: "-1"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+const KMin c.Long = -1`,
+		},
+		{
+			name: "float constant",
+			// /// The ratio of a circle
+			// constexpr double kPi = 3.14;
+			decl: &ast.VarDecl{
+				DeclBase: ast.DeclBase{
+					Doc: &ast.CommentGroup{List: []*ast.Comment{{Text: "/// The ratio of a circle"}}},
+				},
+				Name:        &ast.Ident{Name: "kPi"},
+				MangledName: "_ZL3kPi",
+				Type:        &ast.BuiltinType{Kind: ast.Float, Flags: ast.Double},
+				Value:       &ast.BasicLit{Kind: ast.FloatLit, Value: "3.14"},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+/// The ratio of a circle
+const KPi float64 = 3.14`,
+		},
+		{
+			name: "bool constant",
+			// constexpr bool kDebug = true;
+			decl: &ast.VarDecl{
+				Name:        &ast.Ident{Name: "kDebug"},
+				MangledName: "_ZL6kDebug",
+				Type:        &ast.BuiltinType{Kind: ast.Bool},
+				Value:       &ast.BasicLit{Kind: ast.IntLit, Value: "1"},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+const KDebug bool = true`,
+		},
+		{
+			name: "string constant",
+			// static const char *const kName = "foo";
+			decl: &ast.VarDecl{
+				Name:        &ast.Ident{Name: "kName"},
+				MangledName: "kName",
+				Type:        &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}},
+				Value:       &ast.BasicLit{Kind: ast.StringLit, Value: `"foo"`},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+const KName = "foo"`,
+		},
+		{
+			name: "variable",
+			// /// Count of errors
+			// extern int errcount;
+			decl: &ast.VarDecl{
+				DeclBase: ast.DeclBase{
+					Doc: &ast.CommentGroup{List: []*ast.Comment{{Text: "/// Count of errors"}}},
+				},
+				Name:        &ast.Ident{Name: "errcount"},
+				MangledName: "errcount",
+				Type:        &ast.BuiltinType{Kind: ast.Int},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+/// Count of errors
+//
+//go:linkname Errcount C.errcount
+var Errcount c.Int`,
+		},
+		{
+			name: "C++ variable",
+			// namespace ns { extern int count; }
+			decl: &ast.VarDecl{
+				DeclBase:    ast.DeclBase{Parent: &ast.Ident{Name: "ns"}},
+				Name:        &ast.Ident{Name: "count"},
+				MangledName: "_ZN2ns5countE",
+				Type:        &ast.BuiltinType{Kind: ast.Int},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+//go:linkname NsCount C._ZN2ns5countE
+var NsCount c.Int`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestDoxygen(t *testing.T) {
+	widgetSizeDoc := &ast.CommentGroup{
+		List: []*ast.Comment{
+			{Text: "/**\n"},
+			{Text: " * @brief Returns the size of a widget_t.\n"},
+			{Text: " * @param w the widget\n"},
+			{Text: " * @param[out] type receives the type of \\p w\n"},
+			{Text: " * @return the size, or -1 on error\n"},
+			{Text: " * @deprecated use widget_len() instead\n"},
+			{Text: " */\n"},
+		},
+		Doxygen: &ast.DocComment{
+			Brief: "Returns the size of a widget_t.",
+			Params: []*ast.DocParam{
+				{Name: "w", Text: "the widget"},
+				{Name: "type", Direction: "out", Text: "receives the type of w"},
+			},
+			Return:     "the size, or -1 on error",
+			Deprecated: &ast.DocBlock{Text: "use widget_len() instead"},
+		},
+	}
+	widgetSize := &ast.FuncDecl{
+		DeclBase: ast.DeclBase{
+			Doc:   widgetSizeDoc,
+			Attrs: []*ast.Attr{{Kind: ast.DeprecatedAttr}},
+		},
+		Name:        &ast.Ident{Name: "widget_size"},
+		MangledName: "widget_size",
+		Type: &ast.FuncType{
+			Params: &ast.FieldList{
+				List: []*ast.Field{
+					{Names: []*ast.Ident{{Name: "w"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}},
+					{Names: []*ast.Ident{{Name: "type"}}, Type: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Int}}},
+				},
+			},
+			Ret: &ast.BuiltinType{Kind: ast.Int},
+		},
+	}
+	widgetSymbs := []config.SymbolEntry{
+		{CppName: "widget_size", MangleName: "widget_size", GoName: "WidgetSize"},
+		{CppName: "widget_len", MangleName: "widget_len", GoName: "WidgetLen"},
+	}
+	testCases := []genDeclTestCase{
+		{
+			name:  "func",
+			decl:  widgetSize,
+			symbs: widgetSymbs,
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+// WidgetSize returns the size of a widget_t.
+//
+// Parameters:
+//   - w: The widget.
+//   - type_ (out): Receives the type of w.
+//
+// It returns the size, or -1 on error.
+//
+// Deprecated: Use [WidgetLen] instead.
+//go:linkname WidgetSize C.widget_size
+func WidgetSize(w unsafe.Pointer, type_ *c.Int) c.Int`,
+		},
+		{
+			name: "type with code block",
+			decl: &ast.TypeDecl{
+				DeclBase: ast.DeclBase{
+					Doc: &ast.CommentGroup{
+						List: []*ast.Comment{{Text: "/// A widget.\n"}},
+						Doxygen: &ast.DocComment{
+							Blocks: []*ast.DocBlock{
+								{Text: "a widget, see widget_size() for its size:"},
+								{Text: "widget_t w;\nwidget_size(&w, NULL);", Code: true},
+							},
+							See: []string{"widget_size()"},
+						},
+					},
+				},
+				Name: &ast.Ident{Name: "widget_t"},
+				Type: &ast.RecordType{
+					Tag:    ast.Struct,
+					Fields: &ast.FieldList{List: []*ast.Field{{Names: []*ast.Ident{{Name: "id"}}, Type: &ast.BuiltinType{Kind: ast.Int}}}},
+				},
+			},
+			symbs: widgetSymbs,
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+// A widget, see [WidgetSize] for its size:
+//
+//	widget_t w;
+//	widget_size(&w, NULL);
+//
+// See also [WidgetSize].
+type WidgetT struct {
+	Id c.Int
+}`,
+		},
+		{
+			name:     "keep raw comments",
+			decl:     widgetSize,
+			symbs:    widgetSymbs,
+			cppgconf: &llcppg.Config{KeepRawComments: true},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+/**
+ * @brief Returns the size of a widget_t.
+ * @param w the widget
+ * @param[out] type receives the type of \p w
+ * @return the size, or -1 on error
+ * @deprecated use widget_len() instead
+ */
+//
+// Deprecated: this declaration is marked deprecated in C.
+//
+//go:linkname WidgetSize C.widget_size
+func WidgetSize(w unsafe.Pointer, type_ *c.Int) c.Int`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestRecordLayout(t *testing.T) {
+	field := func(name string, kind ast.TypeKind, flags ast.TypeFlag, offset int64) *ast.Field {
+		return &ast.Field{
+			Names:  []*ast.Ident{{Name: name}},
+			Type:   &ast.BuiltinType{Kind: kind, Flags: flags},
+			Offset: offset,
+		}
+	}
+	testCases := []genDeclTestCase{
+		{
+			name: "matched",
+			// struct Foo { char a; int b; };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("a", ast.Char, ast.Signed, 0),
+							field("b", ast.Int, 0, 32),
+						},
+					},
+					Size:  8,
+					Align: 4,
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A int8
+	B c.Int
+}`,
+		},
+		{
+			name: "static member",
+			// struct Foo { static int count; char a; int b; };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Names:    []*ast.Ident{{Name: "count"}},
+								Type:     &ast.BuiltinType{Kind: ast.Int},
+								IsStatic: true,
+							},
+							field("a", ast.Char, ast.Signed, 0),
+							field("b", ast.Int, 0, 32),
+						},
+					},
+					Size:  8,
+					Align: 4,
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A int8
+	B c.Int
+}`,
+		},
+		{
+			name: "padding",
+			// struct Foo { char a; _Alignas(8) int b; char c[3] __attribute__((aligned(4))); };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("a", ast.Char, ast.Signed, 0),
+							field("b", ast.Int, 0, 64),
+							field("c", ast.Char, ast.Signed, 128),
+						},
+					},
+					Size:  24,
+					Align: 8,
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	_ [0]uint64
+	A int8
+	_ [7]uint8
+	B c.Int
+	_ [4]uint8
+	C int8
+	_ [7]uint8
+}`,
+		},
+		{
+			name: "union padding",
+			// union Foo { char a; short b; } __attribute__((aligned(8)));
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Union,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("a", ast.Char, ast.Signed, 0),
+							field("b", ast.Int, ast.Short, 0),
+						},
+					},
+					Size:  8,
+					Align: 8,
+				},
+			},
+			expected: `
+package testpkg
+
+import "unsafe"
+// Foo is a union in C, so its members are accessed by methods.
+type Foo struct {
+	_ [1]uint64
+}
+
+func (recv_ *Foo) A() *int8 {
+	return (*int8)(unsafe.Pointer(recv_))
+}
+func (recv_ *Foo) SetA(v int8) {
+	*(*int8)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Foo) B() *int16 {
+	return (*int16)(unsafe.Pointer(recv_))
+}
+func (recv_ *Foo) SetB(v int16) {
+	*(*int16)(unsafe.Pointer(recv_)) = v
+}`,
+		},
+		{
+			name: "packed mismatch",
+			// struct __attribute__((packed)) Foo { char a; int b; };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("a", ast.Char, ast.Signed, 0),
+							field("b", ast.Int, 0, 8),
+						},
+					},
+					Size:   5,
+					Align:  1,
+					Packed: true,
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+// Foo is packed in C, Go can't lay out its fields at the same offsets, so they are accessed by methods.
+type Foo struct {
+	_ [5]uint8
+}
+
+func (recv_ *Foo) A() *int8 {
+	return (*int8)(unsafe.Pointer(recv_))
+}
+
+func (recv_ *Foo) B() *c.Int {
+	return (*c.Int)(unsafe.Add(unsafe.Pointer(recv_), 1))
+}`,
+		},
+		{
+			name: "packed typedef",
+			// #pragma pack(2)
+			// /* header */
+			// typedef struct { short tag; long long len; char flag; } Header;
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "Header"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("tag", ast.Int, ast.Short, 0),
+							field("len", ast.Int, ast.LongLong, 16),
+							field("flag", ast.Char, ast.Signed, 80),
+						},
+					},
+					Size:   12,
+					Align:  2,
+					Packed: true,
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+// Header is packed in C, Go can't lay out its fields at the same offsets, so they are accessed by methods.
+type Header struct {
+	_ [6]uint16
+}
+
+func (recv_ *Header) Tag() *int16 {
+	return (*int16)(unsafe.Pointer(recv_))
+}
+
+func (recv_ *Header) Len() *c.LongLong {
+	return (*c.LongLong)(unsafe.Add(unsafe.Pointer(recv_), 2))
+}
+
+func (recv_ *Header) Flag() *int8 {
+	return (*int8)(unsafe.Add(unsafe.Pointer(recv_), 10))
+}`,
+		},
+		{
+			name: "over aligned",
+			// struct Foo { alignas(32) int a; };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("a", ast.Int, 0, 0),
+						},
+					},
+					Size:  32,
+					Align: 32,
+				},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+// Foo is aligned to 32 bytes in C, more than Go can align it.
+type Foo struct {
+	A c.Int
+	_ [28]uint8
+}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestTargetLayout(t *testing.T) {
+	field := func(name string, typ ast.Expr, offset int64) *ast.Field {
+		return &ast.Field{
+			Names:  []*ast.Ident{{Name: name}},
+			Type:   typ,
+			Offset: offset,
+		}
+	}
+	testCases := []genDeclTestCase{
+		{
+			name: "arm",
+			// struct Foo { long a; void *p; long long c; wchar_t w; };
+			// long long is aligned to 8 bytes by the C ABI of arm, not to a word
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("a", &ast.BuiltinType{Kind: ast.Int, Flags: ast.Long}, 0),
+							field("p", &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Void}}, 32),
+							field("c", &ast.BuiltinType{Kind: ast.Int, Flags: ast.LongLong}, 64),
+							field("w", &ast.BuiltinType{Kind: ast.WChar}, 128),
+						},
+					},
+					Size:  24,
+					Align: 8,
+				},
+			},
+			cppgconf: &llcppg.Config{
+				Target: &llcppg.Target{GOOS: "linux", GOARCH: "arm"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+type Foo struct {
+	A c.Long
+	P unsafe.Pointer
+	C c.LongLong
+	W int32
+}`,
+		},
+		{
+			name: "386",
+			// struct Foo { int a; long long c; };
+			// long long is aligned to 4 bytes by the System V ABI of 386
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("a", &ast.BuiltinType{Kind: ast.Int}, 0),
+							field("c", &ast.BuiltinType{Kind: ast.Int, Flags: ast.LongLong}, 32),
+						},
+					},
+					Size:  12,
+					Align: 4,
+				},
+			},
+			cppgconf: &llcppg.Config{
+				Target: &llcppg.Target{GOOS: "linux", GOARCH: "386"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A c.Int
+	C c.LongLong
+}`,
+		},
+		{
+			name: "windows",
+			// struct Foo { long a; int b; wchar_t w; };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("a", &ast.BuiltinType{Kind: ast.Int, Flags: ast.Long}, 0),
+							field("b", &ast.BuiltinType{Kind: ast.Int}, 32),
+							field("w", &ast.BuiltinType{Kind: ast.WChar}, 64),
+						},
+					},
+					Size:  12,
+					Align: 4,
+				},
+			},
+			cppgconf: &llcppg.Config{
+				Target: &llcppg.Target{GOOS: "windows", GOARCH: "amd64"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Foo struct {
+	A c.Long
+	B c.Int
+	W uint16
+}`,
+		},
+		{
+			name: "zero-length array",
+			// struct Foo { int n; char data[0]; };
+			// Go pads a struct ending with a zero-size field, C doesn't
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("n", &ast.BuiltinType{Kind: ast.Int}, 0),
+							field("data", &ast.ArrayType{Elt: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}, Len: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}}, 32),
+						},
+					},
+					Size:  4,
+					Align: 4,
+				},
+			},
+			cppgconf: &llcppg.Config{
+				Target: &llcppg.Target{GOOS: "linux", GOARCH: "amd64"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+// Foo has a layout in C that Go can't match, so its fields are accessed by methods.
+type Foo struct {
+	_ [1]uint32
+}
+
+func (recv_ *Foo) N() *c.Int {
+	return (*c.Int)(unsafe.Pointer(recv_))
+}
+
+func (recv_ *Foo) Data() *[0]int8 {
+	return (*[0]int8)(unsafe.Add(unsafe.Pointer(recv_), 4))
+}`,
+		},
+		{
+			name: "int128",
+			// struct Foo { int a; __int128 b; long double c; };
+			// __int128 and long double are aligned to 16 bytes in C, Go aligns them to 8
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Foo"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{
+						List: []*ast.Field{
+							field("a", &ast.BuiltinType{Kind: ast.Int}, 0),
+							field("b", &ast.BuiltinType{Kind: ast.Int128}, 128),
+							field("c", &ast.BuiltinType{Kind: ast.Float, Flags: ast.Long | ast.Double}, 256),
+						},
+					},
+					Size:  48,
+					Align: 16,
+				},
+			},
+			cppgconf: &llcppg.Config{
+				Target: &llcppg.Target{GOOS: "linux", GOARCH: "amd64"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+// Foo is aligned to 16 bytes in C, more than Go can align it.
+type Foo struct {
+	A c.Int
+	_ [12]uint8
+	B Int128
+	C LongDouble
+}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestExtBuiltin(t *testing.T) {
+	int128 := &ast.BuiltinType{Kind: ast.Int128}
+	longDouble := &ast.BuiltinType{Kind: ast.Float, Flags: ast.Long | ast.Double}
+	param := func(name string, typ ast.Expr) *ast.Field {
+		return &ast.Field{Names: []*ast.Ident{{Name: name}}, Type: typ}
+	}
+	symbs := []config.SymbolEntry{{CppName: "foo", MangleName: "foo", GoName: "Foo"}}
+	testCases := []genDeclTestCase{
+		{
+			name: "long double amd64",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "Real"},
+				Type: longDouble,
+			},
+			cppgconf: &llcppg.Config{
+				Target: &llcppg.Target{GOOS: "linux", GOARCH: "amd64"},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+type Real LongDouble`,
+		},
+		{
+			name: "long double windows",
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "Real"},
+				Type: longDouble,
+			},
+			cppgconf: &llcppg.Config{
+				Target: &llcppg.Target{GOOS: "windows", GOARCH: "amd64"},
+			},
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+type Real float64`,
+		},
+		{
+			name: "by pointer",
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "foo",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{List: []*ast.Field{
+						param("a", &ast.PointerType{X: int128}),
+						param("b", &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Float16}}),
+					}},
+					Ret: &ast.BuiltinType{Kind: ast.Void},
+				},
+			},
+			symbs: symbs,
+			expected: `
+package testpkg
+
+import _ "unsafe"
+
+//go:linkname Foo C.foo
+func Foo(a *Int128, b *Float16)`,
+		},
+		{
+			name: "by value",
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "foo",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{List: []*ast.Field{param("a", int128)}},
+					Ret:    &ast.BuiltinType{Kind: ast.Void},
+				},
+			},
+			symbs:       symbs,
+			expectedErr: "unsupported function foo: __int128 is passed by value",
+		},
+		{
+			name: "result by value",
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "foo",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{},
+					Ret:    &ast.BuiltinType{Kind: ast.Float128},
+				},
+			},
+			symbs:       symbs,
+			expectedErr: "unsupported function foo: __float128 is passed by value",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+
+	t.Run("typedef by value", func(t *testing.T) {
+		pkg := createTestPkg(t, &convert.PackageConfig{
+			SymbolTable: config.CreateSymbolTable(symbs),
+			PkgBase: convert.PkgBase{
+				CppgConf: &llcppg.Config{
+					Target: &llcppg.Target{GOOS: "linux", GOARCH: "arm64"},
+				},
+			},
+		})
+		pkg.SetCurFile(tempFile)
+		err := pkg.NewTypedefDecl(&ast.TypedefDecl{Name: &ast.Ident{Name: "Real"}, Type: longDouble})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = pkg.NewFuncDecl(&ast.FuncDecl{
+			Name:        &ast.Ident{Name: "foo"},
+			MangledName: "foo",
+			Type: &ast.FuncType{
+				Params: &ast.FieldList{List: []*ast.Field{param("a", &ast.Ident{Name: "Real"})}},
+				Ret:    &ast.BuiltinType{Kind: ast.Void},
+			},
+		})
+		compareError(t, err, "unsupported function foo: long double is passed by value")
+	})
+}
+
+func TestWriteBuiltinFile(t *testing.T) {
+	targets := []*llcppg.Target{
+		{GOOS: "linux", GOARCH: "amd64"},
+		{GOOS: "linux", GOARCH: "386"},
+		{GOOS: "linux", GOARCH: "arm64"},
+		{GOOS: "linux", GOARCH: "ppc64le"},
+	}
+	kinds := []*ast.BuiltinType{
+		{Kind: ast.Int128},
+		{Kind: ast.Int128, Flags: ast.Unsigned},
+		{Kind: ast.Float16},
+		{Kind: ast.Float128},
+		{Kind: ast.Float, Flags: ast.Long | ast.Double},
+	}
+	writeBuiltinFile := func(t *testing.T, target *llcppg.Target, kinds []*ast.BuiltinType) string {
+		t.Helper()
+		tempDir, err := os.MkdirTemp(dir, "test_package_builtin")
+		if err != nil {
+			t.Fatalf("Failed to create temporary directory: %v", err)
+		}
+		defer os.RemoveAll(tempDir)
+		pkg := createTestPkg(t, &convert.PackageConfig{
+			OutputDir: tempDir,
+			PkgBase: convert.PkgBase{
+				CppgConf: &llcppg.Config{Target: target},
+			},
+		})
+		pkg.SetCurFile(tempFile)
+		for _, kind := range kinds {
+			if _, err := pkg.ToType(kind); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = pkg.WriteBuiltinFile(); err != nil {
+			t.Fatal(err)
+		}
+		content, err := os.ReadFile(filepath.Join(tempDir, "testpkg_autogen_builtin.go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+	for _, target := range targets {
+		t.Run(target.GOARCH, func(t *testing.T) {
+			content := writeBuiltinFile(t, target, kinds)
+			fset := token.NewFileSet()
+			file, err := parser.ParseFile(fset, "testpkg_autogen_builtin.go", content, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+			if _, err := conf.Check("testpkg", fset, []*goast.File{file}, nil); err != nil {
+				t.Errorf("%v:\n%s", err, content)
+			}
+		})
+	}
+	t.Run("int128", func(t *testing.T) {
+		content := writeBuiltinFile(t, targets[0], kinds[:1])
+		expected := `package testpkg
+// Int128 is the C __int128, the low 64 bits first.
+type Int128 [2]uint64
+// NewInt128 returns the Int128 of i.
+func NewInt128(i int64) Int128 {
+	return Int128{uint64(i), uint64(i >> 63)}
+}
+// Int64 returns the low 64 bits of v.
+func (v Int128) Int64() int64 {
+	return int64(v[0])
+}
+`
+		if content != expected {
+			t.Errorf("unexpected output:\n%s", content)
+		}
+	})
+}
+
+func TestVerifyLayout(t *testing.T) {
+	compiler, err := exec.LookPath("clang")
+	if err != nil {
+		if compiler, err = exec.LookPath("cc"); err != nil {
+			t.Skip("no C compiler")
+		}
+	}
+	hdir := t.TempDir()
+	err = os.WriteFile(filepath.Join(hdir, "layout.h"), []byte(`
+struct Foo { char a; int b; };
+struct Bar { char a; long long b; };
+struct __attribute__((packed)) Packed { char a; int b; };
+typedef struct { short x; } Point;
+enum Color { Red, Green };
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	field := func(name string, kind ast.TypeKind, flags ast.TypeFlag, offset int64) *ast.Field {
+		return &ast.Field{
+			Names:  []*ast.Ident{{Name: name}},
+			Type:   &ast.BuiltinType{Kind: kind, Flags: flags},
+			Offset: offset,
+		}
+	}
+	record := func(size, align int64, packed bool, fields ...*ast.Field) *ast.RecordType {
+		return &ast.RecordType{
+			Tag:    ast.Struct,
+			Fields: &ast.FieldList{List: fields},
+			Size:   size,
+			Align:  align,
+			Packed: packed,
+		}
+	}
+	pkg := createTestPkg(t, &convert.PackageConfig{})
+	pkg.SetCurFile(tempFile)
+	decls := []ast.Decl{
+		&ast.TypeDecl{
+			Name: &ast.Ident{Name: "Foo"},
+			Type: record(8, 4, false, field("a", ast.Char, ast.Signed, 0), field("b", ast.Int, 0, 32)),
+		},
+		// the layout of Bar is wrong, as if long long were an int
+		&ast.TypeDecl{
+			Name: &ast.Ident{Name: "Bar"},
+			Type: record(8, 4, false, field("a", ast.Char, ast.Signed, 0), field("b", ast.Int, 0, 32)),
+		},
+		&ast.TypeDecl{
+			Name: &ast.Ident{Name: "Packed"},
+			Type: record(5, 1, true, field("a", ast.Char, ast.Signed, 0), field("b", ast.Int, 0, 8)),
+		},
+		&ast.TypedefDecl{
+			Name: &ast.Ident{Name: "Point"},
+			Type: record(2, 2, false, field("x", ast.Int, ast.Short, 0)),
+		},
+		&ast.EnumTypeDecl{
+			Name: &ast.Ident{Name: "Color"},
+			Type: &ast.EnumType{Items: []*ast.EnumItem{
+				{Name: &ast.Ident{Name: "Red"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}},
+				{Name: &ast.Ident{Name: "Green"}, Value: &ast.BasicLit{Kind: ast.IntLit, Value: "1"}},
+			}},
+		},
+	}
+	for _, decl := range decls {
+		var err error
+		switch decl := decl.(type) {
+		case *ast.TypeDecl:
+			err = pkg.NewTypeDecl(decl)
+		case *ast.TypedefDecl:
+			err = pkg.NewTypedefDecl(decl)
+		case *ast.EnumTypeDecl:
+			err = pkg.NewEnumTypeDecl(decl)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mismatches, err := pkg.VerifyLayout(&convert.LayoutConfig{
+		Include:  []string{"layout.h"},
+		CFlags:   []string{"-I" + hdir},
+		Compiler: compiler,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, m.String())
+	}
+	want := []string{
+		"Bar (struct Bar): size 16 in C, 8 in Go",
+		"Bar (struct Bar): align 8 in C, 4 in Go",
+		"Bar.b (struct Bar): offset 8 in C, 4 in Go",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("mismatches:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAnonRecord(t *testing.T) {
+	field := func(name string, typ ast.Expr) *ast.Field {
+		f := &ast.Field{Type: typ}
+		if name != "" {
+			f.Names = []*ast.Ident{{Name: name}}
+		}
+		return f
+	}
+	record := func(tag ast.Tag, fields ...*ast.Field) *ast.RecordType {
+		return &ast.RecordType{Tag: tag, Fields: &ast.FieldList{List: fields}}
+	}
+	intType := &ast.BuiltinType{Kind: ast.Int}
+	floatType := &ast.BuiltinType{Kind: ast.Float}
+	testCases := []genDeclTestCase{
+		{
+			name: "named and anonymous members",
+			// struct Outer { union { int i; float f; } u; struct { int x, y; }; };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Outer"},
+				Type: record(ast.Struct,
+					field("u", record(ast.Union, field("i", intType), field("f", floatType))),
+					field("", record(ast.Struct, field("x", intType), field("y", intType))),
+				),
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+type Outer struct {
+	U     Outer_U
+	Anon0 Outer_Anon0
+}
+// Outer_U is a union in C, so its members are accessed by methods.
+type Outer_U struct {
+	_ [1]uint32
+}
+
+func (recv_ *Outer_U) I() *c.Int {
+	return (*c.Int)(unsafe.Pointer(recv_))
+}
+func (recv_ *Outer_U) SetI(v c.Int) {
+	*(*c.Int)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Outer_U) F() *float32 {
+	return (*float32)(unsafe.Pointer(recv_))
+}
+func (recv_ *Outer_U) SetF(v float32) {
+	*(*float32)(unsafe.Pointer(recv_)) = v
+}
+
+type Outer_Anon0 struct {
+	X c.Int
+	Y c.Int
+}
+
+func (recv_ *Outer) X() *c.Int {
+	return &recv_.Anon0.X
+}
+func (recv_ *Outer) Y() *c.Int {
+	return &recv_.Anon0.Y
+}`,
+		},
+		{
+			name: "anonymous union member",
+			// typedef struct { int kind; union { int i; float f; }; } Value;
+			decl: &ast.TypedefDecl{
+				Name: &ast.Ident{Name: "Value"},
+				Type: record(ast.Struct,
+					field("kind", intType),
+					field("", record(ast.Union, field("i", intType), field("f", floatType))),
+				),
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+type Value struct {
+	Kind  c.Int
+	Anon0 Value_Anon0
+}
+// Value_Anon0 is a union in C, so its members are accessed by methods.
+type Value_Anon0 struct {
+	_ [1]uint32
+}
+
+func (recv_ *Value_Anon0) I() *c.Int {
+	return (*c.Int)(unsafe.Pointer(recv_))
+}
+func (recv_ *Value_Anon0) SetI(v c.Int) {
+	*(*c.Int)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Value_Anon0) F() *float32 {
+	return (*float32)(unsafe.Pointer(recv_))
+}
+func (recv_ *Value_Anon0) SetF(v float32) {
+	*(*float32)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Value) I() *c.Int {
+	return recv_.Anon0.I()
+}
+func (recv_ *Value) F() *float32 {
+	return recv_.Anon0.F()
+}`,
+		},
+		{
+			name: "nested anonymous members",
+			// union Nest { struct { union { int a; float b; }; int c; }; float d; };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Nest"},
+				Type: record(ast.Union,
+					field("", record(ast.Struct,
+						field("", record(ast.Union, field("a", intType), field("b", floatType))),
+						field("c", intType),
+					)),
+					field("d", floatType),
+				),
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	"unsafe"
+)
+
+// Nest is a union in C, so its members are accessed by methods.
+type Nest struct {
+	_ [2]uint32
+}
+
+type Nest_Anon0 struct {
+	Anon0 Nest_Anon0_Anon0
+	C     c.Int
+}
+// Nest_Anon0_Anon0 is a union in C, so its members are accessed by methods.
+type Nest_Anon0_Anon0 struct {
+	_ [1]uint32
+}
+
+func (recv_ *Nest_Anon0_Anon0) A() *c.Int {
+	return (*c.Int)(unsafe.Pointer(recv_))
+}
+func (recv_ *Nest_Anon0_Anon0) SetA(v c.Int) {
+	*(*c.Int)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Nest_Anon0_Anon0) B() *float32 {
+	return (*float32)(unsafe.Pointer(recv_))
+}
+func (recv_ *Nest_Anon0_Anon0) SetB(v float32) {
+	*(*float32)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Nest_Anon0) A() *c.Int {
+	return recv_.Anon0.A()
+}
+func (recv_ *Nest_Anon0) B() *float32 {
+	return recv_.Anon0.B()
+}
+func (recv_ *Nest) Anon0() *Nest_Anon0 {
+	return (*Nest_Anon0)(unsafe.Pointer(recv_))
+}
+func (recv_ *Nest) SetAnon0(v Nest_Anon0) {
+	*(*Nest_Anon0)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Nest) D() *float32 {
+	return (*float32)(unsafe.Pointer(recv_))
+}
+func (recv_ *Nest) SetD(v float32) {
+	*(*float32)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Nest) C() *c.Int {
+	return &(*Nest_Anon0)(unsafe.Pointer(recv_)).C
+}
+func (recv_ *Nest) A() *c.Int {
+	return (*Nest_Anon0)(unsafe.Pointer(recv_)).A()
+}
+func (recv_ *Nest) B() *float32 {
+	return (*Nest_Anon0)(unsafe.Pointer(recv_)).B()
+}`,
+		},
+		{
+			name: "promoted name taken",
+			// struct Dup { int x; struct { int x; int y; }; };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Dup"},
+				Type: record(ast.Struct,
+					field("x", intType),
+					field("", record(ast.Struct, field("x", intType), field("y", intType))),
+				),
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+type Dup struct {
+	X     c.Int
+	Anon0 Dup_Anon0
+}
+type Dup_Anon0 struct {
+	X c.Int
+	Y c.Int
+}
+
+func (recv_ *Dup) Y() *c.Int {
+	return &recv_.Anon0.Y
+}`,
+		},
+		{
+			name: "layout",
+			// struct Pad { char c; union { char a; double d; }; };
+			decl: &ast.TypeDecl{
+				Name: &ast.Ident{Name: "Pad"},
+				Type: &ast.RecordType{
+					Tag: ast.Struct,
+					Fields: &ast.FieldList{List: []*ast.Field{
+						{Names: []*ast.Ident{{Name: "c"}}, Type: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}},
+						{
+							Type: &ast.RecordType{
+								Tag: ast.Union,
+								Fields: &ast.FieldList{List: []*ast.Field{
+									field("a", &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}),
+									field("d", &ast.BuiltinType{Kind: ast.Float, Flags: ast.Double}),
+								}},
+								Size:  8,
+								Align: 8,
+							},
+							Offset: 64,
+						},
+					}},
+					Size:  16,
+					Align: 8,
+				},
+			},
+			expected: `
+package testpkg
+
+import "unsafe"
+
+type Pad struct {
+	C     int8
+	Anon0 Pad_Anon0
+}
+// Pad_Anon0 is a union in C, so its members are accessed by methods.
+type Pad_Anon0 struct {
+	_ [1]uint64
+}
+
+func (recv_ *Pad_Anon0) A() *int8 {
+	return (*int8)(unsafe.Pointer(recv_))
+}
+func (recv_ *Pad_Anon0) SetA(v int8) {
+	*(*int8)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Pad_Anon0) D() *float64 {
+	return (*float64)(unsafe.Pointer(recv_))
+}
+func (recv_ *Pad_Anon0) SetD(v float64) {
+	*(*float64)(unsafe.Pointer(recv_)) = v
+}
+func (recv_ *Pad) A() *int8 {
+	return recv_.Anon0.A()
+}
+func (recv_ *Pad) D() *float64 {
+	return recv_.Anon0.D()
+}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
+}
+
+func TestDefaultArgs(t *testing.T) {
+	param := func(name string, typ ast.Expr, def ast.Expr, tokens ...*ast.Token) *ast.Field {
+		return &ast.Field{
+			Names:         []*ast.Ident{{Name: name}},
+			Type:          typ,
+			Default:       def,
+			DefaultTokens: tokens,
+		}
+	}
+	intType := &ast.BuiltinType{Kind: ast.Int}
+	charPtr := &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}}
+	testCases := []genDeclTestCase{
+		{
+			name: "trailing defaults",
+			// int open(const char *path, int mode = 0644, int flags = -1);
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "open"},
+				MangledName: "_Z4openPKcii",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							param("path", charPtr, nil),
+							param("mode", intType, &ast.BasicLit{Kind: ast.IntLit, Value: "0644"}),
+							param("flags", intType, &ast.BasicLit{Kind: ast.IntLit, Value: "-1"}),
+						},
+					},
+					Ret: intType,
+				},
+			},
+			symbs: []config.SymbolEntry{
+				{CppName: "open(const char *, int, int)", MangleName: "_Z4openPKcii", GoName: "Open"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+//go:linkname Open C.open
+func Open(path *int8, mode c.Int, flags c.Int) c.Int
+
+// OpenDefault calls Open with the default arguments: mode = 0644, flags = -1.
+func OpenDefault(path *int8) c.Int {
+	return Open(path, 0644, -1)
+}`,
+		},
+		{
+			name: "partial defaults",
+			// void log(int level = LEVEL, const char *msg = nullptr, bool flush = true);
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "log"},
+				MangledName: "_Z3logiPKcb",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							param("level", intType, nil, &ast.Token{Token: ctoken.IDENT, Lit: "LEVEL"}),
+							param("msg", charPtr, nil, &ast.Token{Token: ctoken.KEYWORD, Lit: "nullptr"}),
+							param("flush", &ast.BuiltinType{Kind: ast.Bool}, nil, &ast.Token{Token: ctoken.KEYWORD, Lit: "true"}),
+						},
+					},
+					Ret: &ast.BuiltinType{Kind: ast.Void},
+				},
+			},
+			symbs: []config.SymbolEntry{
+				{CppName: "log(int, const char *, bool)", MangleName: "_Z3logiPKcb", GoName: "Log"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+//go:linkname Log C.log
+func Log(level c.Int, msg *int8, flush bool)
+
+// LogDefault calls Log with the default arguments: msg = nullptr, flush = true.
+func LogDefault(level c.Int) {
+	Log(level, nil, true)
+}`,
+		},
+		{
+			name: "nonnull nil default",
+			// void log(int level, const char *_Nonnull msg = nullptr);
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "log"},
+				MangledName: "_Z3logiPKc",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							param("level", intType, nil),
+							param("msg", &ast.PointerType{X: charPtr.X, Nullability: ast.Nonnull}, nil, &ast.Token{Token: ctoken.KEYWORD, Lit: "nullptr"}),
+						},
+					},
+					Ret: &ast.BuiltinType{Kind: ast.Void},
+				},
+			},
+			symbs: []config.SymbolEntry{
+				{CppName: "log(int, const char *)", MangleName: "_Z3logiPKc", GoName: "Log"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+// The pointer argument msg must not be nil.
+//
+//go:linkname Log C.log
+func Log(level c.Int, msg *int8)`,
+		},
+		{
+			name: "overloaded",
+			// void foo(int a = 1); void foo(const char *s);
+			decl: &ast.FuncDecl{
+				Name:        &ast.Ident{Name: "foo"},
+				MangledName: "_Z3fooi",
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{
+						List: []*ast.Field{
+							param("a", intType, &ast.BasicLit{Kind: ast.IntLit, Value: "1"}),
+						},
+					},
+					Ret: &ast.BuiltinType{Kind: ast.Void},
+				},
+			},
+			symbs: []config.SymbolEntry{
+				{CppName: "foo(int)", MangleName: "_Z3fooi", GoName: "Foo"},
+				{CppName: "foo(const char *)", MangleName: "_Z3fooPKc", GoName: "Foo__1"},
+			},
+			expected: `
+package testpkg
+
+import (
+	"github.com/goplus/llgo/c"
+	_ "unsafe"
+)
+
+//go:linkname Foo C.foo
+func Foo(a c.Int)`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			testGenDecl(t, tc)
+		})
+	}
 }
 
 type genDeclTestCase struct {
@@ -1669,6 +4916,10 @@ func testGenDecl(t *testing.T, tc genDeclTestCase) {
 		err = pkg.NewFuncDecl(d)
 	case *ast.EnumTypeDecl:
 		err = pkg.NewEnumTypeDecl(d)
+	case *ast.InstantiationDecl:
+		err = pkg.NewInstantiationDecl(d)
+	case *ast.VarDecl:
+		err = pkg.NewVarDecl(d)
 	default:
 		t.Errorf("Unsupported declaration type: %T", tc.decl)
 		return
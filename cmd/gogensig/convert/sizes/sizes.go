@@ -1,22 +1,149 @@
 package sizes
 
 import (
+	"fmt"
 	"go/types"
-	"runtime"
 )
 
-var (
-	std types.Sizes
-)
+// Sizes are the sizes of the types in C on a target.
+type Sizes interface {
+	types.Sizes
+	// SetAlign records the alignment in C of a named type, which is more than
+	// Go gives its underlying type, like __int128 declared as [2]uint64.
+	SetAlign(T *types.Named, align int64)
+	// Go returns the sizes of the types declared in Go, which ignore the
+	// recorded alignments and pad a struct ending with a zero-size field.
+	Go() types.Sizes
+}
 
-func init() {
-	if runtime.Compiler == "gopherjs" {
-		std = &types.StdSizes{WordSize: 4, MaxAlign: 4}
-	} else {
-		std = types.SizesFor(runtime.Compiler, runtime.GOARCH)
+// For returns the sizes of the types on a target, whose C long is 4 bytes on
+// windows and a word on the other systems.
+func For(goos, goarch string) (Sizes, error) {
+	sizes := types.SizesFor("gc", goarch)
+	if sizes == nil {
+		return nil, fmt.Errorf("unsupported target architecture %s", goarch)
 	}
+	long := sizes.Sizeof(types.Typ[types.Uintptr])
+	if goos == "windows" {
+		long = 4
+	}
+	return &targetSizes{Sizes: sizes, long: long, align8: align8(goos, goarch), aligns: make(map[*types.Named]int64)}, nil
 }
 
-func Sizeof(T types.Type) int64 {
-	return std.Sizeof(T)
+// align8 returns the alignment of the 8-byte scalars in C, like long long and
+// double. gc aligns them to a word, but the C ABIs of the 32-bit architectures
+// align them to 8, except the System V ABI of 386.
+func align8(goos, goarch string) int64 {
+	switch goarch {
+	case "386":
+		if goos == "windows" {
+			return 8
+		}
+		return 4
+	}
+	return 8
+}
+
+// targetSizes are the sizes of the types in C on a target. The C long of the
+// llgo c package is declared with the size of the host, so its size is the one
+// of the target instead.
+type targetSizes struct {
+	types.Sizes
+	long   int64
+	align8 int64
+	aligns map[*types.Named]int64 // alignments recorded by SetAlign
+	golang bool                   // sizes of the types in Go instead of C
+}
+
+func (s *targetSizes) SetAlign(T *types.Named, align int64) {
+	s.aligns[T] = align
+}
+
+func (s *targetSizes) Go() types.Sizes {
+	golang := *s
+	golang.golang = true
+	return &golang
+}
+
+func (s *targetSizes) Alignof(T types.Type) int64 {
+	if s.isLong(T) {
+		return s.long
+	}
+	if named, ok := T.(*types.Named); ok && !s.golang {
+		if align, ok := s.aligns[named]; ok {
+			return align
+		}
+	}
+	switch t := T.Underlying().(type) {
+	case *types.Array:
+		return s.Alignof(t.Elem())
+	case *types.Struct:
+		max := int64(1)
+		for i := 0; i < t.NumFields(); i++ {
+			if a := s.Alignof(t.Field(i).Type()); a > max {
+				max = a
+			}
+		}
+		return max
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Int64, types.Uint64, types.Float64, types.Complex128:
+			return s.align8
+		}
+	}
+	return s.Sizes.Alignof(T)
+}
+
+func (s *targetSizes) Offsetsof(fields []*types.Var) []int64 {
+	offsets := make([]int64, len(fields))
+	var off int64
+	for i, f := range fields {
+		a := s.Alignof(f.Type())
+		off = (off + a - 1) / a * a
+		offsets[i] = off
+		off += s.Sizeof(f.Type())
+	}
+	return offsets
+}
+
+func (s *targetSizes) Sizeof(T types.Type) int64 {
+	switch t := T.Underlying().(type) {
+	case *types.Array:
+		if t.Len() <= 0 {
+			return 0
+		}
+		return s.Sizeof(t.Elem()) * t.Len()
+	case *types.Struct:
+		n := t.NumFields()
+		if n == 0 {
+			return 0
+		}
+		fields := make([]*types.Var, n)
+		for i := range fields {
+			fields[i] = t.Field(i)
+		}
+		off := s.Offsetsof(fields)[n-1]
+		size := s.Sizeof(fields[n-1].Type())
+		// the last field of a non-zero-sized struct can't have size 0 in Go
+		// as a pointer to it would point past the struct, C has no such rule
+		if s.golang && off > 0 && size == 0 {
+			size = 1
+		}
+		a := s.Alignof(T)
+		return (off + size + a - 1) / a * a
+	}
+	if s.isLong(T) {
+		return s.long
+	}
+	return s.Sizes.Sizeof(T)
+}
+
+// isLong reports whether T is the C long or unsigned long of the llgo c package.
+func (s *targetSizes) isLong(T types.Type) bool {
+	named, ok := T.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != "github.com/goplus/llgo/c" {
+		return false
+	}
+	name := named.Obj().Name()
+	return name == "Long" || name == "Ulong"
 }
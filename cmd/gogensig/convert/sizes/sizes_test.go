@@ -0,0 +1,88 @@
+package sizes_test
+
+import (
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/goplus/llcppg/cmd/gogensig/convert/sizes"
+)
+
+func TestSizes(t *testing.T) {
+	field := func(name string, typ types.Type) *types.Var {
+		return types.NewField(token.NoPos, nil, name, typ, false)
+	}
+	// struct { int n; char data[0]; }
+	zeroTail := types.NewStruct([]*types.Var{
+		field("N", types.Typ[types.Int32]),
+		field("Data", types.NewArray(types.Typ[types.Int8], 0)),
+	}, nil)
+	// struct { int a; long long b; }
+	longLong := types.NewStruct([]*types.Var{
+		field("A", types.Typ[types.Int32]),
+		field("B", types.Typ[types.Int64]),
+	}, nil)
+	testCases := []struct {
+		name         string
+		goos, goarch string
+		typ          types.Type
+		size, align  int64
+		goSize       int64
+	}{
+		{"zero-size tail", "linux", "amd64", zeroTail, 4, 4, 8},
+		{"long long amd64", "linux", "amd64", longLong, 16, 8, 16},
+		{"long long 386", "linux", "386", longLong, 12, 4, 12},
+		{"long long windows 386", "windows", "386", longLong, 16, 8, 16},
+		{"long long arm", "linux", "arm", longLong, 16, 8, 16},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := sizes.For(tc.goos, tc.goarch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := s.Sizeof(tc.typ); size != tc.size {
+				t.Errorf("Sizeof() = %d, want %d", size, tc.size)
+			}
+			if align := s.Alignof(tc.typ); align != tc.align {
+				t.Errorf("Alignof() = %d, want %d", align, tc.align)
+			}
+			if size := s.Go().Sizeof(tc.typ); size != tc.goSize {
+				t.Errorf("Go().Sizeof() = %d, want %d", size, tc.goSize)
+			}
+		})
+	}
+}
+
+func TestForUnsupported(t *testing.T) {
+	if _, err := sizes.For("linux", "vax"); err == nil {
+		t.Error("expected an error for an unsupported architecture")
+	}
+}
+
+func TestSetAlign(t *testing.T) {
+	s, err := sizes.For("linux", "amd64")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := types.NewTypeName(token.NoPos, nil, "Int128", nil)
+	int128 := types.NewNamed(obj, types.NewArray(types.Typ[types.Uint64], 2), nil)
+	s.SetAlign(int128, 16)
+	// struct { int a; __int128 b; }
+	typ := types.NewStruct([]*types.Var{
+		types.NewField(token.NoPos, nil, "A", types.Typ[types.Int32], false),
+		types.NewField(token.NoPos, nil, "B", int128, false),
+	}, nil)
+	if align := s.Alignof(typ); align != 16 {
+		t.Errorf("Alignof() = %d, want 16", align)
+	}
+	if size := s.Sizeof(typ); size != 32 {
+		t.Errorf("Sizeof() = %d, want 32", size)
+	}
+	if align := s.Go().Alignof(typ); align != 8 {
+		t.Errorf("Go().Alignof() = %d, want 8", align)
+	}
+	if size := s.Go().Sizeof(typ); size != 24 {
+		t.Errorf("Go().Sizeof() = %d, want 24", size)
+	}
+}
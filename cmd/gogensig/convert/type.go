@@ -9,12 +9,13 @@ import (
 	"go/token"
 	"go/types"
 	"log"
-	"unsafe"
+	"strings"
 
 	"github.com/goplus/gogen"
 	"github.com/goplus/llcppg/_xtool/llcppsymg/names"
 	"github.com/goplus/llcppg/ast"
 	"github.com/goplus/llcppg/cmd/gogensig/convert/sizes"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
 	"github.com/goplus/llcppg/cmd/gogensig/errs"
 )
 
@@ -22,6 +23,7 @@ type TypeContext int
 
 var (
 	ErrTypeConv = errors.New("error convert type")
+	ErrLayout   = errors.New("record layout mismatch")
 )
 
 const (
@@ -33,16 +35,19 @@ const (
 type TypeConv struct {
 	pkg     *Package
 	typeMap *BuiltinTypeMap
+	sizes   sizes.Sizes // sizes of the types on the target
 	ctx     TypeContext
 }
 
-func NewConv(p *Package) *TypeConv {
+func NewConv(p *Package, sizes sizes.Sizes) *TypeConv {
 	clib := p.p.Import("github.com/goplus/llgo/c")
 	math := p.p.Import("math")
 	typeMap := NewBuiltinTypeMapWithPkgRefS(clib, math, p.p.Unsafe())
+	typeMap.SetTarget(p.conf.CppgConf.Target.Goos(), p.conf.CppgConf.Target.Goarch())
 	typeConv := &TypeConv{
 		typeMap: typeMap,
 		pkg:     p,
+		sizes:   sizes,
 	}
 	return typeConv
 }
@@ -52,14 +57,23 @@ func (p *TypeConv) ToType(expr ast.Expr) (types.Type, error) {
 	switch t := expr.(type) {
 	case *ast.BuiltinType:
 		typ, err := p.typeMap.FindBuiltinType(*t)
+		if err != nil {
+			// a builtin type Go has no type of the same size for
+			return p.pkg.extBuiltinType(t)
+		}
 		return typ, err
 	case *ast.PointerType:
 		return p.handlePointerType(t)
+	case *ast.LvalueRefType:
+		// a reference is passed as a pointer,like the operands of a C++ operator
+		return p.handlePointerType(&ast.PointerType{X: t.X})
+	case *ast.RvalueRefType:
+		return p.handlePointerType(&ast.PointerType{X: t.X})
 	case *ast.ArrayType:
 		return p.handleArrayType(t)
 	case *ast.FuncType:
 		return p.ToSignature(t, nil)
-	case *ast.Ident, *ast.ScopingExpr, *ast.TagExpr:
+	case *ast.Ident, *ast.ScopingExpr, *ast.TagExpr, *ast.InstantiationType:
 		return p.handleIdentRefer(expr)
 	case *ast.Variadic:
 		return types.NewSlice(gogen.TyEmptyInterface), nil
@@ -164,22 +178,121 @@ func (p *TypeConv) handleIdentRefer(t ast.Expr) (types.Type, error) {
 		return typ
 	}
 	switch t := t.(type) {
-	case *ast.Ident:
-		typ := lookup(t.Name)
+	case *ast.Ident, *ast.ScopingExpr, *ast.InstantiationType:
+		typ := lookup(typeKey(t))
 		return typ, nil
-	case *ast.ScopingExpr:
-		// todo(zzy)
 	case *ast.TagExpr:
-		// todo(zzy):scoping
-		if ident, ok := t.Name.(*ast.Ident); ok {
-			typ := lookup(ident.Name)
+		switch t.Name.(type) {
+		case *ast.Ident, *ast.ScopingExpr:
+			typ := lookup(typeKey(t.Name))
 			return typ, nil
 		}
-		// todo(zzy):scoping expr
 	}
 	return nil, errs.NewUnsupportedReferError(t)
 }
 
+// instKey identifies a class template instantiation by its template and arguments,
+// like: Foo<unsigned_int*,3>. It is used as the c name of the instantiation,
+// so it must not contain spaces (see llcppg.pub).
+func instKey(t *ast.InstantiationType) string {
+	var b strings.Builder
+	b.WriteString(typeKey(t.Template))
+	b.WriteString("<")
+	if t.Args != nil {
+		for i, arg := range t.Args.List {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			b.WriteString(typeKey(arg.Type))
+		}
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+func typeKey(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.TagExpr:
+		return typeKey(t.Name)
+	case *ast.ScopingExpr:
+		if t.Parent == nil {
+			return typeKey(t.X)
+		}
+		return typeKey(t.Parent) + "::" + typeKey(t.X)
+	case *ast.BasicLit:
+		return t.Value
+	case *ast.BuiltinType:
+		return builtinKey(t)
+	case *ast.PointerType:
+		return typeKey(t.X) + "*"
+	case *ast.LvalueRefType:
+		return typeKey(t.X) + "&"
+	case *ast.RvalueRefType:
+		return typeKey(t.X) + "&&"
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return typeKey(t.Elt) + "[]"
+		}
+		return typeKey(t.Elt) + "[" + typeKey(t.Len) + "]"
+	case *ast.InstantiationType:
+		return instKey(t)
+	case *ast.FuncType:
+		var params []string
+		if t.Params != nil {
+			for _, param := range t.Params.List {
+				params = append(params, typeKey(param.Type))
+			}
+		}
+		return typeKey(t.Ret) + "(" + strings.Join(params, ",") + ")"
+	case *ast.Variadic:
+		return "..."
+	}
+	return fmt.Sprintf("%T", expr)
+}
+
+var builtinKeys = map[ast.TypeKind]string{
+	ast.Void:     "void",
+	ast.Bool:     "bool",
+	ast.Char:     "char",
+	ast.Char16:   "char16_t",
+	ast.Char32:   "char32_t",
+	ast.WChar:    "wchar_t",
+	ast.Int:      "int",
+	ast.Int128:   "__int128",
+	ast.Float:    "float",
+	ast.Float16:  "_Float16",
+	ast.Float128: "__float128",
+	ast.Complex:  "_Complex",
+}
+
+// builtinKey spells a builtin type like c,with '_' instead of spaces: unsigned_long_int
+func builtinKey(t *ast.BuiltinType) string {
+	var words []string
+	if t.Flags&ast.Signed != 0 {
+		words = append(words, "signed")
+	}
+	if t.Flags&ast.Unsigned != 0 {
+		words = append(words, "unsigned")
+	}
+	if t.Flags&ast.Short != 0 {
+		words = append(words, "short")
+	}
+	if t.Flags&ast.Long != 0 {
+		words = append(words, "long")
+	}
+	if t.Flags&ast.LongLong != 0 {
+		words = append(words, "long", "long")
+	}
+	if t.Kind == ast.Float && t.Flags&ast.Double != 0 {
+		words = append(words, "double")
+	} else {
+		words = append(words, builtinKeys[t.Kind])
+	}
+	return strings.Join(words, "_")
+}
+
 func (p *TypeConv) ToSignature(funcType *ast.FuncType, recv *types.Var) (*types.Signature, error) {
 	ctx := p.ctx
 	p.ctx = Param
@@ -272,7 +385,7 @@ func (p *TypeConv) fieldListToVars(params *ast.FieldList, hasNamedParam bool) ([
 // todo(zzy): use  Unused [unsafe.Sizeof(0)]byte in the source code
 func (p *TypeConv) defaultRecordField() []*types.Var {
 	return []*types.Var{
-		types.NewVar(token.NoPos, p.types(), "Unused", types.NewArray(types.Typ[types.Byte], int64(unsafe.Sizeof(0)))),
+		types.NewVar(token.NoPos, p.types(), "Unused", types.NewArray(types.Typ[types.Byte], p.sizes.Sizeof(types.Typ[types.Int]))),
 	}
 }
 
@@ -308,23 +421,51 @@ func (p *TypeConv) fieldToVar(field *ast.Field, hasNamedParam bool, argIndex int
 }
 
 func (p *TypeConv) RecordTypeToStruct(recordType *ast.RecordType) (types.Type, error) {
+	typ, _, err := p.recordToStruct("", recordType)
+	return typ, err
+}
+
+// recordToStruct converts a record to a struct, the Go name of the record is
+// used to name its nested anonymous records, or "" if the record is unnamed.
+// It also returns the fields of all members of the record.
+func (p *TypeConv) recordToStruct(name string, recordType *ast.RecordType) (types.Type, []*types.Var, error) {
 	ctx := p.ctx
 	p.ctx = Record
 	defer func() { p.ctx = ctx }()
 	var fields []*types.Var
-	flds, err := p.fieldListToVars(recordType.Fields, false)
+	flds, err := p.recordFields(name, recordType)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if recordType.Tag != ast.Union {
+		base, err := p.baseField(name, recordType.Bases)
+		if err != nil {
+			return nil, nil, err
+		}
 		fields = flds
+		if base != nil {
+			fields = append([]*types.Var{base}, flds...)
+		}
+		// a polymorphic class starts with a vtable pointer, unless it shares the one of its base
+		if recordType.Polymorphic && (base == nil || !p.pkg.isPolymorphic(base.Type())) {
+			fields = append([]*types.Var{p.vptrField()}, fields...)
+		}
 	} else {
+		// a named union is stored in an array, its members are reached by accessors
+		if storable(name, recordType, flds) {
+			if recordType.Size > 0 {
+				return p.storageStruct(name, recordType, flds, nil), flds, nil
+			}
+			if union := p.unionLayout(recordType, flds); union != nil {
+				return p.storageStruct(name, union, flds, nil), flds, nil
+			}
+		}
 		var maxFld *types.Var
 		maxSize := int64(0)
 		for i := len(flds) - 1; i >= 0; i-- {
 			fld := flds[i]
 			t := fld.Type()
-			size := sizes.Sizeof(t)
+			size := p.sizes.Sizeof(t)
 			if size >= maxSize {
 				maxSize = size
 				maxFld = fld
@@ -334,19 +475,85 @@ func (p *TypeConv) RecordTypeToStruct(recordType *ast.RecordType) (types.Type, e
 			fields = []*types.Var{maxFld}
 		}
 	}
-	return types.NewStruct(fields, nil), nil
+	if recordType.Size > 0 {
+		typ, err := p.layoutStruct(recordType, fields)
+		if errors.Is(err, ErrLayout) {
+			if storable(name, recordType, flds) {
+				return p.storageStruct(name, recordType, flds, err), flds, nil
+			}
+			// a class with a base or a vtable pointer keeps the padded struct,
+			// the difference is noted in its doc and reported by verify-layout
+			if dbg.GetDebugError() {
+				log.Printf("recordToStruct %s: %v\n", name, err)
+			}
+			p.pkg.layouts[typ] = &recordLayout{
+				notice: "doesn't match its layout in C: " + strings.TrimPrefix(err.Error(), ErrLayout.Error()+": ") + ".",
+			}
+			return typ, flds, nil
+		}
+		if err == nil && recordType.Align > p.sizes.Go().Alignof(typ) {
+			p.pkg.layouts[typ] = &recordLayout{
+				notice: fmt.Sprintf("is aligned to %d bytes in C, more than Go can align it.", recordType.Align),
+			}
+		}
+		return typ, flds, err
+	}
+	return types.NewStruct(fields, nil), flds, nil
+}
+
+// baseField returns the embedded field of the base class of a C++ class, or nil
+// if it has none, so the methods of the base are promoted to the class.
+// Only single non-virtual inheritance can be expressed by embedding.
+func (p *TypeConv) baseField(name string, bases []*ast.BaseSpec) (*types.Var, error) {
+	if len(bases) == 0 {
+		return nil, nil
+	}
+	if len(bases) > 1 {
+		baseNames := make([]string, len(bases))
+		for i, base := range bases {
+			baseNames[i] = typeKey(base.Type)
+		}
+		return nil, errs.NewUnsupportedInheritanceError(name, "multiple inheritance from "+strings.Join(baseNames, ", "))
+	}
+	if bases[0].Virtual {
+		return nil, errs.NewUnsupportedInheritanceError(name, "virtual inheritance from "+typeKey(bases[0].Type))
+	}
+	typ, err := p.ToType(bases[0].Type)
+	if err != nil {
+		return nil, err
+	}
+	named, ok := typ.(*types.Named)
+	if !ok {
+		return nil, errs.NewUnsupportedInheritanceError(name, "base "+typeKey(bases[0].Type)+" is not a named type")
+	}
+	return types.NewField(token.NoPos, p.types(), named.Obj().Name(), named, true), nil
 }
 
 func (p *TypeConv) ToDefaultEnumType() types.Type {
 	return p.typeMap.CType("Int")
 }
 
-// todo(zzy): Current forward declaration detection is imprecise
-// It incorrectly treats both empty struct `struct a {}` and forward declaration `struct a` as the same
-// by only checking if Fields.List is empty
-// Should use recordType == nil to identify forward declarations, which requires llcppsigfetch support
+// ToEnumType returns the underlying type of an enum, which is c.Int by default.
+func (p *TypeConv) ToEnumType(enumType *ast.EnumType) (types.Type, error) {
+	if enumType == nil || enumType.IntType == nil {
+		return p.ToDefaultEnumType(), nil
+	}
+	typ, err := p.ToType(enumType.IntType)
+	if err != nil {
+		return nil, err
+	}
+	// an alias, like c.Char, is resolved to the basic type it denotes
+	if _, ok := typ.(*types.Named); !ok {
+		typ = typ.Underlying()
+	}
+	return typ, nil
+}
+
+// inComplete reports whether a record is a forward declaration like `struct a;`,
+// whose type is defined by a later declaration or kept opaque, while an empty
+// record like `struct a {};` is complete.
 func (p *TypeConv) inComplete(recordType *ast.RecordType) bool {
-	return recordType.Fields != nil && len(recordType.Fields.List) == 0
+	return recordType.IsForward
 }
 
 // The field name should be public if it's a record field
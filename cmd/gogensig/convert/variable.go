@@ -0,0 +1,105 @@
+/*
+This file is used to convert the variables and constants declared in C, like:
+
+	static const int kMaxSize = 64;
+	constexpr double kPi = 3.14;
+	extern int errcount;
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	"github.com/goplus/llcppg/cmd/gogensig/errs"
+)
+
+// NewVarDecl converts a constant to a typed Go constant, and a variable to a
+// Go variable linked to the C one.
+func (p *Package) NewVarDecl(varDecl *ast.VarDecl) error {
+	isThird, _ := p.handleType(varDecl.Name, varDecl.Loc)
+	if isThird {
+		if dbg.GetDebugLog() {
+			log.Printf("NewVarDecl: %v is a variable of third header file\n", varDecl.Name)
+		}
+		return nil
+	}
+	if dbg.GetDebugLog() {
+		log.Printf("NewVarDecl: %v\n", varDecl.Name)
+	}
+	if hasAttr(varDecl.Attrs, ast.UnavailableAttr) {
+		return nil
+	}
+
+	typ, err := p.ToType(varDecl.Type)
+	if err != nil {
+		return err
+	}
+	ident := qualifiedIdent(varDecl.Parent, varDecl.Name)
+	name, changed, err := p.DeclName(ident.Name, true)
+	if err != nil {
+		return err
+	}
+	doc := p.docComments(varDecl.Doc, name, nil)
+	doc.AddCommentGroup(NewAttrDocComments(p.docAttrs(varDecl.Doc, varDecl.Attrs), len(doc.List) > 0))
+
+	if varDecl.Value != nil {
+		val, typ, err := constValue(varDecl.Value, typ)
+		if err != nil {
+			return err
+		}
+		defs := p.NewConstGroup()
+		defs.defs.SetComments(doc.CommentGroup)
+		defs.New(val, typ, name)
+	} else {
+		if len(doc.List) > 0 {
+			doc.AddComment(&goast.Comment{Text: "//"})
+		}
+		doc.AddCommentGroup(NewFuncDocComments(varDecl.MangledName, name))
+		p.p.NewVarDefs(p.p.Types.Scope()).SetComments(doc.CommentGroup).New(token.NoPos, typ, name)
+	}
+	if changed {
+		if obj := p.p.Types.Scope().Lookup(name); obj != nil {
+			substObj(p.p.Types, p.p.Types.Scope(), ident.Name, obj)
+		}
+	}
+	return nil
+}
+
+// constValue returns the Go value of a constant and its type. The constant keeps
+// the type of the C variable if it is a basic Go type, like c.Int or float64,
+// a string or a constant of other types, like a pointer, is untyped.
+func constValue(lit *ast.BasicLit, typ types.Type) (any, types.Type, error) {
+	basic, ok := typ.Underlying().(*types.Basic)
+	if !ok {
+		typ = nil
+	}
+	switch lit.Kind {
+	case ast.IntLit:
+		val, err := enumItemValue(lit)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok && basic.Info()&types.IsBoolean != 0 {
+			return lit.Value != "0", typ, nil
+		}
+		return val, typ, nil
+	case ast.FloatLit:
+		val, err := litToFloat(lit.Value, 64)
+		if err != nil {
+			return nil, nil, err
+		}
+		return val, typ, nil
+	case ast.StringLit:
+		str, err := litToString(lit.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		return str, nil, nil
+	}
+	return nil, nil, errs.NewCantConvertError(lit.Value, "constant")
+}
@@ -0,0 +1,240 @@
+/*
+This file is used to verify the layout of the generated types against the C
+types, by compiling and running a C program that prints the size and alignment
+of every generated struct, union and enum, and the offsets of their fields:
+
+	printf("t %d %zu %zu\n", 0, sizeof(struct Foo), alignof(struct Foo));
+	printf("f %d %d %zu\n", 0, 1, offsetof(struct Foo, b));
+*/
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/types"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/goplus/llcppg/ast"
+)
+
+// layoutCheck is a generated type whose layout is verified against the C type.
+type layoutCheck struct {
+	cname  string // spelling of the type in C, like: struct Foo
+	named  *types.Named
+	fields []*layoutField // fields whose offsets are verified
+}
+
+type layoutField struct {
+	cname  string
+	offset int64 // offset of the field in Go, or of its accessor
+}
+
+// LayoutConfig configures the C program that probes the layout of the C types.
+type LayoutConfig struct {
+	Include   []string // headers declaring the types, included like: #include <foo.h>
+	CFlags    []string
+	Cplusplus bool
+	Compiler  string // the C compiler, clang if empty
+	Dir       string // directory the compiler runs in, for the relative paths of CFlags
+}
+
+// LayoutMismatch is a difference between the layout of a generated Go type and
+// the layout of its C type.
+type LayoutMismatch struct {
+	Type  string // Go name of the type
+	CType string // spelling of the type in C
+	Field string // C name of the field, empty for the size and alignment of the type
+	Kind  string // size, align or offset
+	C     int64
+	Go    int64
+}
+
+func (m *LayoutMismatch) String() string {
+	name := m.Type
+	if m.Field != "" {
+		name += "." + m.Field
+	}
+	return fmt.Sprintf("%s (%s): %s %d in C, %d in Go", name, m.CType, m.Kind, m.C, m.Go)
+}
+
+// forwardEnum reports whether an enum is declared without its items and integer
+// type, like: enum E; its C type is incomplete. A scoped enum is of int by default.
+func forwardEnum(enumType *ast.EnumType) bool {
+	return len(enumType.Items) == 0 && enumType.IntType == nil && !enumType.Scoped
+}
+
+// addLayoutCheck records a generated type to verify, with the fields of its record
+// converted to fields, if any.
+func (p *Package) addLayoutCheck(cname string, named *types.Named, recordType *ast.RecordType, fields []*types.Var) {
+	// a record declared but not defined has no layout in C, its Go type is a placeholder
+	if recordType != nil && (recordType.IsForward || recordType.Fields == nil) {
+		return
+	}
+	check := &layoutCheck{cname: cname, named: named}
+	p.layoutChecks = append(p.layoutChecks, check)
+	// the offsets of the fields are unknown to clang if the record has bit-fields
+	if recordType == nil || recordType.Size == 0 || recordType.Fields == nil || len(fields) != len(recordType.Fields.List) {
+		return
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return
+	}
+	layout := p.layouts[st]
+	var vars []*types.Var
+	for i := 0; i < st.NumFields(); i++ {
+		vars = append(vars, st.Field(i))
+	}
+	offsets := p.cvt.sizes.Go().Offsetsof(vars)
+	for i, field := range recordType.Fields.List {
+		if field.IsStatic || len(field.Names) == 0 || field.Access == ast.Protected || field.Access == ast.Private {
+			continue
+		}
+		var offset int64
+		switch {
+		case layout != nil && layout.fields != nil:
+			offset = layout.offsets[i]
+		case recordType.Tag == ast.Union:
+			// the fields of a union are at its start
+		default:
+			index := fieldIndex(vars, fields[i])
+			if index < 0 {
+				continue
+			}
+			offset = offsets[index]
+		}
+		check.fields = append(check.fields, &layoutField{cname: field.Names[0].Name, offset: offset})
+	}
+}
+
+func fieldIndex(vars []*types.Var, field *types.Var) int {
+	for i, v := range vars {
+		if v == field {
+			return i
+		}
+	}
+	return -1
+}
+
+// VerifyLayout compiles and runs a C program that probes the layout of the C types
+// of the generated structs, unions and enums, and returns the differences with the
+// layout of the Go types.
+func (p *Package) VerifyLayout(conf *LayoutConfig) ([]*LayoutMismatch, error) {
+	if len(p.layoutChecks) == 0 {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp("", "llcppg_layout")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ext, lang := ".c", "c"
+	if conf.Cplusplus {
+		ext, lang = ".cpp", "c++"
+	}
+	src := filepath.Join(dir, "probe"+ext)
+	if err := os.WriteFile(src, []byte(layoutProbe(p.layoutChecks, conf)), 0644); err != nil {
+		return nil, err
+	}
+	compiler := conf.Compiler
+	if compiler == "" {
+		compiler = "clang"
+	}
+	exe := filepath.Join(dir, "probe")
+	args := append([]string{"-x", lang}, conf.CFlags...)
+	args = append(args, src, "-o", exe)
+	cmd := exec.Command(compiler, args...)
+	cmd.Dir = conf.Dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("compile the layout probe: %w\n%s", err, out)
+	}
+	out, err := exec.Command(exe).Output()
+	if err != nil {
+		return nil, fmt.Errorf("run the layout probe: %w", err)
+	}
+	return p.compareLayout(out)
+}
+
+// layoutProbe returns the source of the C program that prints the layouts.
+func layoutProbe(checks []*layoutCheck, conf *LayoutConfig) string {
+	alignof := "_Alignof"
+	if conf.Cplusplus {
+		alignof = "alignof"
+	}
+	var b strings.Builder
+	b.WriteString("#include <stddef.h>\n#include <stdio.h>\n")
+	for _, inc := range conf.Include {
+		fmt.Fprintf(&b, "#include <%s>\n", inc)
+	}
+	b.WriteString("\nint main(void) {\n")
+	for i, check := range checks {
+		fmt.Fprintf(&b, "\tprintf(\"t %%d %%zu %%zu\\n\", %d, sizeof(%s), (size_t)%s(%s));\n", i, check.cname, alignof, check.cname)
+		for j, field := range check.fields {
+			fmt.Fprintf(&b, "\tprintf(\"f %%d %%d %%zu\\n\", %d, %d, offsetof(%s, %s));\n", i, j, check.cname, field.cname)
+		}
+	}
+	b.WriteString("\treturn 0;\n}\n")
+	return b.String()
+}
+
+// compareLayout compares the output of the layout probe with the Go layouts.
+func (p *Package) compareLayout(out []byte) ([]*LayoutMismatch, error) {
+	var mismatches []*LayoutMismatch
+	add := func(check *layoutCheck, field, kind string, c, goVal int64) {
+		if c != goVal {
+			mismatches = append(mismatches, &LayoutMismatch{
+				Type:  check.named.Obj().Name(),
+				CType: check.cname,
+				Field: field,
+				Kind:  kind,
+				C:     c,
+				Go:    goVal,
+			})
+		}
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		nums := make([]int64, len(parts))
+		for i := 1; i < len(parts); i++ {
+			n, err := strconv.ParseInt(parts[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid layout probe output %q", scanner.Text())
+			}
+			nums[i] = n
+		}
+		switch {
+		case len(parts) == 4 && parts[0] == "t" && nums[1] < int64(len(p.layoutChecks)):
+			check := p.layoutChecks[nums[1]]
+			add(check, "", "size", nums[2], p.cvt.sizes.Go().Sizeof(check.named))
+			add(check, "", "align", nums[3], p.cvt.sizes.Go().Alignof(check.named))
+		case len(parts) == 4 && parts[0] == "f" && nums[1] < int64(len(p.layoutChecks)) &&
+			nums[2] < int64(len(p.layoutChecks[nums[1]].fields)):
+			check := p.layoutChecks[nums[1]]
+			field := check.fields[nums[2]]
+			add(check, field.cname, "offset", nums[3], field.offset)
+		default:
+			return nil, fmt.Errorf("invalid layout probe output %q", scanner.Text())
+		}
+	}
+	return mismatches, scanner.Err()
+}
+
+// cTypeName returns the spelling of a record or enum in C, like: struct Foo
+func cTypeName(tag ast.Tag, name string) string {
+	switch tag {
+	case ast.Union:
+		return "union " + name
+	case ast.Enum:
+		return "enum " + name
+	case ast.Class:
+		return "class " + name
+	}
+	return "struct " + name
+}
@@ -0,0 +1,126 @@
+/*
+This file is used to convert the methods of C++ classes, and the virtual methods of abstract classes to Go interfaces
+*/
+package convert
+
+import (
+	goast "go/ast"
+	"go/token"
+	"go/types"
+	"log"
+
+	"github.com/goplus/llcppg/_xtool/llcppsymg/names"
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/dbg"
+	"github.com/goplus/llcppg/cmd/gogensig/errs"
+)
+
+// polyClass is a polymorphic C++ class, whose objects start with a vtable pointer.
+type polyClass struct {
+	virtuals []*types.Func // virtual methods, including the inherited ones
+}
+
+// isPolymorphic reports whether a type is a polymorphic class, so a class
+// derived from it shares its vtable pointer.
+func (p *Package) isPolymorphic(typ types.Type) bool {
+	named, ok := typ.(*types.Named)
+	return ok && p.polyClasses[named] != nil
+}
+
+// vptrField returns the hidden vtable pointer of a polymorphic class.
+func (p *TypeConv) vptrField() *types.Var {
+	return types.NewField(token.NoPos, p.types(), "_", p.typeMap.CType("Pointer"), false)
+}
+
+// newClassMethods generates the methods declared in a C++ class. The interface
+// of the virtual methods is generated for an abstract class, like:
+//
+//	class Shape { public: virtual double area() = 0; };
+//	type ShapeInterface interface { Area() c.Double }
+//
+// Methods without a symbol, like inline and pure virtual ones, are skipped.
+func (p *Package) newClassMethods(named *types.Named, class *ast.RecordType) error {
+	recvType := types.NewPointer(named)
+	for _, method := range class.Methods {
+		if err := p.newMethod(recvType, method); err != nil && dbg.GetDebugLog() {
+			log.Printf("newClassMethods: %s::%s %s\n", named.Obj().Name(), method.Name.Name, err.Error())
+		}
+	}
+	if !class.Polymorphic {
+		return nil
+	}
+
+	poly := &polyClass{}
+	p.polyClasses[named] = poly
+	if base := embeddedBase(named); base != nil && p.polyClasses[base] != nil {
+		poly.virtuals = append(poly.virtuals, p.polyClasses[base].virtuals...)
+	}
+	for _, method := range class.Methods {
+		if !method.IsVirtual || method.IsDestructor {
+			continue
+		}
+		sig, err := p.cvt.ToSignature(method.Type, nil)
+		if err != nil {
+			return err
+		}
+		poly.override(types.NewFunc(token.NoPos, p.p.Types, p.methodName(method), sig))
+	}
+
+	if !class.Abstract || len(poly.virtuals) == 0 {
+		return nil
+	}
+	name := named.Obj().Name() + "Interface"
+	if obj := p.p.Types.Scope().Lookup(name); obj != nil {
+		return errs.NewTypeDefinedError(name, named.Obj().Name())
+	}
+	methods := make([]*types.Func, len(poly.virtuals))
+	for i, fn := range poly.virtuals {
+		sig := fn.Type().(*types.Signature)
+		methods[i] = types.NewFunc(token.NoPos, p.p.Types, fn.Name(), types.NewSignatureType(nil, nil, nil, sig.Params(), sig.Results(), sig.Variadic()))
+	}
+	typeBlock := p.p.NewTypeDefs()
+	typeBlock.SetComments(&goast.CommentGroup{List: []*goast.Comment{
+		{Text: "// " + name + " is implemented by the classes derived from " + named.Obj().Name() + "."},
+	}})
+	decl := typeBlock.NewType(name)
+	decl.InitType(p.p, types.NewInterfaceType(methods, nil).Complete())
+	return nil
+}
+
+// methodName returns the Go name of a method. A pure virtual method has no
+// symbol, so it is named like llcppsymg does.
+func (p *Package) methodName(method *ast.FuncDecl) string {
+	if fnSpec, err := p.LookupSymbol(method.MangledName); err == nil {
+		return fnSpec.FnName
+	}
+	if op, ok := names.Operator(method.Name.Name); ok {
+		return names.OperatorGoName(op, len(method.Type.Params.List)+1)
+	}
+	return names.GoName(method.Name.Name, p.trimPrefixes(), true)
+}
+
+// override replaces the inherited virtual method of the same name, or adds it.
+func (c *polyClass) override(fn *types.Func) {
+	for i, m := range c.virtuals {
+		if m.Name() == fn.Name() {
+			c.virtuals[i] = fn
+			return
+		}
+	}
+	c.virtuals = append(c.virtuals, fn)
+}
+
+// embeddedBase returns the base class embedded in a class, or nil if it has none.
+func embeddedBase(named *types.Named) *types.Named {
+	structType, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	for i := 0; i < structType.NumFields(); i++ {
+		if field := structType.Field(i); field.Embedded() {
+			base, _ := field.Type().(*types.Named)
+			return base
+		}
+	}
+	return nil
+}
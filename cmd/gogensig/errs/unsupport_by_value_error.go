@@ -0,0 +1,16 @@
+package errs
+
+import "fmt"
+
+type UnsupportedByValueError struct {
+	Func string
+	Type string
+}
+
+func (p *UnsupportedByValueError) Error() string {
+	return fmt.Sprintf("unsupported function %s: %s is passed by value, which Go can't pass as C does", p.Func, p.Type)
+}
+
+func NewUnsupportedByValueError(fn, typ string) *UnsupportedByValueError {
+	return &UnsupportedByValueError{Func: fn, Type: typ}
+}
@@ -0,0 +1,16 @@
+package errs
+
+import "fmt"
+
+type UnsupportedInheritanceError struct {
+	Class  string
+	Reason string
+}
+
+func (p *UnsupportedInheritanceError) Error() string {
+	return fmt.Sprintf("unsupported inheritance of %s: %s", p.Class, p.Reason)
+}
+
+func NewUnsupportedInheritanceError(class, reason string) *UnsupportedInheritanceError {
+	return &UnsupportedInheritanceError{Class: class, Reason: reason}
+}
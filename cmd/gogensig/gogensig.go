@@ -20,13 +20,17 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/goplus/llcppg/_xtool/llcppsymg/args"
+	"github.com/goplus/llcppg/ast"
 	"github.com/goplus/llcppg/cmd/gogensig/config"
 	"github.com/goplus/llcppg/cmd/gogensig/convert"
 	"github.com/goplus/llcppg/cmd/gogensig/dbg"
 	"github.com/goplus/llcppg/cmd/gogensig/unmarshal"
+	"github.com/goplus/llcppg/llcppg"
+	"github.com/goplus/llgo/xtool/env"
 )
 
 func main() {
@@ -43,11 +47,25 @@ func main() {
 	}
 
 	var cfgFile string
+	ndjson := false
+	verifyLayout := false
+	var target *llcppg.Target
 	for i := 0; i < len(remainArgs); i++ {
 		arg := remainArgs[i]
 		if strings.HasPrefix(arg, "-cfg=") {
 			cfgFile = args.StringArg(arg, args.LLCPPG_CFG)
 		}
+		if strings.HasPrefix(arg, "-ndjson=") {
+			ndjson = args.BoolArg(arg, false)
+		}
+		if strings.HasPrefix(arg, "-verify-layout=") {
+			verifyLayout = args.BoolArg(arg, false)
+		}
+		if strings.HasPrefix(arg, "-target=") {
+			var err error
+			target, err = llcppg.ParseTarget(args.StringArg(arg, ""))
+			check(err)
+		}
 	}
 	if cfgFile == "" {
 		cfgFile = args.LLCPPG_CFG
@@ -55,29 +73,99 @@ func main() {
 
 	conf, err := config.GetCppgCfgFromPath(cfgFile)
 	check(err)
+	if target != nil {
+		conf.Target = target
+	}
 	wd, err := os.Getwd()
 	check(err)
 
 	err = prepareEnv(wd, conf.Name, conf.Deps)
 	check(err)
 
-	data, err := config.ReadSigfetchFile(filepath.Join(wd, ags.CfgFile))
+	sigfetchFile := filepath.Join(wd, ags.CfgFile)
+	var cvt *convert.Converter
+	if ndjson {
+		cvt = convertStream(wd, cfgFile, conf.Name, sigfetchFile, target)
+	} else {
+		cvt = convertFile(wd, cfgFile, conf.Name, sigfetchFile, target)
+	}
+	if verifyLayout {
+		verify(cvt, conf, wd)
+	}
+}
+
+// convertFile converts the JSON output of llcppsigfetch.
+func convertFile(wd, cfgFile, name, sigfetchFile string, target *llcppg.Target) *convert.Converter {
+	data, err := config.ReadSigfetchFile(sigfetchFile)
 	check(err)
 
 	convertPkg, err := unmarshal.Pkg(data)
 	check(err)
 
 	cvt, err := convert.NewConverter(&convert.Config{
-		PkgName:  conf.Name,
+		PkgName:  name,
 		SymbFile: filepath.Join(wd, args.LLCPPG_SYMB),
 		CfgFile:  filepath.Join(wd, cfgFile),
 		PubFile:  filepath.Join(wd, args.LLCPPG_PUB),
+		Target:   target,
 		Pkg:      convertPkg,
 	})
 	if err != nil {
 		check(err)
 	}
 	cvt.Convert()
+	return cvt
+}
+
+// convertStream converts the NDJSON output of llcppsigfetch -ndjson=true,
+// each node is converted as it is read.
+func convertStream(wd, cfgFile, name, sigfetchFile string, target *llcppg.Target) *convert.Converter {
+	f, err := config.OpenSigfetchFile(sigfetchFile)
+	check(err)
+	defer f.Close()
+
+	stream, err := unmarshal.NewPkgStream(f)
+	check(err)
+
+	cvt, err := convert.NewConverter(&convert.Config{
+		PkgName:  name,
+		SymbFile: filepath.Join(wd, args.LLCPPG_SYMB),
+		CfgFile:  filepath.Join(wd, cfgFile),
+		PubFile:  filepath.Join(wd, args.LLCPPG_PUB),
+		Target:   target,
+		Pkg: &llcppg.Pkg{
+			File:    &ast.File{},
+			FileMap: stream.FileMap,
+		},
+	})
+	check(err)
+	err = cvt.ConvertStream(stream, stream.Names)
+	check(err)
+	return cvt
+}
+
+// verify compares the layout of the generated types with the C types, and exits
+// with status 1 if they differ.
+func verify(cvt *convert.Converter, conf *llcppg.Config, wd string) {
+	// the probe of the layouts runs on the host
+	if conf.Target.Goos() != runtime.GOOS || conf.Target.Goarch() != runtime.GOARCH {
+		check(fmt.Errorf("can't verify the layouts for the target %s/%s on the host", conf.Target.Goos(), conf.Target.Goarch()))
+	}
+	mismatches, err := cvt.GenPkg.VerifyLayout(&convert.LayoutConfig{
+		Include:   conf.Include,
+		CFlags:    strings.Fields(env.ExpandEnv(conf.CFlags)),
+		Cplusplus: conf.Cplusplus,
+		Dir:       wd,
+	})
+	check(err)
+	for _, m := range mismatches {
+		fmt.Fprintln(os.Stderr, "layout mismatch:", m)
+	}
+	if len(mismatches) > 0 {
+		fmt.Fprintf(os.Stderr, "%d layout mismatches\n", len(mismatches))
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "layouts verified")
 }
 
 func check(err error) {
@@ -119,5 +207,5 @@ func prepareEnv(wd, pkg string, deps []string) error {
 }
 
 func printUsage() {
-	fmt.Fprintln(os.Stderr, "Usage: gogensig [-v|-cfg] [sigfetch-file]")
+	fmt.Fprintln(os.Stderr, "Usage: gogensig [-v|-cfg|-ndjson=<bool>|-verify-layout=<bool>|-target=<goos/goarch>] [sigfetch-file]")
 }
@@ -0,0 +1,484 @@
+/*
+This package is used to marshal the AST of llcppsigfetch to JSON in Go, the
+output has the same _Type-tagged format as the output of llcppsigfetch, and
+can be read by the unmarshal package.
+*/
+package marshal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/llcppg"
+)
+
+// object is a JSON object which keeps the order of its fields.
+type object struct {
+	keys   []string
+	values []any
+}
+
+func newObject(typ string) *object {
+	obj := &object{}
+	if typ != "" {
+		obj.set("_Type", typ)
+	}
+	return obj
+}
+
+func (o *object) set(key string, value any) {
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, value)
+}
+
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Pkg marshals a Pkg like llcppsigfetch, with the SchemaVersion of the format.
+func Pkg(pkg *llcppg.Pkg) ([]byte, error) {
+	root := newObject("")
+	root.set("_Version", llcppg.SchemaVersion)
+	root.set("File", marshalFile(pkg.File))
+	root.set("FileMap", marshalFileMap(pkg.FileMap))
+	return json.Marshal(root)
+}
+
+// PkgNDJSON marshals a Pkg like llcppsigfetch -ndjson=true: the first line is the
+// header with the FileMap and the names declared by the decls of each file, and
+// each following line is an include, macro or decl.
+func PkgNDJSON(pkg *llcppg.Pkg) ([]byte, error) {
+	header := newObject("Pkg")
+	header.set("_Version", llcppg.SchemaVersion)
+	header.set("FileMap", marshalFileMap(pkg.FileMap))
+	names := map[string][]string{}
+	if pkg.File != nil {
+		names = llcppg.DeclNames(pkg.File.Decls)
+	}
+	header.set("Names", names)
+	lines := []any{header}
+	if pkg.File != nil {
+		for _, include := range pkg.File.Includes {
+			lines = append(lines, marshalInclude(include))
+		}
+		for _, macro := range pkg.File.Macros {
+			lines = append(lines, marshalMacro(macro))
+		}
+		for _, decl := range pkg.File.Decls {
+			lines = append(lines, marshalDecl(decl))
+		}
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		data, err := json.Marshal(line)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func marshalFileMap(fileMap map[string]*llcppg.FileInfo) any {
+	if fileMap == nil {
+		return map[string]*llcppg.FileInfo{}
+	}
+	return fileMap
+}
+
+// Node marshals a node of the AST, like a File, Decl or Expr.
+func Node(node ast.Node) ([]byte, error) {
+	var value any
+	switch n := node.(type) {
+	case *ast.File:
+		value = marshalFile(n)
+	case *ast.Include:
+		value = marshalInclude(n)
+	case *ast.Macro:
+		value = marshalMacro(n)
+	case *ast.Token:
+		value = marshalToken(n)
+	case ast.Decl:
+		value = marshalDecl(n)
+	case ast.Expr:
+		value = marshalExpr(n)
+	default:
+		return nil, fmt.Errorf("marshal error: unsupported node type %T", node)
+	}
+	return json.Marshal(value)
+}
+
+func marshalFile(file *ast.File) any {
+	if file == nil {
+		return nil
+	}
+	root := newObject("File")
+	decls := []any{}
+	for _, decl := range file.Decls {
+		decls = append(decls, marshalDecl(decl))
+	}
+	root.set("decls", decls)
+	includes := []any{}
+	for _, include := range file.Includes {
+		includes = append(includes, marshalInclude(include))
+	}
+	root.set("includes", includes)
+	macros := []any{}
+	for _, macro := range file.Macros {
+		macros = append(macros, marshalMacro(macro))
+	}
+	root.set("macros", macros)
+	return root
+}
+
+func marshalInclude(include *ast.Include) any {
+	root := newObject("Include")
+	root.set("Path", include.Path)
+	return root
+}
+
+func marshalMacro(macro *ast.Macro) any {
+	root := newObject("Macro")
+	root.set("Loc", marshalLocation(macro.Loc))
+	root.set("Name", macro.Name)
+	root.set("Tokens", marshalTokenList(macro.Tokens))
+	return root
+}
+
+func marshalToken(tok *ast.Token) any {
+	root := newObject("Token")
+	root.set("Token", uint(tok.Token))
+	root.set("Lit", tok.Lit)
+	return root
+}
+
+func marshalLocation(loc *ast.Location) any {
+	if loc == nil {
+		return nil
+	}
+	root := newObject("Location")
+	root.set("File", loc.File)
+	return root
+}
+
+func marshalTokenList(list []*ast.Token) any {
+	if list == nil {
+		return nil
+	}
+	tokens := []any{}
+	for _, tok := range list {
+		tokens = append(tokens, marshalToken(tok))
+	}
+	return tokens
+}
+
+func marshalDecl(decl ast.Decl) any {
+	if decl == nil {
+		return nil
+	}
+	var root *object
+	switch d := decl.(type) {
+	case *ast.EnumTypeDecl:
+		root = newObject("EnumTypeDecl")
+		marshalDeclBase(d.DeclBase, root)
+		root.set("Name", marshalExpr(d.Name))
+		root.set("Type", marshalExpr(d.Type))
+	case *ast.TypedefDecl:
+		root = newObject("TypedefDecl")
+		marshalDeclBase(d.DeclBase, root)
+		root.set("Name", marshalExpr(d.Name))
+		root.set("Type", marshalExpr(d.Type))
+	case *ast.FuncDecl:
+		if d == nil {
+			return nil
+		}
+		root = newObject("FuncDecl")
+		marshalDeclBase(d.DeclBase, root)
+		root.set("Name", marshalExpr(d.Name))
+		root.set("MangledName", d.MangledName)
+		root.set("Type", marshalExpr(d.Type))
+		root.set("IsInline", d.IsInline)
+		root.set("IsStatic", d.IsStatic)
+		if d.IsExternC {
+			root.set("IsExternC", d.IsExternC)
+		}
+		root.set("IsConst", d.IsConst)
+		root.set("IsExplicit", d.IsExplicit)
+		root.set("IsConstructor", d.IsConstructor)
+		root.set("IsDestructor", d.IsDestructor)
+		root.set("IsVirtual", d.IsVirtual)
+		root.set("IsOverride", d.IsOverride)
+	case *ast.VarDecl:
+		root = newObject("VarDecl")
+		marshalDeclBase(d.DeclBase, root)
+		root.set("Name", marshalExpr(d.Name))
+		root.set("MangledName", d.MangledName)
+		root.set("Type", marshalExpr(d.Type))
+		if d.Value != nil {
+			root.set("Value", marshalExpr(d.Value))
+		}
+	case *ast.TypeDecl:
+		root = newObject("TypeDecl")
+		marshalDeclBase(d.DeclBase, root)
+		root.set("Name", marshalExpr(d.Name))
+		root.set("Type", marshalExpr(d.Type))
+	case *ast.InstantiationDecl:
+		root = newObject("InstantiationDecl")
+		marshalDeclBase(d.DeclBase, root)
+		root.set("Name", marshalExpr(d.Name))
+		root.set("Template", marshalExpr(d.Template))
+		root.set("Type", marshalExpr(d.Type))
+	default:
+		return newObject("")
+	}
+	return root
+}
+
+func marshalDeclBase(decl ast.DeclBase, root *object) {
+	root.set("Loc", marshalLocation(decl.Loc))
+	root.set("Doc", marshalExpr(decl.Doc))
+	root.set("Parent", marshalExpr(decl.Parent))
+	// most declarations have no attributes, keep them out of the output
+	if len(decl.Attrs) > 0 {
+		attrs := []any{}
+		for _, attr := range decl.Attrs {
+			item := newObject("Attr")
+			item.set("Kind", uint(attr.Kind))
+			args := []string{}
+			args = append(args, attr.Args...)
+			item.set("Args", args)
+			attrs = append(attrs, item)
+		}
+		root.set("Attrs", attrs)
+	}
+}
+
+func marshalDocComment(doc *ast.DocComment) any {
+	root := newObject("DocComment")
+	root.set("Brief", doc.Brief)
+	blocks := []any{}
+	for _, block := range doc.Blocks {
+		blocks = append(blocks, marshalDocBlock(block))
+	}
+	root.set("Blocks", blocks)
+	params := []any{}
+	for _, param := range doc.Params {
+		item := newObject("DocParam")
+		item.set("Name", param.Name)
+		item.set("Direction", param.Direction)
+		item.set("Text", param.Text)
+		params = append(params, item)
+	}
+	root.set("Params", params)
+	root.set("Return", doc.Return)
+	if doc.Deprecated != nil {
+		root.set("Deprecated", marshalDocBlock(doc.Deprecated))
+	} else {
+		root.set("Deprecated", nil)
+	}
+	see := []string{}
+	see = append(see, doc.See...)
+	root.set("See", see)
+	return root
+}
+
+func marshalDocBlock(block *ast.DocBlock) any {
+	root := newObject("DocBlock")
+	root.set("Text", block.Text)
+	root.set("Code", block.Code)
+	return root
+}
+
+func marshalExpr(t ast.Expr) any {
+	if t == nil {
+		return nil
+	}
+	var root *object
+	switch d := t.(type) {
+	case *ast.EnumType:
+		root = newObject("EnumType")
+		items := []any{}
+		for _, e := range d.Items {
+			items = append(items, marshalExpr(e))
+		}
+		root.set("Items", items)
+		if d.IntType != nil {
+			root.set("IntType", marshalExpr(d.IntType))
+		}
+		if d.Scoped {
+			root.set("Scoped", true)
+		}
+	case *ast.EnumItem:
+		root = newObject("EnumItem")
+		root.set("Name", marshalExpr(d.Name))
+		root.set("Value", marshalExpr(d.Value))
+	case *ast.RecordType:
+		root = newObject("RecordType")
+		root.set("Tag", uint(d.Tag))
+		root.set("Fields", marshalExpr(d.Fields))
+		methods := []any{}
+		for _, m := range d.Methods {
+			methods = append(methods, marshalDecl(m))
+		}
+		root.set("Methods", methods)
+		if len(d.Bases) > 0 {
+			bases := []any{}
+			for _, b := range d.Bases {
+				bases = append(bases, marshalExpr(b))
+			}
+			root.set("Bases", bases)
+		}
+		if d.Polymorphic {
+			root.set("Polymorphic", true)
+		}
+		if d.Abstract {
+			root.set("Abstract", true)
+		}
+		if d.Size > 0 {
+			root.set("Size", uint(d.Size))
+			root.set("Align", uint(d.Align))
+		}
+		if d.Packed {
+			root.set("Packed", true)
+		}
+		if d.IsForward {
+			root.set("IsForward", true)
+		}
+	case *ast.BaseSpec:
+		root = newObject("BaseSpec")
+		root.set("Type", marshalExpr(d.Type))
+		root.set("Access", uint(d.Access))
+		root.set("Virtual", d.Virtual)
+		if d.Offset > 0 {
+			root.set("Offset", uint(d.Offset))
+		}
+	case *ast.FuncType:
+		root = newObject("FuncType")
+		root.set("Params", marshalExpr(d.Params))
+		root.set("Ret", marshalExpr(d.Ret))
+	case *ast.FieldList:
+		root = newObject("FieldList")
+		if d.List == nil {
+			root.set("List", nil)
+		} else {
+			list := []any{}
+			for _, field := range d.List {
+				list = append(list, marshalExpr(field))
+			}
+			root.set("List", list)
+		}
+	case *ast.Field:
+		root = newObject("Field")
+		root.set("Type", marshalExpr(d.Type))
+		root.set("Doc", marshalExpr(d.Doc))
+		root.set("Comment", marshalExpr(d.Comment))
+		root.set("IsStatic", d.IsStatic)
+		root.set("Access", uint(d.Access))
+		if d.Names == nil {
+			root.set("Names", nil)
+		} else {
+			names := []any{}
+			for _, name := range d.Names {
+				names = append(names, marshalExpr(name))
+			}
+			root.set("Names", names)
+		}
+		if d.Offset > 0 {
+			root.set("Offset", uint(d.Offset))
+		}
+		if d.Default != nil {
+			root.set("Default", marshalExpr(d.Default))
+		}
+		if d.DefaultTokens != nil {
+			root.set("DefaultTokens", marshalTokenList(d.DefaultTokens))
+		}
+	case *ast.Variadic:
+		root = newObject("Variadic")
+	case *ast.Ident:
+		if d == nil {
+			return nil
+		}
+		root = newObject("Ident")
+		root.set("Name", d.Name)
+	case *ast.TagExpr:
+		root = newObject("TagExpr")
+		root.set("Name", marshalExpr(d.Name))
+		root.set("Tag", uint(d.Tag))
+	case *ast.BasicLit:
+		root = newObject("BasicLit")
+		root.set("Kind", uint(d.Kind))
+		root.set("Value", d.Value)
+	case *ast.LvalueRefType:
+		root = newObject("LvalueRefType")
+		root.set("X", marshalExpr(d.X))
+	case *ast.RvalueRefType:
+		root = newObject("RvalueRefType")
+		root.set("X", marshalExpr(d.X))
+	case *ast.PointerType:
+		root = newObject("PointerType")
+		root.set("X", marshalExpr(d.X))
+		if d.Nullability != ast.NullabilityNone {
+			root.set("Nullability", uint(d.Nullability))
+		}
+	case *ast.ArrayType:
+		root = newObject("ArrayType")
+		root.set("Elt", marshalExpr(d.Elt))
+		root.set("Len", marshalExpr(d.Len))
+	case *ast.BuiltinType:
+		root = newObject("BuiltinType")
+		root.set("Kind", uint(d.Kind))
+		root.set("Flags", uint(d.Flags))
+	case *ast.Comment:
+		if d == nil {
+			return nil
+		}
+		root = newObject("Comment")
+		root.set("Text", d.Text)
+	case *ast.CommentGroup:
+		if d == nil {
+			return nil
+		}
+		root = newObject("CommentGroup")
+		list := []any{}
+		for _, c := range d.List {
+			list = append(list, marshalExpr(c))
+		}
+		root.set("List", list)
+		if d.Doxygen != nil {
+			root.set("Doxygen", marshalDocComment(d.Doxygen))
+		}
+	case *ast.ScopingExpr:
+		root = newObject("ScopingExpr")
+		root.set("X", marshalExpr(d.X))
+		root.set("Parent", marshalExpr(d.Parent))
+	case *ast.InstantiationType:
+		root = newObject("InstantiationType")
+		root.set("Template", marshalExpr(d.Template))
+		root.set("Args", marshalExpr(d.Args))
+	default:
+		return nil
+	}
+	return root
+}
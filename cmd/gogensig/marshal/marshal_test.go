@@ -0,0 +1,376 @@
+package marshal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/marshal"
+	"github.com/goplus/llcppg/cmd/gogensig/unmarshal"
+	"github.com/goplus/llcppg/llcppg"
+	"github.com/goplus/llcppg/token"
+)
+
+func intType() *ast.BuiltinType {
+	return &ast.BuiltinType{Kind: ast.Int}
+}
+
+func ident(name string) *ast.Ident {
+	return &ast.Ident{Name: name}
+}
+
+func TestRoundTripNode(t *testing.T) {
+	funcType := &ast.FuncType{
+		Params: &ast.FieldList{List: []*ast.Field{
+			{Type: intType(), Names: []*ast.Ident{ident("a")}},
+			{Type: &ast.Variadic{}},
+		}},
+		Ret: &ast.PointerType{X: &ast.BuiltinType{Kind: ast.Char, Flags: ast.Signed}},
+	}
+	doc := &ast.CommentGroup{
+		List: []*ast.Comment{{Text: "/** Returns the size. */"}},
+		Doxygen: &ast.DocComment{
+			Brief:      "Returns the size.",
+			Blocks:     []*ast.DocBlock{{Text: "size(w);", Code: true}},
+			Params:     []*ast.DocParam{{Name: "w", Direction: "in", Text: "the widget"}},
+			Return:     "the size",
+			Deprecated: &ast.DocBlock{Text: "use Len"},
+			See:        []string{"Len"},
+		},
+	}
+	testCases := []struct {
+		name string
+		node ast.Node
+	}{
+		{"Token", &ast.Token{Token: token.IDENT, Lit: "DEBUG"}},
+		{"Include", &ast.Include{Path: "foo.h"}},
+		{"Macro", &ast.Macro{Loc: &ast.Location{File: "foo.h"}, Name: "FOO", Tokens: []*ast.Token{
+			{Token: token.IDENT, Lit: "FOO"}, {Token: token.LITERAL, Lit: "1"},
+		}}},
+		{"Macro without tokens", &ast.Macro{Name: "FOO"}},
+		{"BasicLit", &ast.BasicLit{Kind: ast.StringLit, Value: `"foo"`}},
+		{"BuiltinType", &ast.BuiltinType{Kind: ast.Float, Flags: ast.Double | ast.Long}},
+		{"Ident", ident("foo")},
+		{"Variadic", &ast.Variadic{}},
+		{"PointerType", &ast.PointerType{X: &ast.PointerType{X: intType()}}},
+		{"PointerType with nullability", &ast.PointerType{X: intType(), Nullability: ast.Nullable}},
+		{"LvalueRefType", &ast.LvalueRefType{X: intType()}},
+		{"RvalueRefType", &ast.RvalueRefType{X: intType()}},
+		{"ArrayType", &ast.ArrayType{Elt: intType(), Len: &ast.BasicLit{Kind: ast.IntLit, Value: "10"}}},
+		{"ArrayType without len", &ast.ArrayType{Elt: intType()}},
+		{"TagExpr", &ast.TagExpr{Tag: ast.Struct, Name: ident("foo")}},
+		{"ScopingExpr", &ast.ScopingExpr{Parent: ident("ns"), X: &ast.ScopingExpr{Parent: ident("Foo"), X: ident("Bar")}}},
+		{"InstantiationType", &ast.InstantiationType{
+			Template: ident("vector"),
+			Args:     &ast.FieldList{List: []*ast.Field{{Type: intType()}}},
+		}},
+		{"EnumItem", &ast.EnumItem{Name: ident("Red"), Value: &ast.BasicLit{Kind: ast.IntLit, Value: "0"}}},
+		{"EnumType", &ast.EnumType{Items: []*ast.EnumItem{{Name: ident("Red")}}, IntType: &ast.BuiltinType{Kind: ast.Int, Flags: ast.Unsigned}}},
+		{"Scoped EnumType", &ast.EnumType{Items: []*ast.EnumItem{{Name: ident("Red")}}, Scoped: true}},
+		{"FieldList", &ast.FieldList{}},
+		{"Field", &ast.Field{
+			Type:     intType(),
+			Doc:      &ast.CommentGroup{List: []*ast.Comment{{Text: "/// doc"}}},
+			Comment:  &ast.CommentGroup{List: []*ast.Comment{{Text: "// comment"}}},
+			IsStatic: true,
+			Access:   ast.Private,
+			Names:    []*ast.Ident{ident("x"), ident("y")},
+			Offset:   32,
+		}},
+		{"Field with default", &ast.Field{
+			Type:          &ast.PointerType{X: intType()},
+			Names:         []*ast.Ident{ident("p")},
+			Default:       &ast.BasicLit{Kind: ast.IntLit, Value: "0"},
+			DefaultTokens: []*ast.Token{{Token: token.KEYWORD, Lit: "nullptr"}},
+		}},
+		{"FuncType", funcType},
+		{"RecordType", &ast.RecordType{
+			Tag:    ast.Class,
+			Fields: &ast.FieldList{List: []*ast.Field{{Type: intType(), Names: []*ast.Ident{ident("x")}}}},
+			Methods: []*ast.FuncDecl{{
+				Name:        ident("get"),
+				MangledName: "_ZN3Foo3getEv",
+				Type:        &ast.FuncType{Params: &ast.FieldList{}, Ret: intType()},
+				IsConst:     true,
+				IsVirtual:   true,
+			}},
+			Size:  4,
+			Align: 4,
+		}},
+		{"RecordType with bases", &ast.RecordType{
+			Tag:     ast.Class,
+			Fields:  &ast.FieldList{},
+			Methods: []*ast.FuncDecl{},
+			Bases: []*ast.BaseSpec{
+				{Type: ident("Base"), Access: ast.Public},
+				{Type: ident("Other"), Access: ast.Protected, Virtual: true, Offset: 64},
+			},
+		}},
+		{"RecordType packed", &ast.RecordType{
+			Tag:     ast.Struct,
+			Fields:  &ast.FieldList{List: []*ast.Field{{Type: intType(), Names: []*ast.Ident{ident("x")}, Offset: 8}}},
+			Methods: []*ast.FuncDecl{},
+			Size:    5,
+			Align:   1,
+			Packed:  true,
+		}},
+		{"RecordType of forward declaration", &ast.RecordType{
+			Tag:       ast.Struct,
+			Fields:    &ast.FieldList{},
+			Methods:   []*ast.FuncDecl{},
+			IsForward: true,
+		}},
+		{"RecordType of abstract class", &ast.RecordType{
+			Tag:         ast.Class,
+			Fields:      &ast.FieldList{},
+			Methods:     []*ast.FuncDecl{},
+			Polymorphic: true,
+			Abstract:    true,
+			Size:        8,
+			Align:       8,
+		}},
+		{"FuncDecl", &ast.FuncDecl{
+			DeclBase: ast.DeclBase{
+				Loc:    &ast.Location{File: "foo.h"},
+				Doc:    doc,
+				Parent: ident("ns"),
+				Attrs:  []*ast.Attr{{Kind: ast.DeprecatedAttr, Args: []string{"use Len"}}, {Kind: ast.NoReturnAttr, Args: []string{}}},
+			},
+			Name:          ident("size"),
+			MangledName:   "_ZN2ns4sizeEi",
+			Type:          funcType,
+			IsInline:      true,
+			IsStatic:      true,
+			IsExplicit:    true,
+			IsConstructor: true,
+			IsDestructor:  true,
+			IsOverride:    true,
+		}},
+		{"VarDecl", &ast.VarDecl{
+			DeclBase:    ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+			Name:        ident("count"),
+			MangledName: "count",
+			Type:        intType(),
+		}},
+		{"VarDecl of constant", &ast.VarDecl{
+			DeclBase:    ast.DeclBase{Loc: &ast.Location{File: "foo.h"}, Doc: doc},
+			Name:        ident("kMax"),
+			MangledName: "_ZL4kMax",
+			Type:        intType(),
+			Value:       &ast.BasicLit{Kind: ast.IntLit, Value: "64"},
+		}},
+		{"FuncDecl of extern C", &ast.FuncDecl{
+			DeclBase:    ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+			Name:        ident("add"),
+			MangledName: "add",
+			Type:        funcType,
+			IsExternC:   true,
+		}},
+		{"TypeDecl", &ast.TypeDecl{
+			DeclBase: ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+			Name:     ident("Foo"),
+			Type:     &ast.RecordType{Tag: ast.Union, Fields: &ast.FieldList{}, Methods: []*ast.FuncDecl{}},
+		}},
+		{"TypedefDecl", &ast.TypedefDecl{
+			DeclBase: ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+			Name:     ident("foo_t"),
+			Type:     &ast.TagExpr{Tag: ast.Struct, Name: ident("foo")},
+		}},
+		{"EnumTypeDecl", &ast.EnumTypeDecl{
+			DeclBase: ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+			Name:     ident("Color"),
+			Type:     &ast.EnumType{Items: []*ast.EnumItem{{Name: ident("Red")}, {Name: ident("Green")}}},
+		}},
+		{"InstantiationDecl", &ast.InstantiationDecl{
+			DeclBase: ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+			Name:     ident("IntVector"),
+			Template: &ast.InstantiationType{
+				Template: ident("vector"),
+				Args:     &ast.FieldList{List: []*ast.Field{{Type: intType()}}},
+			},
+			Type: &ast.RecordType{Tag: ast.Class, Fields: &ast.FieldList{}, Methods: []*ast.FuncDecl{}, Size: 24, Align: 8},
+		}},
+		{"File", &ast.File{
+			Decls: []ast.Decl{
+				&ast.TypedefDecl{Name: ident("foo_t"), Type: intType()},
+			},
+			Includes: []*ast.Include{{Path: "bar.h"}},
+			Macros:   []*ast.Macro{{Name: "FOO", Tokens: []*ast.Token{{Token: token.IDENT, Lit: "FOO"}}}},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := marshal.Node(tc.node)
+			if err != nil {
+				t.Fatalf("Node failed: %v", err)
+			}
+			got, err := unmarshal.Node(data)
+			if err != nil {
+				t.Fatalf("unmarshal.Node failed: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got, tc.node) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				wantJSON, _ := json.MarshalIndent(tc.node, "", "  ")
+				t.Errorf("round trip mismatch\ngot: %s\nwant: %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestRoundTripPkg(t *testing.T) {
+	pkg := &llcppg.Pkg{
+		File: &ast.File{
+			Decls: []ast.Decl{
+				&ast.FuncDecl{
+					DeclBase:    ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+					Name:        ident("foo"),
+					MangledName: "foo",
+					Type:        &ast.FuncType{Params: &ast.FieldList{}, Ret: &ast.BuiltinType{Kind: ast.Void}},
+				},
+			},
+			Includes: []*ast.Include{},
+			Macros:   []*ast.Macro{},
+		},
+		FileMap: map[string]*llcppg.FileInfo{
+			"foo.h": {FileType: llcppg.Inter},
+			"bar.h": {FileType: llcppg.Third},
+		},
+	}
+	data, err := marshal.Pkg(pkg)
+	if err != nil {
+		t.Fatalf("Pkg failed: %v", err)
+	}
+	got, err := unmarshal.Pkg(data)
+	if err != nil {
+		t.Fatalf("unmarshal.Pkg failed: %v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(got, pkg) {
+		t.Errorf("round trip mismatch\n%s", data)
+	}
+}
+
+func TestRoundTripNDJSON(t *testing.T) {
+	pkg := &llcppg.Pkg{
+		File: &ast.File{
+			Decls: []ast.Decl{
+				&ast.TypedefDecl{DeclBase: ast.DeclBase{Loc: &ast.Location{File: "foo.h"}}, Name: ident("foo_t"), Type: intType()},
+				&ast.EnumTypeDecl{
+					DeclBase: ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+					Name:     ident("Color"),
+					Type:     &ast.EnumType{Items: []*ast.EnumItem{{Name: ident("Red")}}},
+				},
+			},
+			Includes: []*ast.Include{{Path: "bar.h"}},
+			Macros:   []*ast.Macro{{Loc: &ast.Location{File: "foo.h"}, Name: "FOO"}},
+		},
+		FileMap: map[string]*llcppg.FileInfo{"foo.h": {FileType: llcppg.Inter}},
+	}
+	data, err := marshal.PkgNDJSON(pkg)
+	if err != nil {
+		t.Fatalf("PkgNDJSON failed: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 5 {
+		t.Fatalf("expected 5 lines, got %d:\n%s", lines, data)
+	}
+	stream, err := unmarshal.NewPkgStream(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("NewPkgStream failed: %v", err)
+	}
+	got := &llcppg.Pkg{File: &ast.File{Decls: []ast.Decl{}}, FileMap: stream.FileMap}
+	for {
+		node, err := stream.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		switch node := node.(type) {
+		case *ast.Include:
+			got.File.Includes = append(got.File.Includes, node)
+		case *ast.Macro:
+			got.File.Macros = append(got.File.Macros, node)
+		case ast.Decl:
+			got.File.Decls = append(got.File.Decls, node)
+		}
+	}
+	if !reflect.DeepEqual(got, pkg) {
+		t.Errorf("round trip mismatch\n%s", data)
+	}
+}
+
+// the output must be the same as the output of llcppsigfetch
+func TestFormat(t *testing.T) {
+	pkg := &llcppg.Pkg{
+		File: &ast.File{
+			Decls: []ast.Decl{
+				&ast.TypeDecl{
+					DeclBase: ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+					Name:     ident("Foo"),
+					Type: &ast.RecordType{
+						Tag:    ast.Struct,
+						Fields: &ast.FieldList{List: []*ast.Field{{Type: intType(), Names: []*ast.Ident{ident("x")}}}},
+					},
+				},
+			},
+			Includes: []*ast.Include{{Path: "bar.h"}},
+		},
+		FileMap: map[string]*llcppg.FileInfo{"foo.h": {FileType: llcppg.Inter}},
+	}
+	expected := `{
+  "_Version": 1,
+  "File": {
+    "_Type": "File",
+    "decls": [{
+      "_Type": "TypeDecl",
+      "Loc": {"_Type": "Location", "File": "foo.h"},
+      "Doc": null,
+      "Parent": null,
+      "Name": {"_Type": "Ident", "Name": "Foo"},
+      "Type": {
+        "_Type": "RecordType",
+        "Tag": 0,
+        "Fields": {
+          "_Type": "FieldList",
+          "List": [{
+            "_Type": "Field",
+            "Type": {"_Type": "BuiltinType", "Kind": 6, "Flags": 0},
+            "Doc": null,
+            "Comment": null,
+            "IsStatic": false,
+            "Access": 0,
+            "Names": [{"_Type": "Ident", "Name": "x"}]
+          }]
+        },
+        "Methods": []
+      }
+    }],
+    "includes": [{"_Type": "Include", "Path": "bar.h"}],
+    "macros": []
+  },
+  "FileMap": {"foo.h": {"FileType": 1}}
+}`
+	data, err := marshal.Pkg(pkg)
+	if err != nil {
+		t.Fatalf("Pkg failed: %v", err)
+	}
+	var want bytes.Buffer
+	if err := json.Compact(&want, []byte(expected)); err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want.String() {
+		t.Errorf("format mismatch\ngot:  %s\nwant: %s", data, want.String())
+	}
+}
+
+func TestNodeError(t *testing.T) {
+	_, err := marshal.Node(&ast.Location{File: "foo.h"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported node type *ast.Location") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
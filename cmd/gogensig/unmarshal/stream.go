@@ -0,0 +1,85 @@
+package unmarshal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/llcppg"
+)
+
+// PkgStream reads a Pkg written as NDJSON, like the output of llcppsigfetch -ndjson=true.
+// The first line is the header of the Pkg with its FileMap and the names declared by
+// the decls of each file, and each following line is an include, macro or decl of the
+// File, which is read by Next as it comes.
+type PkgStream struct {
+	r       *bufio.Reader
+	FileMap map[string]*llcppg.FileInfo
+	Names   map[string][]string // the qualified names declared by the decls of each file, like llcppg.DeclNames
+}
+
+func NewPkgStream(r io.Reader) (*PkgStream, error) {
+	s := &PkgStream{r: bufio.NewReader(r)}
+	data, err := s.line()
+	if err == io.EOF {
+		return nil, fmt.Errorf("unmarshal error in PkgStream: missing header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	type headerTemp struct {
+		Type    string `json:"_Type"`
+		Version int    `json:"_Version"`
+		FileMap map[string]*llcppg.FileInfo
+		Names   map[string][]string
+	}
+	var header headerTemp
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, newDeserializeError("PkgStream", header, data, err)
+	}
+	if header.Type != "Pkg" {
+		return nil, fmt.Errorf("unmarshal error in PkgStream: got %q, want Pkg header", header.Type)
+	}
+	if header.Version > llcppg.SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d of Pkg, want at most %d", header.Version, llcppg.SchemaVersion)
+	}
+	s.FileMap = header.FileMap
+	s.Names = header.Names
+	if s.FileMap == nil {
+		s.FileMap = make(map[string]*llcppg.FileInfo)
+	}
+	return s, nil
+}
+
+// Next returns the next include, macro or decl of the stream, or io.EOF at its end.
+func (s *PkgStream) Next() (ast.Node, error) {
+	data, err := s.line()
+	if err != nil {
+		return nil, err
+	}
+	node, err := Node(data)
+	if err != nil {
+		return nil, err
+	}
+	switch node.(type) {
+	case *ast.Include, *ast.Macro, ast.Decl:
+		return node, nil
+	}
+	return nil, newUnexpectType("PkgStream", node, "*ast.Include, *ast.Macro, ast.Decl")
+}
+
+// line returns the next non-empty line, a line is read whole however long it is.
+func (s *PkgStream) line() ([]byte, error) {
+	for {
+		data, err := s.r.ReadBytes('\n')
+		if line := bytes.TrimSpace(data); len(line) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
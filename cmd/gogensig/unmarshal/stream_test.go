@@ -0,0 +1,107 @@
+package unmarshal_test
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/goplus/llcppg/ast"
+	"github.com/goplus/llcppg/cmd/gogensig/unmarshal"
+	"github.com/goplus/llcppg/llcppg"
+)
+
+func TestPkgStream(t *testing.T) {
+	// a long line is read whole
+	longName := strings.Repeat("x", 1<<17)
+	input := `{"_Type": "Pkg", "_Version": 1, "FileMap": {"foo.h": {"FileType": 1}}, "Names": {"foo.h": ["` + longName + `"]}}
+{"_Type": "Include", "Path": "bar.h"}
+
+{"_Type": "Macro", "Loc": {"_Type": "Location", "File": "foo.h"}, "Name": "FOO", "Tokens": null}
+{"_Type": "TypedefDecl", "Loc": {"_Type": "Location", "File": "foo.h"}, "Doc": null, "Parent": null, "Name": {"_Type": "Ident", "Name": "` + longName + `"}, "Type": {"_Type": "BuiltinType", "Kind": 6, "Flags": 0}}`
+
+	stream, err := unmarshal.NewPkgStream(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("NewPkgStream failed: %v", err)
+	}
+	expectedFileMap := map[string]*llcppg.FileInfo{"foo.h": {FileType: llcppg.Inter}}
+	if !reflect.DeepEqual(stream.FileMap, expectedFileMap) {
+		t.Errorf("unexpected FileMap: %v", stream.FileMap)
+	}
+	if expectedNames := map[string][]string{"foo.h": {longName}}; !reflect.DeepEqual(stream.Names, expectedNames) {
+		t.Errorf("unexpected Names: %v", stream.Names)
+	}
+	expected := []ast.Node{
+		&ast.Include{Path: "bar.h"},
+		&ast.Macro{Loc: &ast.Location{File: "foo.h"}, Name: "FOO"},
+		&ast.TypedefDecl{
+			DeclBase: ast.DeclBase{Loc: &ast.Location{File: "foo.h"}},
+			Name:     &ast.Ident{Name: longName},
+			Type:     &ast.BuiltinType{Kind: ast.Int},
+		},
+	}
+	for i, want := range expected {
+		got, err := stream.Next()
+		if err != nil {
+			t.Fatalf("Next %d failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Next %d: got %#v, want %#v", i, got, want)
+		}
+	}
+	if _, err := stream.Next(); err != io.EOF {
+		t.Errorf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestPkgStreamErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expectedErr string
+	}{
+		{
+			name:        "Empty",
+			input:       "\n",
+			expectedErr: "missing header",
+		},
+		{
+			name:        "Invalid header",
+			input:       `{"_Type": "Pkg"`,
+			expectedErr: "unmarshal error in PkgStream",
+		},
+		{
+			name:        "Not a header",
+			input:       `{"_Type": "File", "decls": []}`,
+			expectedErr: "want Pkg header",
+		},
+		{
+			name:        "Newer schema version",
+			input:       `{"_Type": "Pkg", "_Version": 1000}`,
+			expectedErr: "unsupported schema version 1000 of Pkg",
+		},
+		{
+			name: "Not a top-level node",
+			input: `{"_Type": "Pkg", "_Version": 1}
+{"_Type": "Ident", "Name": "foo"}`,
+			expectedErr: "unmarshal error in PkgStream: got *ast.Ident",
+		},
+		{
+			name: "Unknown node",
+			input: `{"_Type": "Pkg", "_Version": 1}
+{"_Type": "Unknown"}`,
+			expectedErr: "unknown node type: Unknown",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stream, err := unmarshal.NewPkgStream(strings.NewReader(tc.input))
+			if err == nil {
+				_, err = stream.Next()
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+				t.Errorf("Expected error containing %q, but got: %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
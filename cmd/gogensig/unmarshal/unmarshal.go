@@ -38,11 +38,16 @@ func init() {
 		"EnumType":    EnumType,
 		"FuncType":    FuncType,
 		"RecordType":  RecordType,
+		"BaseSpec":    BaseSpec,
 		"TypedefDecl": TypeDefDecl,
 
-		"FuncDecl":     FuncDecl,
-		"TypeDecl":     TypeDecl,
-		"EnumTypeDecl": EnumTypeDecl,
+		"InstantiationType": InstantiationType,
+
+		"FuncDecl":          FuncDecl,
+		"VarDecl":           VarDecl,
+		"TypeDecl":          TypeDecl,
+		"EnumTypeDecl":      EnumTypeDecl,
+		"InstantiationDecl": InstantiationDecl,
 
 		"File": File,
 	}
@@ -50,6 +55,7 @@ func init() {
 
 func Pkg(data []byte) (*llcppg.Pkg, error) {
 	type pkgTemp struct {
+		Version int `json:"_Version"`
 		File    json.RawMessage
 		FileMap map[string]*llcppg.FileInfo
 	}
@@ -58,6 +64,10 @@ func Pkg(data []byte) (*llcppg.Pkg, error) {
 	if err := json.Unmarshal(data, &pkgData); err != nil {
 		return nil, newDeserializeError("Pkg", pkgData, data, err)
 	}
+	// the output of an older llcppsigfetch has no version
+	if pkgData.Version > llcppg.SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d of Pkg, want at most %d", pkgData.Version, llcppg.SchemaVersion)
+	}
 	fileNode, err := Node(pkgData.File)
 	if err != nil {
 		return nil, newUnmarshalFieldError("Pkg", pkgData, "File", data, err)
@@ -179,7 +189,18 @@ func XType(data []byte, xType ast.Node) (ast.Node, error) {
 }
 
 func PointerType(data []byte) (ast.Node, error) {
-	return XType(data, &ast.PointerType{})
+	node, err := XType(data, &ast.PointerType{})
+	if err != nil {
+		return nil, err
+	}
+	var nullability struct {
+		Nullability ast.Nullability
+	}
+	if err := json.Unmarshal(data, &nullability); err != nil {
+		return nil, newDeserializeError("PointerType", nullability, data, err)
+	}
+	node.(*ast.PointerType).Nullability = nullability.Nullability
+	return node, nil
 }
 
 func LvalueRefType(data []byte) (ast.Node, error) {
@@ -236,6 +257,10 @@ func Field(data []byte) (ast.Node, error) {
 		Comment  *ast.CommentGroup
 		Access   ast.AccessSpecifier
 		IsStatic bool
+		Offset   int64
+
+		Default       json.RawMessage
+		DefaultTokens []*ast.Token
 	}
 	var fieldData fieldTemp
 	if err := json.Unmarshal(data, &fieldData); err != nil {
@@ -252,7 +277,22 @@ func Field(data []byte) (ast.Node, error) {
 		Comment:  fieldData.Comment,
 		Access:   fieldData.Access,
 		IsStatic: fieldData.IsStatic,
+		Offset:   fieldData.Offset,
 		Type:     typeNode.(ast.Expr),
+
+		DefaultTokens: fieldData.DefaultTokens,
+	}
+
+	if len(fieldData.Default) > 0 && !isJSONNull(fieldData.Default) {
+		defaultNode, err := Node(fieldData.Default)
+		if err != nil {
+			return nil, newUnmarshalFieldError("Field", fieldData, "Default", data, err)
+		}
+		defaultExpr, ok := defaultNode.(ast.Expr)
+		if !ok {
+			return nil, newUnexpectType("Field", defaultNode, "ast.Expr")
+		}
+		field.Default = defaultExpr
 	}
 
 	return field, nil
@@ -345,6 +385,40 @@ func ScopingExpr(data []byte) (ast.Node, error) {
 	return scopingExpr, nil
 }
 
+func InstantiationType(data []byte) (ast.Node, error) {
+	type instantiationTypeTemp struct {
+		Template json.RawMessage
+		Args     json.RawMessage
+	}
+	var instData instantiationTypeTemp
+	if err := json.Unmarshal(data, &instData); err != nil {
+		return nil, newDeserializeError("InstantiationType", instData, data, err)
+	}
+
+	templateNode, err := Node(instData.Template)
+	if err != nil {
+		return nil, newUnmarshalFieldError("InstantiationType", instData, "Template", data, err)
+	}
+	template, ok := templateNode.(ast.Expr)
+	if !ok {
+		return nil, newUnexpectType("InstantiationType", templateNode, "ast.Expr")
+	}
+
+	argsNode, err := Node(instData.Args)
+	if err != nil {
+		return nil, newUnmarshalFieldError("InstantiationType", instData, "Args", data, err)
+	}
+	args, ok := argsNode.(*ast.FieldList)
+	if !ok {
+		return nil, newUnexpectType("InstantiationType", argsNode, &ast.FieldList{})
+	}
+
+	return &ast.InstantiationType{
+		Template: template,
+		Args:     args,
+	}, nil
+}
+
 func EnumItem(data []byte) (ast.Node, error) {
 	type enumItemTemp struct {
 		Name  *ast.Ident
@@ -377,14 +451,16 @@ func EnumItem(data []byte) (ast.Node, error) {
 
 func EnumType(data []byte) (ast.Node, error) {
 	type enumTypeTemp struct {
-		Items []json.RawMessage
+		Items   []json.RawMessage
+		IntType json.RawMessage
+		Scoped  bool
 	}
 	var enumTypeData enumTypeTemp
 	if err := json.Unmarshal(data, &enumTypeData); err != nil {
 		return nil, newDeserializeError("EnumType", enumTypeData, data, err)
 	}
 
-	result := &ast.EnumType{}
+	result := &ast.EnumType{Scoped: enumTypeData.Scoped}
 	for _, itemData := range enumTypeData.Items {
 		itemNode, err := Node(itemData)
 		if err != nil {
@@ -397,14 +473,33 @@ func EnumType(data []byte) (ast.Node, error) {
 		result.Items = append(result.Items, item)
 	}
 
+	if len(enumTypeData.IntType) > 0 && !isJSONNull(enumTypeData.IntType) {
+		intTypeNode, err := Node(enumTypeData.IntType)
+		if err != nil {
+			return nil, newUnmarshalFieldError("EnumType", enumTypeData, "IntType", data, err)
+		}
+		intType, ok := intTypeNode.(ast.Expr)
+		if !ok {
+			return nil, newUnexpectType("EnumType", intTypeNode, "ast.Expr")
+		}
+		result.IntType = intType
+	}
+
 	return result, nil
 }
 
 func RecordType(data []byte) (ast.Node, error) {
 	type recordTypeTemp struct {
-		Tag     ast.Tag
-		Fields  json.RawMessage
-		Methods []json.RawMessage
+		Tag         ast.Tag
+		Fields      json.RawMessage
+		Methods     []json.RawMessage
+		Bases       []json.RawMessage
+		Polymorphic bool
+		Abstract    bool
+		Size        int64
+		Align       int64
+		Packed      bool
+		IsForward   bool
 	}
 	var recordTypeData recordTypeTemp
 	if err := json.Unmarshal(data, &recordTypeData); err != nil {
@@ -412,8 +507,14 @@ func RecordType(data []byte) (ast.Node, error) {
 	}
 
 	recordType := &ast.RecordType{
-		Tag:     recordTypeData.Tag,
-		Methods: []*ast.FuncDecl{},
+		Tag:         recordTypeData.Tag,
+		Methods:     []*ast.FuncDecl{},
+		Polymorphic: recordTypeData.Polymorphic,
+		Abstract:    recordTypeData.Abstract,
+		Size:        recordTypeData.Size,
+		Align:       recordTypeData.Align,
+		Packed:      recordTypeData.Packed,
+		IsForward:   recordTypeData.IsForward,
 	}
 
 	fieldsNode, err := Node(recordTypeData.Fields)
@@ -438,9 +539,48 @@ func RecordType(data []byte) (ast.Node, error) {
 		recordType.Methods = append(recordType.Methods, method)
 	}
 
+	for _, baseData := range recordTypeData.Bases {
+		baseNode, err := Node(baseData)
+		if err != nil {
+			return nil, newUnmarshalFieldError("RecordType", recordTypeData, "Bases", data, err)
+		}
+		base, ok := baseNode.(*ast.BaseSpec)
+		if !ok {
+			return nil, newUnexpectType("RecordType", baseNode, &ast.BaseSpec{})
+		}
+		recordType.Bases = append(recordType.Bases, base)
+	}
+
 	return recordType, nil
 }
 
+func BaseSpec(data []byte) (ast.Node, error) {
+	type baseSpecTemp struct {
+		Type    json.RawMessage
+		Access  ast.AccessSpecifier
+		Virtual bool
+		Offset  int64
+	}
+	var baseData baseSpecTemp
+	if err := json.Unmarshal(data, &baseData); err != nil {
+		return nil, newDeserializeError("BaseSpec", baseData, data, err)
+	}
+	typeNode, err := Node(baseData.Type)
+	if err != nil {
+		return nil, newUnmarshalFieldError("BaseSpec", baseData, "Type", data, err)
+	}
+	typ, ok := typeNode.(ast.Expr)
+	if !ok {
+		return nil, newUnexpectType("BaseSpec", typeNode, "ast.Expr")
+	}
+	return &ast.BaseSpec{
+		Type:    typ,
+		Access:  baseData.Access,
+		Virtual: baseData.Virtual,
+		Offset:  baseData.Offset,
+	}, nil
+}
+
 func FuncType(data []byte) (ast.Node, error) {
 	type funcTypeTemp struct {
 		Params json.RawMessage
@@ -481,6 +621,7 @@ func FuncDecl(data []byte) (ast.Node, error) {
 		Type          json.RawMessage
 		IsInline      bool
 		IsStatic      bool
+		IsExternC     bool
 		IsConst       bool
 		IsExplicit    bool
 		IsConstructor bool
@@ -514,6 +655,7 @@ func FuncDecl(data []byte) (ast.Node, error) {
 		MangledName:   funcDeclData.MangledName,
 		IsInline:      funcDeclData.IsInline,
 		IsStatic:      funcDeclData.IsStatic,
+		IsExternC:     funcDeclData.IsExternC,
 		IsConst:       funcDeclData.IsConst,
 		IsExplicit:    funcDeclData.IsExplicit,
 		IsConstructor: funcDeclData.IsConstructor,
@@ -523,6 +665,41 @@ func FuncDecl(data []byte) (ast.Node, error) {
 	}, nil
 }
 
+func VarDecl(data []byte) (ast.Node, error) {
+	type varDeclTemp struct {
+		Name        *ast.Ident
+		MangledName string
+		Type        json.RawMessage
+		Value       *ast.BasicLit
+	}
+	var varDeclData varDeclTemp
+	if err := json.Unmarshal(data, &varDeclData); err != nil {
+		return nil, newDeserializeError("VarDecl", varDeclData, data, err)
+	}
+
+	typeNode, err := Node(varDeclData.Type)
+	if err != nil {
+		return nil, newUnmarshalFieldError("VarDecl", varDeclData, "Type", data, err)
+	}
+	typ, ok := typeNode.(ast.Expr)
+	if !ok {
+		return nil, newUnexpectType("VarDecl", typeNode, "ast.Expr")
+	}
+
+	declBase, err := declBase(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.VarDecl{
+		DeclBase:    declBase,
+		Name:        varDeclData.Name,
+		MangledName: varDeclData.MangledName,
+		Type:        typ,
+		Value:       varDeclData.Value,
+	}, nil
+}
+
 func TypeDecl(data []byte) (ast.Node, error) {
 	type typeDeclTemp struct {
 		Name *ast.Ident
@@ -554,6 +731,48 @@ func TypeDecl(data []byte) (ast.Node, error) {
 	}, nil
 }
 
+func InstantiationDecl(data []byte) (ast.Node, error) {
+	type instantiationDeclTemp struct {
+		Name     *ast.Ident
+		Template json.RawMessage
+		Type     json.RawMessage
+	}
+	var instDeclData instantiationDeclTemp
+	if err := json.Unmarshal(data, &instDeclData); err != nil {
+		return nil, newDeserializeError("InstantiationDecl", instDeclData, data, err)
+	}
+
+	templateNode, err := Node(instDeclData.Template)
+	if err != nil {
+		return nil, newUnmarshalFieldError("InstantiationDecl", instDeclData, "Template", data, err)
+	}
+	template, ok := templateNode.(*ast.InstantiationType)
+	if !ok {
+		return nil, newUnexpectType("InstantiationDecl", templateNode, &ast.InstantiationType{})
+	}
+
+	typeNode, err := Node(instDeclData.Type)
+	if err != nil {
+		return nil, newUnmarshalFieldError("InstantiationDecl", instDeclData, "Type", data, err)
+	}
+	typ, ok := typeNode.(*ast.RecordType)
+	if !ok {
+		return nil, newUnexpectType("InstantiationDecl", typeNode, &ast.RecordType{})
+	}
+
+	declBase, err := declBase(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ast.InstantiationDecl{
+		DeclBase: declBase,
+		Name:     instDeclData.Name,
+		Template: template,
+		Type:     typ,
+	}, nil
+}
+
 func TypeDefDecl(data []byte) (ast.Node, error) {
 	type typeDefDeclTemp struct {
 		Name *ast.Ident
@@ -621,6 +840,7 @@ func declBase(data []byte) (ast.DeclBase, error) {
 		Loc    *ast.Location
 		Doc    *ast.CommentGroup
 		Parent json.RawMessage
+		Attrs  []*ast.Attr
 	}
 	var declBaseData declBaseTemp
 	if err := json.Unmarshal(data, &declBaseData); err != nil {
@@ -628,8 +848,9 @@ func declBase(data []byte) (ast.DeclBase, error) {
 	}
 
 	declBase := ast.DeclBase{
-		Loc: declBaseData.Loc,
-		Doc: declBaseData.Doc,
+		Loc:   declBaseData.Loc,
+		Doc:   declBaseData.Doc,
+		Attrs: declBaseData.Attrs,
 	}
 
 	if !isJSONNull(declBaseData.Parent) {
@@ -8,6 +8,7 @@ import (
 	"github.com/goplus/llcppg/ast"
 	"github.com/goplus/llcppg/cmd/gogensig/unmarshal"
 	"github.com/goplus/llcppg/llcppg"
+	"github.com/goplus/llcppg/token"
 )
 
 func TestUnmarshalNode(t *testing.T) {
@@ -69,6 +70,22 @@ func TestUnmarshalNode(t *testing.T) {
 					},
 				}},
 		},
+		{
+			name: "PointerType with nullability",
+			json: `{
+					"_Type":	"PointerType",
+					"X":	{
+						"_Type":	"BuiltinType",
+						"Kind":	6,
+						"Flags":	0
+					},
+					"Nullability":	1
+				}`,
+			expected: &ast.PointerType{
+				X:           &ast.BuiltinType{Kind: 6},
+				Nullability: ast.Nonnull,
+			},
+		},
 		{
 			name: "ArrayType",
 			json: `{
@@ -306,6 +323,81 @@ func TestUnmarshalNode(t *testing.T) {
 				Names:    []*ast.Ident{{Name: "a"}},
 			},
 		},
+		{
+			name: "Field with default",
+			json: `{
+				"_Type":	"Field",
+				"Type":	{
+					"_Type":	"BuiltinType",
+					"Kind":	6,
+					"Flags":	0
+				},
+				"Doc":	null,
+				"Comment":	null,
+				"IsStatic":	false,
+				"Access":	0,
+				"Names":	[{
+						"_Type":	"Ident",
+						"Name":	"mode"
+					}],
+				"Default":	{
+					"_Type":	"BasicLit",
+					"Kind":	0,
+					"Value":	"0644"
+				}
+			}`,
+			expected: &ast.Field{
+				Type:    &ast.BuiltinType{Kind: 6},
+				Names:   []*ast.Ident{{Name: "mode"}},
+				Default: &ast.BasicLit{Kind: ast.IntLit, Value: "0644"},
+			},
+		},
+		{
+			name: "Field with default tokens",
+			json: `{
+				"_Type":	"Field",
+				"Type":	{
+					"_Type":	"BuiltinType",
+					"Kind":	6,
+					"Flags":	0
+				},
+				"Doc":	null,
+				"Comment":	null,
+				"IsStatic":	false,
+				"Access":	0,
+				"Names":	[{
+						"_Type":	"Ident",
+						"Name":	"size"
+					}],
+				"DefaultTokens":	[{
+						"_Type":	"Token",
+						"Token":	2,
+						"Lit":	"sizeof"
+					}, {
+						"_Type":	"Token",
+						"Token":	1,
+						"Lit":	"("
+					}, {
+						"_Type":	"Token",
+						"Token":	2,
+						"Lit":	"int"
+					}, {
+						"_Type":	"Token",
+						"Token":	1,
+						"Lit":	")"
+					}]
+			}`,
+			expected: &ast.Field{
+				Type:  &ast.BuiltinType{Kind: 6},
+				Names: []*ast.Ident{{Name: "size"}},
+				DefaultTokens: []*ast.Token{
+					{Token: token.KEYWORD, Lit: "sizeof"},
+					{Token: token.PUNCT, Lit: "("},
+					{Token: token.KEYWORD, Lit: "int"},
+					{Token: token.PUNCT, Lit: ")"},
+				},
+			},
+		},
 		{
 			name: "FieldList",
 			json: `{
@@ -535,6 +627,172 @@ func TestUnmarshalNode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "FuncDecl with Attrs",
+			json: `{
+				"_Type":	"FuncDecl",
+				"Loc":	null,
+				"Doc":	null,
+				"Parent":	null,
+				"Attrs":	[{
+						"_Type":	"Attr",
+						"Kind":	0,
+						"Args":	["use bar"]
+					}, {
+						"_Type":	"Attr",
+						"Kind":	3,
+						"Args":	["printf", "1", "2"]
+					}],
+				"Name":	{
+					"_Type":	"Ident",
+					"Name":	"foo"
+				},
+				"Type":	{
+					"_Type":	"FuncType",
+					"Params":	{
+						"_Type":	"FieldList",
+						"List":	[]
+					},
+					"Ret":	{
+						"_Type":	"BuiltinType",
+						"Kind":	0,
+						"Flags":	0
+					}
+				},
+				"IsInline":	false,
+				"IsStatic":	false,
+				"IsConst":	false,
+				"IsExplicit":	false,
+				"IsConstructor":	false,
+				"IsDestructor":	false,
+				"IsVirtual":	false,
+				"IsOverride":	false
+			}`,
+			expected: &ast.FuncDecl{
+				DeclBase: ast.DeclBase{
+					Attrs: []*ast.Attr{
+						{Kind: ast.DeprecatedAttr, Args: []string{"use bar"}},
+						{Kind: ast.FormatAttr, Args: []string{"printf", "1", "2"}},
+					},
+				},
+				Name: &ast.Ident{Name: "foo"},
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{},
+					Ret:    &ast.BuiltinType{Kind: 0},
+				},
+			},
+		},
+		{
+			name: "FuncDecl with Doxygen",
+			json: `{
+				"_Type":	"FuncDecl",
+				"Loc":	null,
+				"Doc":	{
+					"_Type":	"CommentGroup",
+					"List":	[{
+							"_Type":	"Comment",
+							"Text":	"/** @brief Opens a file. @param path the path */\n"
+						}],
+					"Doxygen":	{
+						"_Type":	"DocComment",
+						"Brief":	"Opens a file.",
+						"Blocks":	[{
+								"_Type":	"DocBlock",
+								"Text":	"open(path);",
+								"Code":	true
+							}],
+						"Params":	[{
+								"_Type":	"DocParam",
+								"Name":	"path",
+								"Direction":	"in",
+								"Text":	"the path"
+							}],
+						"Return":	"",
+						"Deprecated":	null,
+						"See":	["close"]
+					}
+				},
+				"Parent":	null,
+				"Name":	{
+					"_Type":	"Ident",
+					"Name":	"open"
+				},
+				"Type":	{
+					"_Type":	"FuncType",
+					"Params":	{
+						"_Type":	"FieldList",
+						"List":	[]
+					},
+					"Ret":	{
+						"_Type":	"BuiltinType",
+						"Kind":	0,
+						"Flags":	0
+					}
+				},
+				"IsInline":	false,
+				"IsStatic":	false,
+				"IsConst":	false,
+				"IsExplicit":	false,
+				"IsConstructor":	false,
+				"IsDestructor":	false,
+				"IsVirtual":	false,
+				"IsOverride":	false
+			}`,
+			expected: &ast.FuncDecl{
+				DeclBase: ast.DeclBase{
+					Doc: &ast.CommentGroup{
+						List: []*ast.Comment{{Text: "/** @brief Opens a file. @param path the path */\n"}},
+						Doxygen: &ast.DocComment{
+							Brief:  "Opens a file.",
+							Blocks: []*ast.DocBlock{{Text: "open(path);", Code: true}},
+							Params: []*ast.DocParam{{Name: "path", Direction: "in", Text: "the path"}},
+							See:    []string{"close"},
+						},
+					},
+				},
+				Name: &ast.Ident{Name: "open"},
+				Type: &ast.FuncType{
+					Params: &ast.FieldList{},
+					Ret:    &ast.BuiltinType{Kind: 0},
+				},
+			},
+		},
+		{
+			name: "VarDecl",
+			json: `{
+				"_Type":	"VarDecl",
+				"Loc":	{
+					"_Type":	"Location",
+					"File":	"temp.h"
+				},
+				"Doc":	null,
+				"Parent":	null,
+				"Name":	{
+					"_Type":	"Ident",
+					"Name":	"kPi"
+				},
+				"MangledName":	"_ZL3kPi",
+				"Type":	{
+					"_Type":	"BuiltinType",
+					"Kind":	8,
+					"Flags":	16
+				},
+				"Value":	{
+					"_Type":	"BasicLit",
+					"Kind":	1,
+					"Value":	"3.14"
+				}
+			}`,
+			expected: &ast.VarDecl{
+				DeclBase: ast.DeclBase{
+					Loc: &ast.Location{File: "temp.h"},
+				},
+				Name:        &ast.Ident{Name: "kPi"},
+				MangledName: "_ZL3kPi",
+				Type:        &ast.BuiltinType{Kind: 8, Flags: 16},
+				Value:       &ast.BasicLit{Kind: ast.FloatLit, Value: "3.14"},
+			},
+		},
 		{
 			name: "RecordType",
 			json: `{
@@ -776,6 +1034,184 @@ func TestUnmarshalNode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "RecordType with layout",
+			json: `{
+					"_Type":	"RecordType",
+					"Tag":	0,
+					"Fields":	{
+						"_Type":	"FieldList",
+						"List":	[{
+								"_Type":	"Field",
+								"Type":	{
+									"_Type":	"BuiltinType",
+									"Kind":	6,
+									"Flags":	0
+								},
+								"Doc":	null,
+								"Comment":	null,
+								"IsStatic":	false,
+								"Access":	1,
+								"Names":	[{
+										"_Type":	"Ident",
+										"Name":	"a"
+									}]
+							}, {
+								"_Type":	"Field",
+								"Type":	{
+									"_Type":	"BuiltinType",
+									"Kind":	6,
+									"Flags":	0
+								},
+								"Doc":	null,
+								"Comment":	null,
+								"IsStatic":	false,
+								"Access":	1,
+								"Names":	[{
+										"_Type":	"Ident",
+										"Name":	"b"
+									}],
+								"Offset":	32
+							}]
+					},
+					"Methods":	[],
+					"Size":	8,
+					"Align":	4
+				}`,
+			expected: &ast.RecordType{
+				Tag: 0,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Type:   &ast.BuiltinType{Kind: 6},
+							Access: 1,
+							Names:  []*ast.Ident{{Name: "a"}},
+						},
+						{
+							Type:   &ast.BuiltinType{Kind: 6},
+							Access: 1,
+							Names:  []*ast.Ident{{Name: "b"}},
+							Offset: 32,
+						},
+					},
+				},
+				Methods: []*ast.FuncDecl{},
+				Size:    8,
+				Align:   4,
+			},
+		},
+		{
+			name: "RecordType packed",
+			json: `{
+					"_Type":	"RecordType",
+					"Tag":	0,
+					"Fields":	{
+						"_Type":	"FieldList",
+						"List":	[{
+								"_Type":	"Field",
+								"Type":	{
+									"_Type":	"BuiltinType",
+									"Kind":	6,
+									"Flags":	0
+								},
+								"Doc":	null,
+								"Comment":	null,
+								"IsStatic":	false,
+								"Access":	1,
+								"Names":	[{
+										"_Type":	"Ident",
+										"Name":	"b"
+									}],
+								"Offset":	8
+							}]
+					},
+					"Methods":	[],
+					"Size":	5,
+					"Align":	1,
+					"Packed":	true
+				}`,
+			expected: &ast.RecordType{
+				Tag: 0,
+				Fields: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Type:   &ast.BuiltinType{Kind: 6},
+							Access: 1,
+							Names:  []*ast.Ident{{Name: "b"}},
+							Offset: 8,
+						},
+					},
+				},
+				Methods: []*ast.FuncDecl{},
+				Size:    5,
+				Align:   1,
+				Packed:  true,
+			},
+		},
+		{
+			name: "RecordType with bases",
+			json: `{
+					"_Type":	"RecordType",
+					"Tag":	3,
+					"Fields":	{
+						"_Type":	"FieldList",
+						"List":	null
+					},
+					"Methods":	[],
+					"Bases":	[{
+							"_Type":	"BaseSpec",
+							"Type":	{
+								"_Type":	"Ident",
+								"Name":	"Base"
+							},
+							"Access":	1,
+							"Virtual":	false
+						}, {
+							"_Type":	"BaseSpec",
+							"Type":	{
+								"_Type":	"Ident",
+								"Name":	"Other"
+							},
+							"Access":	3,
+							"Virtual":	true,
+							"Offset":	64
+						}]
+				}`,
+			expected: &ast.RecordType{
+				Tag:     ast.Class,
+				Fields:  &ast.FieldList{},
+				Methods: []*ast.FuncDecl{},
+				Bases: []*ast.BaseSpec{
+					{Type: &ast.Ident{Name: "Base"}, Access: ast.Public},
+					{Type: &ast.Ident{Name: "Other"}, Access: ast.Private, Virtual: true, Offset: 64},
+				},
+			},
+		},
+		{
+			name: "RecordType of abstract class",
+			json: `{
+					"_Type":	"RecordType",
+					"Tag":	3,
+					"Fields":	{
+						"_Type":	"FieldList",
+						"List":	null
+					},
+					"Methods":	[],
+					"Polymorphic":	true,
+					"Abstract":	true,
+					"Size":	8,
+					"Align":	8
+				}`,
+			expected: &ast.RecordType{
+				Tag:         ast.Class,
+				Fields:      &ast.FieldList{},
+				Methods:     []*ast.FuncDecl{},
+				Polymorphic: true,
+				Abstract:    true,
+				Size:        8,
+				Align:       8,
+			},
+		},
 		{
 			name: "TypedefDecl",
 			json: `{
@@ -818,6 +1254,125 @@ func TestUnmarshalNode(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "InstantiationType",
+			json: `{
+					"_Type":	"InstantiationType",
+					"Template":	{
+						"_Type":	"Ident",
+						"Name":	"Foo"
+					},
+					"Args":	{
+						"_Type":	"FieldList",
+						"List":	[{
+							"_Type":	"Field",
+							"Type":	{
+								"_Type":	"BuiltinType",
+								"Kind":	6,
+								"Flags":	0
+							},
+							"Doc":	null,
+							"Comment":	null,
+							"IsStatic":	false,
+							"Access":	0,
+							"Names":	null
+						}]
+					}
+				}`,
+			expected: &ast.InstantiationType{
+				Template: &ast.Ident{
+					Name: "Foo",
+				},
+				Args: &ast.FieldList{
+					List: []*ast.Field{
+						{
+							Type: &ast.BuiltinType{
+								Kind:  6,
+								Flags: 0,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "InstantiationDecl",
+			json: `{
+				"_Type":	"InstantiationDecl",
+				"Loc":	{
+					"_Type":	"Location",
+					"File":	"temp.h"
+				},
+				"Doc":	null,
+				"Parent":	null,
+				"Name":	{
+					"_Type":	"Ident",
+					"Name":	"Foo<int>"
+				},
+				"Template":	{
+					"_Type":	"InstantiationType",
+					"Template":	{
+						"_Type":	"Ident",
+						"Name":	"Foo"
+					},
+					"Args":	{
+						"_Type":	"FieldList",
+						"List":	[{
+							"_Type":	"Field",
+							"Type":	{
+								"_Type":	"BuiltinType",
+								"Kind":	6,
+								"Flags":	0
+							},
+							"Doc":	null,
+							"Comment":	null,
+							"IsStatic":	false,
+							"Access":	0,
+							"Names":	null
+						}]
+					}
+				},
+				"Type":	{
+					"_Type":	"RecordType",
+					"Tag":	3,
+					"Fields":	{
+						"_Type":	"FieldList",
+						"List":	[]
+					},
+					"Methods":	[]
+				}
+			}`,
+			expected: &ast.InstantiationDecl{
+				DeclBase: ast.DeclBase{
+					Loc: &ast.Location{
+						File: "temp.h",
+					},
+				},
+				Name: &ast.Ident{
+					Name: "Foo<int>",
+				},
+				Template: &ast.InstantiationType{
+					Template: &ast.Ident{
+						Name: "Foo",
+					},
+					Args: &ast.FieldList{
+						List: []*ast.Field{
+							{
+								Type: &ast.BuiltinType{
+									Kind:  6,
+									Flags: 0,
+								},
+							},
+						},
+					},
+				},
+				Type: &ast.RecordType{
+					Tag:     3,
+					Fields:  &ast.FieldList{},
+					Methods: []*ast.FuncDecl{},
+				},
+			},
+		},
 		{
 			name: "EnumItem",
 			json: `{
@@ -1719,6 +2274,74 @@ func TestUnmarshalErrors(t *testing.T) {
 			expectedErr: "unmarshal error in declBase when converting Parent of unmarshal.declBaseTemp",
 		},
 
+		// unmarshalInstantiationType errors
+		{
+			name:        "unmarshalInstantiationType - Invalid JSON",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"invalid": "json"`,
+			expectedErr: "unmarshal error in InstantiationType into unmarshal.instantiationTypeTemp",
+		},
+		{
+			name:        "unmarshalInstantiationType - Invalid Template",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"Template": {"_Type": "InvalidType"}, "Args": {"_Type": "FieldList", "List": []}}`,
+			expectedErr: "unmarshal error in InstantiationType when converting Template of unmarshal.instantiationTypeTemp",
+		},
+		{
+			name:        "unmarshalInstantiationType - Unexpected Template",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"Template": {"_Type": "Token", "Token": 1, "Lit": "test"}, "Args": {"_Type": "FieldList", "List": []}}`,
+			expectedErr: "unmarshal error in InstantiationType: got *ast.Token, want ast.Expr",
+		},
+		{
+			name:        "unmarshalInstantiationType - Invalid Args",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"Template": {"_Type": "Ident", "Name": "Foo"}, "Args": {"_Type": "InvalidType"}}`,
+			expectedErr: "unmarshal error in InstantiationType when converting Args of unmarshal.instantiationTypeTemp",
+		},
+		{
+			name:        "unmarshalInstantiationType - Unexpected Args",
+			fn:          unmarshal.InstantiationType,
+			input:       `{"Template": {"_Type": "Ident", "Name": "Foo"}, "Args": {"_Type": "Token", "Token": 1, "Lit": "test"}}`,
+			expectedErr: "unmarshal error in InstantiationType: got *ast.Token, want *ast.FieldList",
+		},
+		// unmarshalInstantiationDecl errors
+		{
+			name:        "unmarshalInstantiationDecl - Invalid JSON",
+			fn:          unmarshal.InstantiationDecl,
+			input:       `{"invalid": "json"`,
+			expectedErr: "unmarshal error in InstantiationDecl into unmarshal.instantiationDeclTemp",
+		},
+		{
+			name:        "unmarshalInstantiationDecl - Invalid Template",
+			fn:          unmarshal.InstantiationDecl,
+			input:       `{"Name": {"_Type": "Ident", "Name": "Foo<int>"}, "Template": {"_Type": "InvalidType"}}`,
+			expectedErr: "unmarshal error in InstantiationDecl when converting Template of unmarshal.instantiationDeclTemp",
+		},
+		{
+			name:        "unmarshalInstantiationDecl - Unexpected Template",
+			fn:          unmarshal.InstantiationDecl,
+			input:       `{"Name": {"_Type": "Ident", "Name": "Foo<int>"}, "Template": {"_Type": "Ident", "Name": "Foo"}}`,
+			expectedErr: "unmarshal error in InstantiationDecl: got *ast.Ident, want *ast.InstantiationType",
+		},
+		{
+			name:        "unmarshalInstantiationDecl - Invalid Type",
+			fn:          unmarshal.InstantiationDecl,
+			input:       `{"Name": {"_Type": "Ident", "Name": "Foo<int>"}, "Template": {"_Type": "InstantiationType", "Template": {"_Type": "Ident", "Name": "Foo"}, "Args": {"_Type": "FieldList", "List": []}}, "Type": {"_Type": "InvalidType"}}`,
+			expectedErr: "unmarshal error in InstantiationDecl when converting Type of unmarshal.instantiationDeclTemp",
+		},
+		{
+			name:        "unmarshalInstantiationDecl - Unexpected Type",
+			fn:          unmarshal.InstantiationDecl,
+			input:       `{"Name": {"_Type": "Ident", "Name": "Foo<int>"}, "Template": {"_Type": "InstantiationType", "Template": {"_Type": "Ident", "Name": "Foo"}, "Args": {"_Type": "FieldList", "List": []}}, "Type": {"_Type": "Token", "Token": 1, "Lit": "test"}}`,
+			expectedErr: "unmarshal error in InstantiationDecl: got *ast.Token, want *ast.RecordType",
+		},
+		{
+			name:        "unmarshalInstantiationDecl - Invalid DeclBase",
+			fn:          unmarshal.InstantiationDecl,
+			input:       `{"Name": {"_Type": "Ident", "Name": "Foo<int>"}, "Template": {"_Type": "InstantiationType", "Template": {"_Type": "Ident", "Name": "Foo"}, "Args": {"_Type": "FieldList", "List": []}}, "Type": {"_Type": "RecordType", "Tag": 3, "Fields": {"_Type": "FieldList", "List": []}, "Methods": []}, "Loc": {"_Type": "InvalidType"}}`,
+			expectedErr: "unmarshal error in declBase when converting Parent of unmarshal.declBaseTemp",
+		},
 		// unmarshalFile errors
 		{
 			name:        "unmarshalFile - Invalid JSON",
@@ -1780,6 +2403,11 @@ func TestUnmarshalPkgErrors(t *testing.T) {
 			input:       `{"File": {"_Type": "Token", "Token": 1, "Lit": "test"}}`,
 			expectedErr: "unmarshal error in File: got *ast.Token, want *ast.File",
 		},
+		{
+			name:        "Newer schema version",
+			input:       `{"_Version": 1000, "File": {"_Type": "File", "decls": []}}`,
+			expectedErr: "unsupported schema version 1000 of Pkg",
+		},
 	}
 
 	for _, tc := range testCases {
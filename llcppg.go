@@ -24,6 +24,7 @@ import (
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 
 	"github.com/goplus/llcppg/_xtool/llcppsymg/args"
 	"github.com/goplus/llcppg/cmd/gogensig/config"
@@ -74,10 +75,17 @@ func llcppsymg(conf []byte, v verboseFlags) error {
 	return cmd.Run()
 }
 
-func llcppsigfetch(conf []byte, v verboseFlags, out *io.PipeWriter) {
+func llcppsigfetch(conf []byte, v verboseFlags, ndjson bool, cacheDir string, out *io.PipeWriter) {
+	cmdArgs := []string{"-", "-ClangResourceDir=" + config.ClangResourceDir()}
+	if ndjson {
+		cmdArgs = append(cmdArgs, "-ndjson=true")
+	}
+	if cacheDir != "" {
+		cmdArgs = append(cmdArgs, "-cache="+cacheDir)
+	}
 	cmd := command(CommandOptions{
 		Name:    "llcppsigfetch",
-		Args:    []string{"-", "-ClangResourceDir=" + config.ClangResourceDir()},
+		Args:    cmdArgs,
 		Verbose: (v & VerboseSigfetch) != 0,
 	})
 	cmd.Stdin = bytes.NewReader(conf)
@@ -88,10 +96,20 @@ func llcppsigfetch(conf []byte, v verboseFlags, out *io.PipeWriter) {
 	out.Close()
 }
 
-func gogensig(in io.Reader, cfg string, v verboseFlags) error {
+func gogensig(in io.Reader, cfg string, v verboseFlags, ndjson, verifyLayout bool, target *llcppg.Target) error {
+	cmdArgs := []string{"-", "-cfg=" + cfg}
+	if target != nil {
+		cmdArgs = append(cmdArgs, "-target="+target.Goos()+"/"+target.Goarch())
+	}
+	if ndjson {
+		cmdArgs = append(cmdArgs, "-ndjson=true")
+	}
+	if verifyLayout {
+		cmdArgs = append(cmdArgs, "-verify-layout=true")
+	}
 	cmd := command(CommandOptions{
 		Name:    "gogensig",
-		Args:    []string{"-", "-cfg=" + cfg},
+		Args:    cmdArgs,
 		Verbose: (v & VerboseGogen) != 0,
 	})
 	cmd.Stdin = in
@@ -101,10 +119,13 @@ func gogensig(in io.Reader, cfg string, v verboseFlags) error {
 }
 
 func main() {
-	var symbGen, codeGen, help bool
+	var symbGen, codeGen, ndjson, cache, help bool
+	var targetFlag string
 	var vSymg, vSigfetch, vGogen, vAll bool
 	flag.Usage = func() {
-		fmt.Fprintln(os.Stderr, "Usage: llcppg [-v|-vfetch|-vsymg|-vgogen] [-symbgen] [-codegen] [-h|--help] [config-file]")
+		fmt.Fprintln(os.Stderr, "Usage: llcppg [-v|-vfetch|-vsymg|-vgogen] [-symbgen] [-codegen] [-ndjson] [-cache] [-target goos/goarch] [-h|--help] [verify-layout] [config-file]")
+		fmt.Fprintln(os.Stderr, "Commands:")
+		fmt.Fprintln(os.Stderr, "  verify-layout\tGenerate the Go code binding and compare the layout of its types with the C types")
 		fmt.Fprintln(os.Stderr, "Options:")
 		flag.PrintDefaults()
 	}
@@ -114,6 +135,9 @@ func main() {
 	flag.BoolVar(&vGogen, "vgogen", false, "Enable verbose of gogensig")
 	flag.BoolVar(&symbGen, "symbgen", false, "Only use llcppsymg to generate llcppg.symb.json")
 	flag.BoolVar(&codeGen, "codegen", false, "Only use (llcppsigfetch & gogensig) to generate go code binding")
+	flag.BoolVar(&ndjson, "ndjson", false, "Stream the declarations from llcppsigfetch to gogensig as NDJSON, for large headers")
+	flag.BoolVar(&cache, "cache", false, "Cache the preprocessed headers between runs, in the llcppg directory of the user cache directory")
+	flag.StringVar(&targetFlag, "target", "", "Generate the bindings for the target `goos/goarch`, like linux/arm64, instead of the host")
 	flag.BoolVar(&help, "h", false, "Display help information")
 	flag.BoolVar(&help, "help", false, "Display help information")
 	flag.Parse()
@@ -148,6 +172,13 @@ func main() {
 
 	remainArgs := flag.Args()
 
+	verifyLayout := false
+	if len(remainArgs) > 0 && remainArgs[0] == "verify-layout" {
+		verifyLayout = true
+		mode = ModeCodegen
+		remainArgs = remainArgs[1:]
+	}
+
 	var cfgFile string
 	if len(remainArgs) > 0 {
 		cfgFile = remainArgs[0]
@@ -155,10 +186,24 @@ func main() {
 		cfgFile = args.LLCPPG_CFG
 	}
 
-	do(cfgFile, mode, verbose)
+	var cacheDir string
+	if cache {
+		dir, err := os.UserCacheDir()
+		check(err)
+		cacheDir = filepath.Join(dir, "llcppg")
+	}
+
+	var target *llcppg.Target
+	if targetFlag != "" {
+		var err error
+		target, err = llcppg.ParseTarget(targetFlag)
+		check(err)
+	}
+
+	do(cfgFile, mode, verbose, ndjson, cacheDir, verifyLayout, target)
 }
 
-func do(cfgFile string, mode modeFlags, verbose verboseFlags) {
+func do(cfgFile string, mode modeFlags, verbose verboseFlags, ndjson bool, cacheDir string, verifyLayout bool, target *llcppg.Target) {
 	f, err := os.Open(cfgFile)
 	check(err)
 	defer f.Close()
@@ -167,6 +212,9 @@ func do(cfgFile string, mode modeFlags, verbose verboseFlags) {
 	json.NewDecoder(f).Decode(&conf)
 	conf.CFlags = env.ExpandEnv(conf.CFlags)
 	conf.Libs = env.ExpandEnv(conf.Libs)
+	if target != nil {
+		conf.Target = target
+	}
 
 	b, err := json.MarshalIndent(&conf, "", "  ")
 	check(err)
@@ -178,9 +226,9 @@ func do(cfgFile string, mode modeFlags, verbose verboseFlags) {
 
 	if mode&ModeCodegen != 0 {
 		r, w := io.Pipe()
-		go llcppsigfetch(b, verbose, w)
+		go llcppsigfetch(b, verbose, ndjson, cacheDir, w)
 
-		err = gogensig(r, cfgFile, verbose)
+		err = gogensig(r, cfgFile, verbose, ndjson, verifyLayout, target)
 		check(err)
 	}
 }
@@ -1,12 +1,22 @@
 package llcppg
 
-import "github.com/goplus/llcppg/ast"
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/goplus/llcppg/ast"
+)
 
 const LLCPPG_CFG = "llcppg.cfg"
 const LLCPPG_SYMB = "llcppg.symb.json"
 const LLCPPG_SIGFETCH = "llcppg.sigfetch.json"
 const LLCPPG_PUB = "llcppg.pub"
 
+// SchemaVersion is the version of the JSON format of a Pkg, written to its
+// "_Version" field. It's increased when the format changes incompatibly.
+const SchemaVersion = 1
+
 type Condition struct {
 	OS   []string `json:"os"`
 	Arch []string `json:"arch"`
@@ -21,18 +31,130 @@ func NewImplFiles() *ImplFiles {
 	return &ImplFiles{Files: []string{}, Cond: Condition{OS: []string{}, Arch: []string{}}}
 }
 
+// NamespaceNaming controls how a C++ qualified name is converted to a Go name.
+type NamespaceNaming string
+
+const (
+	// ns::Widget -> NsWidget, the default
+	NamespacePrefix NamespaceNaming = "prefix"
+	// ns::Widget -> Widget, the scopes are kept when the names of different scopes collide
+	NamespaceDrop NamespaceNaming = "drop"
+)
+
 // Config represents a configuration for the llcppg tool.
 type Config struct {
-	Name           string      `json:"name"`
-	CFlags         string      `json:"cflags"`
-	Libs           string      `json:"libs"`
-	Include        []string    `json:"include"`
-	TrimPrefixes   []string    `json:"trimPrefixes"`
-	Cplusplus      bool        `json:"cplusplus"`
-	Deps           []string    `json:"deps"`
-	KeepUnderScore bool        `json:"keepUnderScore"`
-	Impl           []ImplFiles `json:"impl"`
-	Mix            bool        `json:"mix"`
+	Name            string          `json:"name"`
+	CFlags          string          `json:"cflags"`
+	Libs            string          `json:"libs"`
+	Include         []string        `json:"include"`
+	TrimPrefixes    []string        `json:"trimPrefixes"`
+	Cplusplus       bool            `json:"cplusplus"`
+	Deps            []string        `json:"deps"`
+	KeepUnderScore  bool            `json:"keepUnderScore"`
+	Impl            []ImplFiles     `json:"impl"`
+	Mix             bool            `json:"mix"`
+	NamespaceNaming NamespaceNaming `json:"namespaceNaming,omitempty"`
+	KeepRawComments bool            `json:"keepRawComments,omitempty"`
+	// Operators maps C++ operator functions to Go method names,like "operator()": "Eval",
+	// they override the default names like Add for operator+ and ToBool for operator bool.
+	Operators map[string]string `json:"operators,omitempty"`
+	// EnumString generates a String method returning the item name for each enum.
+	EnumString bool `json:"enumString,omitempty"`
+	// TrimEnumPrefix trims the prefix common to the items of each enum from their names,
+	// like CJSON_Invalid -> Invalid, unless a name without it is taken.
+	TrimEnumPrefix bool `json:"trimEnumPrefix,omitempty"`
+	// TypedAnonEnum declares each anonymous enum whose items share a prefix as a type
+	// named after it, like enum { CJSON_Invalid } -> type CJSON c.Int, the items of
+	// the other anonymous enums are constants of the integer type of the enum.
+	TypedAnonEnum bool `json:"typedAnonEnum,omitempty"`
+	// Target is the platform the bindings are generated for, the host if nil.
+	Target *Target `json:"target,omitempty"`
+}
+
+// Target is a platform the bindings are generated for, its sizes and alignments
+// are used instead of the ones of the host, like: {"goos": "linux", "goarch": "arm64"}
+type Target struct {
+	GOOS   string `json:"goos,omitempty"`   // the host one if empty
+	GOARCH string `json:"goarch,omitempty"` // the host one if empty
+	// Triple is the clang target triple the headers are parsed with, like
+	// aarch64-unknown-linux-gnu, derived from GOOS and GOARCH if empty.
+	Triple string `json:"triple,omitempty"`
+}
+
+// ParseTarget parses a target in the form goos/goarch, like: linux/arm64
+func ParseTarget(s string) (*Target, error) {
+	goos, goarch, ok := strings.Cut(s, "/")
+	if !ok || goos == "" || goarch == "" {
+		return nil, fmt.Errorf("invalid target %q, expected goos/goarch", s)
+	}
+	return &Target{GOOS: goos, GOARCH: goarch}, nil
+}
+
+// Goos returns the GOOS of the target, or of the host if t is nil.
+func (t *Target) Goos() string {
+	if t == nil || t.GOOS == "" {
+		return runtime.GOOS
+	}
+	return t.GOOS
+}
+
+// Goarch returns the GOARCH of the target, or of the host if t is nil.
+func (t *Target) Goarch() string {
+	if t == nil || t.GOARCH == "" {
+		return runtime.GOARCH
+	}
+	return t.GOARCH
+}
+
+// ClangTriple returns the clang target triple of the target, or "" if it's
+// the host, whose headers are parsed without --target.
+func (t *Target) ClangTriple() string {
+	if t == nil {
+		return ""
+	}
+	if t.Triple != "" {
+		return t.Triple
+	}
+	goos, goarch := t.Goos(), t.Goarch()
+	if goos == runtime.GOOS && goarch == runtime.GOARCH {
+		return ""
+	}
+	arch, ok := clangArchs[goarch]
+	if !ok {
+		arch = goarch
+	}
+	switch goos {
+	case "darwin", "ios":
+		if goarch == "arm64" {
+			arch = "arm64"
+		}
+		return arch + "-apple-" + goos
+	case "windows":
+		return arch + "-pc-windows-msvc"
+	case "linux":
+		if goarch == "arm" {
+			return arch + "-unknown-linux-gnueabihf"
+		}
+		return arch + "-unknown-linux-gnu"
+	}
+	return arch + "-unknown-" + goos
+}
+
+// clangArchs maps the GOARCH to the architecture of the clang target triple.
+var clangArchs = map[string]string{
+	"386":      "i686",
+	"amd64":    "x86_64",
+	"arm":      "armv7",
+	"arm64":    "aarch64",
+	"mips":     "mips",
+	"mipsle":   "mipsel",
+	"mips64":   "mips64",
+	"mips64le": "mips64el",
+	"ppc64":    "powerpc64",
+	"ppc64le":  "powerpc64le",
+	"riscv64":  "riscv64",
+	"s390x":    "s390x",
+	"wasm":     "wasm32",
 }
 
 func NewDefaultConfig() *Config {
@@ -61,3 +183,50 @@ type Pkg struct {
 	File    *ast.File
 	FileMap map[string]*FileInfo
 }
+
+// DeclNames returns the qualified names the decls of each file declare at the
+// top level of the Go package, like ns::Widget, with the items of the unscoped
+// enums. The NDJSON output of llcppsigfetch writes them in its header, so the Go
+// names of the decls are reserved before any of them is converted.
+func DeclNames(decls []ast.Decl) map[string][]string {
+	names := make(map[string][]string)
+	add := func(base *ast.DeclBase, name *ast.Ident) {
+		if name == nil {
+			return
+		}
+		var file string
+		if base.Loc != nil {
+			file = base.Loc.File
+		}
+		names[file] = append(names[file], qualifiedName(base.Parent, name.Name))
+	}
+	for _, decl := range decls {
+		switch d := decl.(type) {
+		case *ast.TypeDecl:
+			add(&d.DeclBase, d.Name)
+		case *ast.TypedefDecl:
+			add(&d.DeclBase, d.Name)
+		case *ast.VarDecl:
+			add(&d.DeclBase, d.Name)
+		case *ast.EnumTypeDecl:
+			add(&d.DeclBase, d.Name)
+			if !d.Type.Scoped {
+				for _, item := range d.Type.Items {
+					add(&d.DeclBase, item.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// qualifiedName returns a name qualified by its scopes, like ns::Widget.
+func qualifiedName(scope ast.Expr, name string) string {
+	switch s := scope.(type) {
+	case *ast.Ident:
+		return s.Name + "::" + name
+	case *ast.ScopingExpr:
+		return qualifiedName(s.Parent, qualifiedName(s.X, name))
+	}
+	return name
+}